@@ -0,0 +1,65 @@
+package fastgpt
+
+import (
+	"HelpStudent/config"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/store/pg"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/service"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configYml string
+	StartCmd  = &cobra.Command{
+		Use:   "fastgpt",
+		Short: "FastGPT module maintenance",
+	}
+	rotateKeyCmd = &cobra.Command{
+		Use:     "rotate-key",
+		Short:   "Re-encrypt all FastGPT API keys with the current master key",
+		Example: "app fastgpt rotate-key -c config/config.yaml",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rotateKey(); err != nil {
+				println(err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	StartCmd.PersistentFlags().StringVarP(&configYml, "config", "c", "config/config.yaml", "Start with provided configuration file")
+	StartCmd.AddCommand(rotateKeyCmd)
+}
+
+func rotateKey() error {
+	config.LoadConfig(configYml)
+	if logx.SystemLogger == nil {
+		logx.SystemLogger = logx.Setup()
+	}
+
+	keyring, err := service.NewKeyring(config.GetConfig().FastGPT)
+	if err != nil {
+		return err
+	}
+	if !keyring.Enabled() {
+		return fmt.Errorf("FastGPT.MasterKeys is empty")
+	}
+
+	orm := pg.MustNewPGOrm(config.GetConfig().MainPostgres)
+	if err := dao.InitPG(orm.GetOrm(), keyring); err != nil {
+		return err
+	}
+
+	count, err := dao.FastgptApp.RotateAPIKeys(context.Background())
+	if err != nil {
+		return err
+	}
+	println(fmt.Sprintf("re-encrypted %d api keys with master key %s", count, keyring.PrimaryId()))
+	return nil
+}
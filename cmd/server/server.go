@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"HelpStudent/config"
+	"HelpStudent/core/fileServer"
 	"HelpStudent/core/healthz"
 	"HelpStudent/core/kernel"
 	"HelpStudent/core/logx"
@@ -19,6 +20,7 @@ import (
 	"HelpStudent/core/stringx"
 	"HelpStudent/core/tracex"
 	"HelpStudent/internal/app/appInitialize"
+	"HelpStudent/internal/app/fastgpt/fake"
 
 	"github.com/flamego/cors"
 	"github.com/flamego/flamego"
@@ -31,14 +33,18 @@ import (
 )
 
 var (
-	configYml string
-	engine    *kernel.Engine
-	StartCmd  = &cobra.Command{
+	configYml   string
+	fakeFastGPT bool
+	engine      *kernel.Engine
+	StartCmd    = &cobra.Command{
 		Use:     "server",
 		Short:   "Set Application config info",
 		Example: "main server -c config/settings.yml",
 		PreRun: func(cmd *cobra.Command, args []string) {
 			setUp()
+			if fakeFastGPT {
+				startFakeFastGPT()
+			}
 			loadStore()
 			loadApp()
 		},
@@ -50,6 +56,7 @@ var (
 
 func init() {
 	StartCmd.PersistentFlags().StringVarP(&configYml, "config", "c", "config/config.yaml", "Start server with provided configuration file")
+	StartCmd.PersistentFlags().BoolVar(&fakeFastGPT, "fake-fastgpt", false, "Start an in-process fake FastGPT and use it instead of FastGPT.BaseURL")
 }
 
 // 初始化配置和日志
@@ -95,9 +102,24 @@ func setUp() {
 
 }
 
+// 启动进程内的 FastGPT 模拟服务，配置重新加载后仍指向模拟服务
+func startFakeFastGPT() {
+	baseURL := fake.New().Start()
+	useFake := func(globalConfig *config.GlobalConfig) {
+		globalConfig.FastGPT.BaseURL = baseURL
+	}
+	useFake(config.GetConfig())
+	engine.ConfigListener = append(engine.ConfigListener, useFake)
+	println(stringx.Yellow("Fake FastGPT run at: " + baseURL))
+}
+
 // 存储介质连接
 func loadStore() {
 	engine.MainPG = pg.MustNewPGOrm(config.GetConfig().MainPostgres)
+	if err := fileServer.InitFileServers(config.GetConfig().FileServers); err != nil {
+		logx.SystemLogger.Errorw("failed to init file servers", zap.Field{Key: "error", Type: zapcore.StringType, String: err.Error()})
+		os.Exit(1)
+	}
 }
 
 // 加载应用，包含多个生命周期
@@ -64,3 +64,8 @@ func GenConfig(configYml string, force bool) error {
 func GetConfig() *GlobalConfig {
 	return serveConfig
 }
+
+// SetConfig 直接设置配置，供测试等不读取配置文件的场景使用
+func SetConfig(c *GlobalConfig) {
+	serveConfig = c
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"HelpStudent/core/fileServer"
 	"HelpStudent/core/store/pg"
 )
 
@@ -16,12 +17,95 @@ type GlobalConfig struct {
 		Secret string `yaml:"Secret"`
 		Issuer string `yaml:"Issuer"`
 	} `yaml:"Auth"`
-	OAuth   []OAuth `yaml:"OAuth"`
-	FastGPT FastGPT `yaml:"FastGPT"`
+	OAuth       []OAuth             `yaml:"OAuth"`
+	FastGPT     FastGPT             `yaml:"FastGPT"`
+	FileServers []fileServer.Config `yaml:"FileServers"`
 }
 
 type FastGPT struct {
 	BaseURL string `yaml:"BaseURL"`
+	// MasterKeys API Key 加密主密钥（base64 编码的 32 字节），第一个用于加密，其余仅用于解密旧数据
+	MasterKeys []MasterKey `yaml:"MasterKeys"`
+	// Quota 对话配额默认值，可在应用上单独覆盖
+	Quota Quota `yaml:"Quota"`
+	// Client 访问 FastGPT 的超时、重试与连接池设置，只在启动时读取，修改后需重启
+	Client FastGPTClient `yaml:"Client"`
+	// StreamResumeGrace 流式对话客户端断线后继续生成、以及生成结束后保留以供续传的秒数，默认 60
+	StreamResumeGrace int `yaml:"StreamResumeGrace"`
+	// Documents 课程资料上传
+	Documents Documents `yaml:"Documents"`
+	// ForwardFeedback 是否将用户对回答的评价同步到 FastGPT
+	ForwardFeedback bool `yaml:"ForwardFeedback"`
+	// AnswerCacheTTL 回答缓存默认保留秒数，应用未单独设置时使用，默认 3600
+	AnswerCacheTTL int `yaml:"AnswerCacheTTL"`
+	// ImageCache FastGPT 图片缓存
+	ImageCache ImageCache `yaml:"ImageCache"`
+	// HealthCheck 应用 API Key 与分享链接的定期检查
+	HealthCheck HealthCheck `yaml:"HealthCheck"`
+	// Review 回答人工审核
+	Review Review `yaml:"Review"`
+	// Queue 流式对话排队
+	Queue Queue `yaml:"Queue"`
+	// OutLinkSecret 派生外链用户标识（outLinkUid）的密钥，为空时使用 Auth.Secret；修改后用户将看不到此前的外链会话
+	OutLinkSecret string `yaml:"OutLinkSecret"`
+}
+
+// Queue 流式对话达到应用的 AppStreams 上限后排队等待，按用户轮流放行
+type Queue struct {
+	MaxWaiting     int `yaml:"MaxWaiting"`     // 每个应用最多排队的请求数，默认 100
+	MaxWaitSeconds int `yaml:"MaxWaitSeconds"` // 最长排队秒数，默认 120
+}
+
+// Review 回答人工审核设置
+type Review struct {
+	// MinQuoteScore 检索了知识库但最高引用相似度低于该值（或没有引用）时提交审核，0 表示不按相似度标记
+	MinQuoteScore float64 `yaml:"MinQuoteScore"`
+}
+
+// HealthCheck 应用健康检查设置
+type HealthCheck struct {
+	Interval     int    `yaml:"Interval"`     // 检查间隔秒数，默认 300，小于 0 时不检查
+	Timeout      int    `yaml:"Timeout"`      // 单个应用的检查超时秒数，默认 10
+	AlertWebhook string `yaml:"AlertWebhook"` // 应用状态变化时以 POST JSON 通知的地址，可选
+}
+
+// ImageCache FastGPT 图片缓存设置，StorageKey 为空时存放在本地 Dir 目录
+type ImageCache struct {
+	StorageKey string `yaml:"StorageKey"` // 使用的 FileServers 存储 Key
+	Dir        string `yaml:"Dir"`        // 本地缓存目录，默认 data/image-cache
+	MaxTotalMB int    `yaml:"MaxTotalMB"` // 缓存总大小上限，超出时淘汰最久未访问的图片，默认 256
+	MaxImageMB int    `yaml:"MaxImageMB"` // 单张图片大小上限，默认 10
+	MaxAge     int    `yaml:"MaxAge"`     // 浏览器缓存秒数，默认 86400
+}
+
+// Documents 课程资料上传设置
+type Documents struct {
+	StorageKey string `yaml:"StorageKey"` // 原始文件使用的 FileServers 存储 Key，默认 documents
+	MaxSizeMB  int    `yaml:"MaxSizeMB"`  // 单个文件大小上限，默认 50
+	ChunkSize  int    `yaml:"ChunkSize"`  // 本地切分时每段的最大字数，默认 800
+}
+
+// FastGPTClient 时间单位均为秒，0 表示使用默认值
+type FastGPTClient struct {
+	ConnectTimeout      int `yaml:"ConnectTimeout"`      // 建立连接，默认 5
+	RequestTimeout      int `yaml:"RequestTimeout"`      // 非流式请求整体，默认 30
+	FirstByteTimeout    int `yaml:"FirstByteTimeout"`    // 流式请求等待响应头，默认 60
+	StreamIdleTimeout   int `yaml:"StreamIdleTimeout"`   // 流式响应两次数据之间的最长间隔，默认 120
+	MaxRetries          int `yaml:"MaxRetries"`          // 幂等请求失败后的最大重试次数，默认 2，-1 表示不重试
+	MaxIdleConnsPerHost int `yaml:"MaxIdleConnsPerHost"` // 每个 FastGPT 地址保留的空闲连接数，默认 32
+}
+
+// Quota 对话配额，0 表示不限制
+type Quota struct {
+	UserRPM           int `yaml:"UserRPM"`           // 每个用户每分钟请求数
+	UserDailyMessages int `yaml:"UserDailyMessages"` // 每个用户每天消息数
+	AppConcurrency    int `yaml:"AppConcurrency"`    // 每个应用同时进行的非流式对话数，AppStreams 未设置时也作为流式对话数上限
+	AppStreams        int `yaml:"AppStreams"`        // 每个应用同时进行的流式对话数，超出时排队
+}
+
+type MasterKey struct {
+	Id  string `yaml:"Id"`
+	Key string `yaml:"Key"`
 }
 
 type OAuth struct {
@@ -4,8 +4,9 @@ import "github.com/pkg/errors"
 
 var clients = make(map[string]FileClient)
 var creatorMap = map[string]func(Config) (FileClient, error){
-	"mock": NewMock,
-	"oss":  NewAliOSS,
+	"mock":  NewMock,
+	"oss":   NewAliOSS,
+	"local": NewLocal,
 }
 
 func InitFileServers(cfgs []Config) error {
@@ -0,0 +1,129 @@
+package fileServer
+
+import (
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local 本地磁盘存储，Prefix 为存储根目录，适用于开发环境或单机部署
+type Local struct {
+	root string
+}
+
+func NewLocal(cfg Config) (FileClient, error) {
+	if cfg.Prefix == "" {
+		return nil, errors.New("本地存储未配置 Prefix 目录")
+	}
+	root, err := filepath.Abs(cfg.Prefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "无法解析本地存储目录")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, errors.Wrap(err, "无法创建本地存储目录")
+	}
+	return &Local{root: root}, nil
+}
+
+// path 文件的绝对路径，拒绝跳出根目录的文件名
+func (l *Local) path(fileName string) (string, error) {
+	p := filepath.Join(l.root, filepath.FromSlash(fileName))
+	if p != l.root && !strings.HasPrefix(p, l.root+string(filepath.Separator)) {
+		return "", errors.New("非法的文件名: " + fileName)
+	}
+	return p, nil
+}
+
+func (l *Local) UploadFile(file []byte, fileName string) (string, error) {
+	p, err := l.path(fileName)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", errors.Wrap(err, "上传文件失败")
+	}
+	if err := os.WriteFile(p, file, 0o644); err != nil {
+		return "", errors.Wrap(err, "上传文件失败")
+	}
+	return fileName, nil
+}
+
+func (l *Local) UploadFileFromIO(fd io.Reader, fileName string) (string, error) {
+	p, err := l.path(fileName)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", errors.Wrap(err, "上传文件失败")
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return "", errors.Wrap(err, "上传文件失败")
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, fd); err != nil {
+		return "", errors.Wrap(err, "上传文件失败")
+	}
+	return fileName, nil
+}
+
+func (l *Local) ReadAll(fileName string) ([]byte, error) {
+	p, err := l.path(fileName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "读取文件失败")
+	}
+	return data, nil
+}
+
+func (l *Local) DeleteFile(fileName string) error {
+	p, err := l.path(fileName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "删除文件失败")
+	}
+	return nil
+}
+
+func (l *Local) ReadDir(dir string) ([]DirItem, error) {
+	p, err := l.path(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "读取文件夹失败")
+	}
+	result := make([]DirItem, 0, len(entries))
+	for _, e := range entries {
+		item := DirItem{Name: filepath.ToSlash(filepath.Join(dir, e.Name())), IsDir: e.IsDir()}
+		if info, err := e.Info(); err == nil {
+			item.Size = info.Size()
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func (l *Local) DownloadLink(string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (l *Local) UploadLink(string, string, CallbackConfig) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (l *Local) StsToken(string, string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (l *Local) GetPreviewLink(string) (string, error) {
+	return "", errors.New("not implemented")
+}
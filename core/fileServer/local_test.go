@@ -0,0 +1,40 @@
+package fileServer
+
+import "testing"
+
+func TestLocal_UploadReadDelete(t *testing.T) {
+	client, err := NewLocal(Config{Prefix: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UploadFile([]byte("hello"), "a/b/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := client.ReadAll("a/b/c.txt")
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("ReadAll returned %q, %v", data, err)
+	}
+	items, err := client.ReadDir("a/b")
+	if err != nil || len(items) != 1 || items[0].Name != "a/b/c.txt" {
+		t.Fatalf("ReadDir returned %+v, %v", items, err)
+	}
+	if err := client.DeleteFile("a/b/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ReadAll("a/b/c.txt"); err == nil {
+		t.Error("deleted file should not be readable")
+	}
+}
+
+func TestLocal_RejectTraversal(t *testing.T) {
+	client, err := NewLocal(Config{Prefix: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UploadFile([]byte("x"), "../escape.txt"); err == nil {
+		t.Error("path outside root should be rejected")
+	}
+	if _, err := client.ReadAll("a/../../escape.txt"); err == nil {
+		t.Error("path outside root should be rejected")
+	}
+}
@@ -20,6 +20,12 @@ type Options struct {
 	PingInterval time.Duration
 }
 
+// Identifiable 消息实现该接口且返回值非空时，会在 data 之前写出 id 字段，
+// 客户端断线重连时可通过 Last-Event-ID 请求头带回
+type Identifiable interface {
+	EventId() string
+}
+
 type connection struct {
 	Options
 
@@ -145,6 +151,11 @@ loop:
 				return
 			}
 
+			if m, ok := message.Interface().(Identifiable); ok {
+				if id := m.EventId(); id != "" && !write("id: "+id+"\n") {
+					return
+				}
+			}
 			if !write("data: ") {
 				return
 			}
@@ -9,6 +9,9 @@ import (
 	"strings"
 )
 
+// WebSocketTokenProtocol 浏览器无法为 WebSocket 设置请求头，可在子协议中以 bearer.<token> 传递登录凭证
+const WebSocketTokenProtocol = "bearer."
+
 func Authorization(c flamego.Context, r flamego.Render) {
 	token := c.Request().Header.Get("Authorization")
 	if token == "" || strings.Index(token, "Bearer") != 0 {
@@ -17,6 +20,30 @@ func Authorization(c flamego.Context, r flamego.Render) {
 	}
 	fmt.Println("1,token:", token)
 	token = strings.Replace(token, "Bearer ", "", 1)
+	authorize(c, r, token)
+}
+
+// WebSocketAuthorization WebSocket 握手的登录校验，凭证依次取 Authorization 请求头、
+// Sec-WebSocket-Protocol 中的 bearer.<token> 子协议与 access_token 查询参数
+func WebSocketAuthorization(c flamego.Context, r flamego.Render) {
+	if header := c.Request().Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		authorize(c, r, strings.TrimPrefix(header, "Bearer "))
+		return
+	}
+	for _, protocol := range strings.Split(c.Request().Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if token, ok := strings.CutPrefix(strings.TrimSpace(protocol), WebSocketTokenProtocol); ok && token != "" {
+			authorize(c, r, token)
+			return
+		}
+	}
+	if token := c.Query("access_token"); token != "" {
+		authorize(c, r, token)
+		return
+	}
+	response.UnAuthorization(r)
+}
+
+func authorize(c flamego.Context, r flamego.Render, token string) {
 	entity, err := auth.ParseToken(token)
 	if err != nil {
 		fmt.Println("2,token:", token)
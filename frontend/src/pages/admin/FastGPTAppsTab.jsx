@@ -12,6 +12,7 @@ const FastGPTAppsTab = () => {
   const [modalVisible, setModalVisible] = useState(false);
   const [editingFastgptApp, setEditingFastgptApp] = useState(null);
   const [form] = Form.useForm();
+  const provider = Form.useWatch('provider', form);
 
   useEffect(() => {
     fetchFastgptApps(1, 10);
@@ -49,7 +50,10 @@ const FastGPTAppsTab = () => {
           appId: values.appId,
           shareId: values.shareId,
           apiKey: values.apiKey,
-          description: values.description
+          description: values.description,
+          provider: values.provider,
+          baseUrl: values.baseUrl,
+          model: values.model
         }, token);
       } else {
         response = await createFastgptApp({
@@ -57,7 +61,10 @@ const FastGPTAppsTab = () => {
           appId: values.appId,
           shareId: values.shareId,
           apiKey: values.apiKey,
-          description: values.description
+          description: values.description,
+          provider: values.provider,
+          baseUrl: values.baseUrl,
+          model: values.model
         }, token);
       }
 
@@ -95,6 +102,7 @@ const FastGPTAppsTab = () => {
   const openAddModal = () => {
     setEditingFastgptApp(null);
     form.resetFields();
+    form.setFieldsValue({ provider: 'fastgpt' });
     setModalVisible(true);
   };
 
@@ -104,8 +112,11 @@ const FastGPTAppsTab = () => {
       appName: record.appName,
       appId: record.appId,
       shareId: record.shareId,
-      apiKey: record.apiKey,
-      description: record.description
+      apiKey: '',
+      description: record.description,
+      provider: record.provider || 'fastgpt',
+      baseUrl: record.baseUrl,
+      model: record.model
     });
     setModalVisible(true);
   };
@@ -138,11 +149,11 @@ const FastGPTAppsTab = () => {
     },
     {
       title: '密钥',
-      dataIndex: 'apiKey',
-      key: 'apiKey',
+      dataIndex: 'apiKeyMask',
+      key: 'apiKeyMask',
       render: (text) => (
         <Tag color="blue" style={{ fontFamily: 'monospace' }}>
-          {text ? text.substring(0, 8) + '...' + text.substring(text.length - 4) : '-'}
+          {text || '-'}
         </Tag>
       )
     },
@@ -206,25 +217,55 @@ const FastGPTAppsTab = () => {
             <Input placeholder="给学科起个名字" />
           </Form.Item>
           <Form.Item
-            name="appId"
-            label="AppId"
-            rules={[{ required: true, message: '请输入 FastGPT AppId' }]}
+            name="provider"
+            label="对话后端"
           >
-            <Input placeholder="FastGPT 应用ID" />
-          </Form.Item>
-          <Form.Item
-            name="shareId"
-            label="ShareId"
-            rules={[{ required: true, message: '请输入 FastGPT ShareId' }]}
-          >
-            <Input placeholder="FastGPT 分享链接ID" />
+            <Radio.Group>
+              <Radio value="fastgpt">FastGPT</Radio>
+              <Radio value="openai">OpenAI 兼容接口</Radio>
+            </Radio.Group>
           </Form.Item>
+          {provider === 'openai' ? (
+            <>
+              <Form.Item
+                name="baseUrl"
+                label="接口地址"
+                rules={[{ required: true, message: '请输入接口地址' }]}
+              >
+                <Input placeholder="如 http://vllm:8000/v1" />
+              </Form.Item>
+              <Form.Item
+                name="model"
+                label="模型"
+                rules={[{ required: true, message: '请输入模型名称' }]}
+              >
+                <Input placeholder="如 qwen2.5-7b-instruct" />
+              </Form.Item>
+            </>
+          ) : (
+            <>
+              <Form.Item
+                name="appId"
+                label="AppId"
+                rules={[{ required: true, message: '请输入 FastGPT AppId' }]}
+              >
+                <Input placeholder="FastGPT 应用ID" />
+              </Form.Item>
+              <Form.Item
+                name="shareId"
+                label="ShareId"
+                rules={[{ required: true, message: '请输入 FastGPT ShareId' }]}
+              >
+                <Input placeholder="FastGPT 分享链接ID" />
+              </Form.Item>
+            </>
+          )}
           <Form.Item
             name="apiKey"
             label="密钥"
-            rules={[{ required: true, message: '请输入密钥' }]}
+            rules={[{ required: !editingFastgptApp && provider !== 'openai', message: '请输入密钥' }]}
           >
-            <Input.Password placeholder="FastGPT API Key" />
+            <Input.Password placeholder={editingFastgptApp ? '留空则不修改' : 'API Key'} />
           </Form.Item>
           <Form.Item
             name="description"
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type answerCache struct {
+	*gorm.DB
+}
+
+func (u *answerCache) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptAnswerCacheSetting{})
+}
+
+// GetByAppId 获取应用的回答缓存设置，未配置时返回 nil
+func (u *answerCache) GetByAppId(ctx context.Context, appId string) (*model.FastgptAnswerCacheSetting, error) {
+	var setting model.FastgptAnswerCacheSetting
+	err := u.WithContext(ctx).Where("app_id = ?", appId).First(&setting).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// Save 保存回答缓存设置
+func (u *answerCache) Save(ctx context.Context, setting *model.FastgptAnswerCacheSetting) error {
+	return u.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "app_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "ttl_seconds", "variables", "updated_by", "updated_at"}),
+	}).Create(setting).Error
+}
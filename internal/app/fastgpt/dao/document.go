@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type document struct {
+	*gorm.DB
+}
+
+// DocumentFilter 资料查询条件
+type DocumentFilter struct {
+	AppId       string
+	SubjectName string
+	DatasetId   string
+}
+
+func (u *document) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptDocument{})
+}
+
+// Create 创建资料记录
+func (u *document) Create(ctx context.Context, doc *model.FastgptDocument) error {
+	return u.WithContext(ctx).Create(doc).Error
+}
+
+// Get 获取资料，不存在时返回 nil
+func (u *document) Get(ctx context.Context, id string) (*model.FastgptDocument, error) {
+	var doc model.FastgptDocument
+	err := u.WithContext(ctx).Where("id = ?", id).First(&doc).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Update 更新资料处理进度
+func (u *document) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	return u.WithContext(ctx).Model(&model.FastgptDocument{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// List 分页查询资料
+func (u *document) List(ctx context.Context, f DocumentFilter, offset, limit int) ([]model.FastgptDocument, int64, error) {
+	var docs []model.FastgptDocument
+	var total int64
+
+	query := u.WithContext(ctx).Model(&model.FastgptDocument{})
+	if f.AppId != "" {
+		query = query.Where("app_id = ?", f.AppId)
+	}
+	if f.SubjectName != "" {
+		query = query.Where("subject_name = ?", f.SubjectName)
+	}
+	if f.DatasetId != "" {
+		query = query.Where("dataset_id = ?", f.DatasetId)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&docs).Error
+	return docs, total, err
+}
+
+// Delete 删除资料记录
+func (u *document) Delete(ctx context.Context, id string) error {
+	return u.WithContext(ctx).Where("id = ?", id).Delete(&model.FastgptDocument{}).Error
+}
+
+// FailInterrupted 服务重启时将仍在处理中的资料标记为失败
+func (u *document) FailInterrupted(ctx context.Context) (int64, error) {
+	result := u.WithContext(ctx).Model(&model.FastgptDocument{}).
+		Where("status = ?", model.DocumentProcessing).
+		Updates(map[string]interface{}{
+			"status":    model.DocumentError,
+			"error_msg": "服务重启导致处理中断，请重新上传",
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DatasetIds 应用上传过资料的知识库 ID
+func (u *document) DatasetIds(ctx context.Context, appId string) ([]string, error) {
+	var ids []string
+	err := u.WithContext(ctx).Model(&model.FastgptDocument{}).
+		Where("app_id = ?", appId).
+		Distinct().
+		Pluck("dataset_id", &ids).Error
+	return ids, err
+}
@@ -2,26 +2,57 @@ package dao
 
 import (
 	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/pkg/utils/crypto"
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type fastgpt struct {
 	*gorm.DB
+	keyring *crypto.Keyring
 }
 
 var FastgptApp *fastgpt
 
-func (u *fastgpt) Init(db *gorm.DB) (err error) {
+func (u *fastgpt) Init(db *gorm.DB, keyring *crypto.Keyring) (err error) {
 	u.DB = db
+	u.keyring = keyring
 	FastgptApp = u
 	return db.AutoMigrate(&model.FastgptApp{})
 }
 
+// encryptKey 加密 API Key，未配置主密钥时原样存储
+func (u *fastgpt) encryptKey(apiKey string) (string, error) {
+	enc, err := u.keyring.Encrypt(apiKey)
+	if err != nil {
+		return "", fmt.Errorf("encrypt api key: %w", err)
+	}
+	return enc, nil
+}
+
+// decryptApp 解密应用的 API Key
+func (u *fastgpt) decryptApp(app *model.FastgptApp) error {
+	apiKey, err := u.keyring.Decrypt(app.APIKey)
+	if err != nil {
+		return fmt.Errorf("decrypt api key of app %s: %w", app.ID, err)
+	}
+	app.APIKey = apiKey
+	return nil
+}
+
 // CreateApp 创建应用
 func (u *fastgpt) CreateApp(app *model.FastgptApp) error {
+	plain := app.APIKey
+	enc, err := u.encryptKey(plain)
+	if err != nil {
+		return err
+	}
+	app.APIKey = enc
+	defer func() { app.APIKey = plain }()
 	return u.Create(app).Error
 }
 
@@ -31,10 +62,13 @@ func (u *fastgpt) GetAppByID(appID string) (*model.FastgptApp, error) {
 	err := u.Where("id = ?", appID).First(&app).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("应用不存在或已禁用")
+			return nil, errors.New("应用不存在")
 		}
 		return nil, err
 	}
+	if err := u.decryptApp(&app); err != nil {
+		return nil, err
+	}
 	return &app, nil
 }
 
@@ -44,18 +78,23 @@ func (u *fastgpt) GetAppByShareID(shareID string) (*model.FastgptApp, error) {
 	err := u.Where("share_id = ?", shareID).First(&app).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("应用不存在或已禁用")
+			return nil, errors.New("应用不存在")
 		}
 		return nil, err
 	}
+	if err := u.decryptApp(&app); err != nil {
+		return nil, err
+	}
 	return &app, nil
 }
 
 // GetAppByPrimaryID 根据主键ID获取应用
 func (u *fastgpt) GetAppByPrimaryID(ctx context.Context, id string) (*model.FastgptApp, error) {
 	var app model.FastgptApp
-	err := u.Model(&model.FastgptApp{}).WithContext(ctx).Where("id = ?", id).First(&app).Error
-	return &app, err
+	if err := u.Model(&model.FastgptApp{}).WithContext(ctx).Where("id = ?", id).First(&app).Error; err != nil {
+		return &app, err
+	}
+	return &app, u.decryptApp(&app)
 }
 
 // GetAllApps 获取所有应用列表
@@ -69,15 +108,135 @@ func (u *fastgpt) GetAllApps(ctx context.Context, offset, limit int) ([]model.Fa
 	}
 
 	// 查询列表
-	err := u.WithContext(ctx).Offset(offset).Limit(limit).Order("created_at ASC").Find(&apps).Error
-	return apps, total, err
+	if err := u.WithContext(ctx).Offset(offset).Limit(limit).Order("created_at ASC").Find(&apps).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range apps {
+		if err := u.decryptApp(&apps[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return apps, total, nil
+}
+
+// ListAllApps 获取全部应用，用于后台检查
+func (u *fastgpt) ListAllApps(ctx context.Context) ([]model.FastgptApp, error) {
+	var apps []model.FastgptApp
+	if err := u.WithContext(ctx).Order("created_at ASC").Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	for i := range apps {
+		if err := u.decryptApp(&apps[i]); err != nil {
+			return nil, err
+		}
+	}
+	return apps, nil
+}
+
+// UpdateHealth 记录健康检查结果，不更新 updated_at
+func (u *fastgpt) UpdateHealth(ctx context.Context, id, status, lastError string, checkedAt time.Time) error {
+	return u.Model(&model.FastgptApp{}).WithContext(ctx).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"health_status":     status,
+		"health_error":      lastError,
+		"health_checked_at": checkedAt,
+	}).Error
 }
 
-// UpdateApp 更新应用
+// UpdateApp 更新应用，updates 中的 api_key 为明文，写入前加密
 func (u *fastgpt) UpdateApp(id string, updates map[string]interface{}) error {
+	if apiKey, ok := updates["api_key"].(string); ok {
+		enc, err := u.encryptKey(apiKey)
+		if err != nil {
+			return err
+		}
+		updates["api_key"] = enc
+	}
 	return u.Model(&model.FastgptApp{}).Where("id = ?", id).Updates(updates).Error
 }
 
+// RotateAPIKeys 使用当前主密钥重新加密所有应用（包括已软删除的）及其历史版本中的 API Key，返回重新加密的条数
+func (u *fastgpt) RotateAPIKeys(ctx context.Context) (int, error) {
+	if !u.keyring.Enabled() {
+		return 0, crypto.ErrNoMasterKey
+	}
+
+	rotated := 0
+	err := u.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var apps []model.FastgptApp
+		if err := tx.Unscoped().Find(&apps).Error; err != nil {
+			return err
+		}
+		for i := range apps {
+			if !u.keyring.NeedsRotation(apps[i].APIKey) {
+				continue
+			}
+			if err := u.decryptApp(&apps[i]); err != nil {
+				return err
+			}
+			enc, err := u.encryptKey(apps[i].APIKey)
+			if err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&model.FastgptApp{}).Where("id = ?", apps[i].ID).
+				UpdateColumn("api_key", enc).Error; err != nil {
+				return err
+			}
+			rotated++
+		}
+
+		// 历史版本中的 API Key 同样需要重新加密，否则停用旧主密钥后无法回滚
+		var revisions []model.FastgptAppRevision
+		if err := tx.Select("id", "app_id", "version", "api_key").Find(&revisions).Error; err != nil {
+			return err
+		}
+		for _, rev := range revisions {
+			if !u.keyring.NeedsRotation(rev.APIKey) {
+				continue
+			}
+			apiKey, err := u.keyring.Decrypt(rev.APIKey)
+			if err != nil {
+				return fmt.Errorf("decrypt api key of app %s version %d: %w", rev.AppId, rev.Version, err)
+			}
+			enc, err := u.encryptKey(apiKey)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&model.FastgptAppRevision{}).Where("id = ?", rev.ID).
+				UpdateColumn("api_key", enc).Error; err != nil {
+				return err
+			}
+			rotated++
+		}
+		return nil
+	})
+	return rotated, err
+}
+
+// GetAppIncludingDeleted 根据主键ID获取应用，包括已软删除的应用，不存在时返回 nil
+func (u *fastgpt) GetAppIncludingDeleted(ctx context.Context, id string) (*model.FastgptApp, error) {
+	var app model.FastgptApp
+	if err := u.Unscoped().WithContext(ctx).Where("id = ?", id).First(&app).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &app, u.decryptApp(&app)
+}
+
+// RestoreApp 恢复已软删除的应用并写入 updates，updates 中的 api_key 为明文
+func (u *fastgpt) RestoreApp(ctx context.Context, id string, updates map[string]interface{}) error {
+	if apiKey, ok := updates["api_key"].(string); ok {
+		enc, err := u.encryptKey(apiKey)
+		if err != nil {
+			return err
+		}
+		updates["api_key"] = enc
+	}
+	updates["deleted_at"] = nil
+	return u.Unscoped().Model(&model.FastgptApp{}).WithContext(ctx).Where("id = ?", id).Updates(updates).Error
+}
+
 // DeleteApp 删除应用（软删除）
 func (u *fastgpt) DeleteApp(ctx context.Context, id string) error {
 	return u.Model(&model.FastgptApp{}).WithContext(ctx).Where("id = ?", id).Delete(&model.FastgptApp{}).Error
@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type feedback struct {
+	*gorm.DB
+}
+
+// FeedbackFilter 回答评价查询条件
+type FeedbackFilter struct {
+	AppId       string
+	SubjectName string
+	ChatId      string
+	Rating      string
+}
+
+func (u *feedback) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptAnswerFeedback{})
+}
+
+// Save 保存评价，同一用户重复评价同一回答时覆盖并重置同步状态
+func (u *feedback) Save(ctx context.Context, fb *model.FastgptAnswerFeedback) error {
+	return u.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "data_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"rating":        fb.Rating,
+			"reason":        fb.Reason,
+			"forwarded":     false,
+			"forward_error": "",
+			"updated_at":    time.Now(),
+		}),
+	}).Create(fb).Error
+}
+
+// MarkForwarded 记录同步到 FastGPT 的结果
+func (u *feedback) MarkForwarded(ctx context.Context, dataId, userId string, forwardErr error) error {
+	updates := map[string]interface{}{"forwarded": forwardErr == nil, "forward_error": ""}
+	if forwardErr != nil {
+		updates["forward_error"] = forwardErr.Error()
+	}
+	return u.WithContext(ctx).Model(&model.FastgptAnswerFeedback{}).
+		Where("data_id = ? AND user_id = ?", dataId, userId).
+		Updates(updates).Error
+}
+
+// List 分页查询评价
+func (u *feedback) List(ctx context.Context, f FeedbackFilter, offset, limit int) ([]model.FastgptAnswerFeedback, int64, error) {
+	var items []model.FastgptAnswerFeedback
+	var total int64
+
+	query := u.WithContext(ctx).Model(&model.FastgptAnswerFeedback{})
+	if f.AppId != "" {
+		query = query.Where("app_id = ?", f.AppId)
+	}
+	if f.SubjectName != "" {
+		query = query.Where("subject_name = ?", f.SubjectName)
+	}
+	if f.ChatId != "" {
+		query = query.Where("chat_id = ?", f.ChatId)
+	}
+	if f.Rating != "" {
+		query = query.Where("rating = ?", f.Rating)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("updated_at DESC").Offset(offset).Limit(limit).Find(&items).Error
+	return items, total, err
+}
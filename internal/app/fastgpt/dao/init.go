@@ -1,15 +1,72 @@
 package dao
 
 import (
+	"HelpStudent/pkg/utils/crypto"
+
 	"gorm.io/gorm"
 )
 
 var (
-	Fastgpt = &fastgpt{}
+	Fastgpt     = &fastgpt{}
+	ChatRecord  = &chatRecord{}
+	AppQuota    = &appQuota{}
+	Moderation  = &moderation{}
+	Document    = &document{}
+	Usage       = &usage{}
+	Feedback    = &feedback{}
+	AnswerCache = &answerCache{}
+	AppSchedule = &appSchedule{}
+	AppRevision = &appRevision{}
+	Variables   = &variableSetting{}
+	Review      = &review{}
 )
 
-func InitPG(db *gorm.DB) error {
-	err := Fastgpt.Init(db)
+func InitPG(db *gorm.DB, keyring *crypto.Keyring) error {
+	err := Fastgpt.Init(db, keyring)
+	if err != nil {
+		return err
+	}
+	err = ChatRecord.Init(db)
+	if err != nil {
+		return err
+	}
+	err = AppQuota.Init(db)
+	if err != nil {
+		return err
+	}
+	err = Moderation.Init(db)
+	if err != nil {
+		return err
+	}
+	err = Document.Init(db)
+	if err != nil {
+		return err
+	}
+	err = Usage.Init(db)
+	if err != nil {
+		return err
+	}
+	err = Feedback.Init(db)
+	if err != nil {
+		return err
+	}
+	err = AnswerCache.Init(db)
+	if err != nil {
+		return err
+	}
+	err = AppSchedule.Init(db)
+	if err != nil {
+		return err
+	}
+	err = AppRevision.Init(db)
+	if err != nil {
+		return err
+	}
+	err = Variables.Init(db)
+	if err != nil {
+		return err
+	}
+	err = Review.Init(db)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type moderation struct {
+	*gorm.DB
+}
+
+// HitFilter 命中记录查询条件
+type HitFilter struct {
+	AppId     string
+	StaffId   string
+	Direction string
+}
+
+func (u *moderation) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptSensitiveWord{}, &model.FastgptModerationHit{})
+}
+
+// GetAllWords 获取全部敏感词
+func (u *moderation) GetAllWords(ctx context.Context) ([]model.FastgptSensitiveWord, error) {
+	var words []model.FastgptSensitiveWord
+	err := u.WithContext(ctx).Find(&words).Error
+	return words, err
+}
+
+// ListWords 分页查询敏感词，subjectName 为 nil 时不按科目筛选
+func (u *moderation) ListWords(ctx context.Context, subjectName *string, keyword string, offset, limit int) ([]model.FastgptSensitiveWord, int64, error) {
+	var words []model.FastgptSensitiveWord
+	var total int64
+
+	query := u.WithContext(ctx).Model(&model.FastgptSensitiveWord{})
+	if subjectName != nil {
+		query = query.Where("subject_name = ?", *subjectName)
+	}
+	if keyword != "" {
+		query = query.Where("word LIKE ?", "%"+keyword+"%")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&words).Error
+	return words, total, err
+}
+
+// SaveWords 批量保存敏感词，已存在的更新处理方式
+func (u *moderation) SaveWords(ctx context.Context, words []model.FastgptSensitiveWord) error {
+	if len(words) == 0 {
+		return nil
+	}
+	return u.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "word"}, {Name: "subject_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"action", "created_by", "updated_at"}),
+	}).Create(&words).Error
+}
+
+// DeleteWords 删除敏感词
+func (u *moderation) DeleteWords(ctx context.Context, ids []string) error {
+	return u.WithContext(ctx).Unscoped().Where("id IN ?", ids).Delete(&model.FastgptSensitiveWord{}).Error
+}
+
+// CreateHit 记录一次命中
+func (u *moderation) CreateHit(ctx context.Context, hit *model.FastgptModerationHit) error {
+	return u.WithContext(ctx).Create(hit).Error
+}
+
+// ListHits 分页查询命中记录
+func (u *moderation) ListHits(ctx context.Context, f HitFilter, offset, limit int) ([]model.FastgptModerationHit, int64, error) {
+	var hits []model.FastgptModerationHit
+	var total int64
+
+	query := u.WithContext(ctx).Model(&model.FastgptModerationHit{})
+	if f.AppId != "" {
+		query = query.Where("app_id = ?", f.AppId)
+	}
+	if f.StaffId != "" {
+		query = query.Where("staff_id = ?", f.StaffId)
+	}
+	if f.Direction != "" {
+		query = query.Where("direction = ?", f.Direction)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&hits).Error
+	return hits, total, err
+}
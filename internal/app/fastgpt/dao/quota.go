@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type appQuota struct {
+	*gorm.DB
+}
+
+func (u *appQuota) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptAppQuota{})
+}
+
+// GetByAppId 获取应用配额，未配置时返回 nil
+func (u *appQuota) GetByAppId(ctx context.Context, appId string) (*model.FastgptAppQuota, error) {
+	var quota model.FastgptAppQuota
+	err := u.WithContext(ctx).Where("app_id = ?", appId).First(&quota).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// Save 保存应用配额
+func (u *appQuota) Save(ctx context.Context, quota *model.FastgptAppQuota) error {
+	return u.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "app_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_rpm", "user_daily_messages", "app_concurrency", "app_streams", "updated_by", "updated_at"}),
+	}).Create(quota).Error
+}
@@ -0,0 +1,142 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type chatRecord struct {
+	*gorm.DB
+}
+
+// RecordFilter 聊天记录查询条件
+type RecordFilter struct {
+	AppId   string
+	ChatId  string
+	StaffId string
+	Status  string
+	Start   *time.Time
+	End     *time.Time
+}
+
+func (u *chatRecord) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptChatSession{}, &model.FastgptChatMessage{})
+}
+
+// CreateMessage 创建问答记录
+func (u *chatRecord) CreateMessage(ctx context.Context, msg *model.FastgptChatMessage) error {
+	return u.WithContext(ctx).Create(msg).Error
+}
+
+// FinishMessage 更新问答记录的回答与结束状态
+func (u *chatRecord) FinishMessage(ctx context.Context, id string, updates map[string]interface{}) error {
+	return u.WithContext(ctx).Model(&model.FastgptChatMessage{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// TouchSession 记录会话的一次提问，会话不存在时创建
+func (u *chatRecord) TouchSession(ctx context.Context, session *model.FastgptChatSession) error {
+	session.MessageCount = 1
+	return u.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "app_id"}, {Name: "chat_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"message_count":   gorm.Expr("fastgpt_chat_sessions.message_count + 1"),
+			"last_message_at": session.LastMessageAt,
+			"updated_at":      time.Now(),
+		}),
+	}).Create(session).Error
+}
+
+// ListSessions 分页查询会话
+func (u *chatRecord) ListSessions(ctx context.Context, f RecordFilter, offset, limit int) ([]model.FastgptChatSession, int64, error) {
+	var sessions []model.FastgptChatSession
+	var total int64
+
+	query := u.WithContext(ctx).Model(&model.FastgptChatSession{})
+	if f.AppId != "" {
+		query = query.Where("app_id = ?", f.AppId)
+	}
+	if f.ChatId != "" {
+		query = query.Where("chat_id = ?", f.ChatId)
+	}
+	if f.StaffId != "" {
+		query = query.Where("staff_id = ?", f.StaffId)
+	}
+	if f.Start != nil {
+		query = query.Where("last_message_at >= ?", *f.Start)
+	}
+	if f.End != nil {
+		query = query.Where("last_message_at < ?", *f.End)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("last_message_at DESC").Offset(offset).Limit(limit).Find(&sessions).Error
+	return sessions, total, err
+}
+
+// ListMessages 分页查询问答记录
+func (u *chatRecord) ListMessages(ctx context.Context, f RecordFilter, offset, limit int) ([]model.FastgptChatMessage, int64, error) {
+	var messages []model.FastgptChatMessage
+	var total int64
+
+	query := u.WithContext(ctx).Model(&model.FastgptChatMessage{})
+	if f.AppId != "" {
+		query = query.Where("app_id = ?", f.AppId)
+	}
+	if f.ChatId != "" {
+		query = query.Where("chat_id = ?", f.ChatId)
+	}
+	if f.StaffId != "" {
+		query = query.Where("staff_id = ?", f.StaffId)
+	}
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	if f.Start != nil {
+		query = query.Where("request_at >= ?", *f.Start)
+	}
+	if f.End != nil {
+		query = query.Where("request_at < ?", *f.End)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("request_at DESC").Offset(offset).Limit(limit).Find(&messages).Error
+	return messages, total, err
+}
+
+// RecentSuccessMessages 获取会话中最近成功的问答，按时间正序返回
+func (u *chatRecord) RecentSuccessMessages(ctx context.Context, appId, chatId string, limit int) ([]model.FastgptChatMessage, error) {
+	var messages []model.FastgptChatMessage
+	err := u.WithContext(ctx).
+		Where("app_id = ? AND chat_id = ? AND status = ?", appId, chatId, model.ChatStatusSuccess).
+		Order("request_at DESC").Limit(limit).Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// GetSession 获取会话，不存在时返回 nil
+func (u *chatRecord) GetSession(ctx context.Context, appId, chatId string) (*model.FastgptChatSession, error) {
+	var session model.FastgptChatSession
+	err := u.WithContext(ctx).Where("app_id = ? AND chat_id = ?", appId, chatId).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
@@ -0,0 +1,137 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type review struct {
+	*gorm.DB
+}
+
+// ReviewFilter 审核列表查询条件，AppIds 不为 nil 时只查询这些应用
+type ReviewFilter struct {
+	AppIds   []string
+	AppId    string
+	Status   string
+	Source   string
+	Assignee string
+}
+
+func (u *review) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptReview{}, &model.FastgptReviewComment{}, &model.FastgptReviewer{})
+}
+
+// Flag 标记一条回答，同一回答已存在时在事务中锁定该条并按 merge 返回的字段更新
+func (u *review) Flag(ctx context.Context, r *model.FastgptReview, merge func(existing *model.FastgptReview) map[string]interface{}) error {
+	return u.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(r)
+		if res.Error != nil || res.RowsAffected == 1 {
+			return res.Error
+		}
+		var existing model.FastgptReview
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("app_id = ? AND flag_key = ?", r.AppId, r.FlagKey).First(&existing).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&existing).Updates(merge(&existing)).Error
+	})
+}
+
+// Get 获取审核项，不存在时返回 nil
+func (u *review) Get(ctx context.Context, id string) (*model.FastgptReview, error) {
+	var r model.FastgptReview
+	err := u.WithContext(ctx).Where("id = ?", id).First(&r).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+// List 分页查询审核项，按待处理、处理中、已结束排序，同一状态内新的在前
+func (u *review) List(ctx context.Context, f ReviewFilter, offset, limit int) ([]model.FastgptReview, int64, error) {
+	var items []model.FastgptReview
+	var total int64
+
+	query := u.WithContext(ctx).Model(&model.FastgptReview{})
+	if f.AppIds != nil {
+		query = query.Where("app_id IN ?", f.AppIds)
+	}
+	if f.AppId != "" {
+		query = query.Where("app_id = ?", f.AppId)
+	}
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	if f.Source != "" {
+		query = query.Where("sources LIKE ?", "%"+f.Source+"%")
+	}
+	if f.Assignee != "" {
+		query = query.Where("assignee = ?", f.Assignee)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:  "CASE status WHEN ? THEN 0 WHEN ? THEN 1 ELSE 2 END, created_at DESC",
+		Vars: []interface{}{model.ReviewPending, model.ReviewInProgress},
+	}}).Offset(offset).Limit(limit).Find(&items).Error
+	return items, total, err
+}
+
+// Transition 仅当审核项处于 from 中的某个状态时更新，返回是否更新成功
+func (u *review) Transition(ctx context.Context, id string, from []string, updates map[string]interface{}) (bool, error) {
+	res := u.WithContext(ctx).Model(&model.FastgptReview{}).
+		Where("id = ? AND status IN ?", id, from).Updates(updates)
+	return res.RowsAffected == 1, res.Error
+}
+
+// Update 更新审核项
+func (u *review) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	return u.WithContext(ctx).Model(&model.FastgptReview{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// AddComment 添加评论
+func (u *review) AddComment(ctx context.Context, comment *model.FastgptReviewComment) error {
+	return u.WithContext(ctx).Create(comment).Error
+}
+
+// ListComments 审核项的全部评论，按时间正序
+func (u *review) ListComments(ctx context.Context, reviewId string) ([]model.FastgptReviewComment, error) {
+	var comments []model.FastgptReviewComment
+	err := u.WithContext(ctx).Where("review_id = ?", reviewId).Order("created_at ASC").Find(&comments).Error
+	return comments, err
+}
+
+// ListReviewers 应用的审核教师
+func (u *review) ListReviewers(ctx context.Context, appId string) ([]model.FastgptReviewer, error) {
+	var reviewers []model.FastgptReviewer
+	err := u.WithContext(ctx).Where("app_id = ?", appId).Order("created_at ASC").Find(&reviewers).Error
+	return reviewers, err
+}
+
+// AddReviewer 添加审核教师，已存在时忽略
+func (u *review) AddReviewer(ctx context.Context, reviewer *model.FastgptReviewer) error {
+	return u.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(reviewer).Error
+}
+
+// RemoveReviewer 移除审核教师
+func (u *review) RemoveReviewer(ctx context.Context, appId, staffId string) error {
+	return u.WithContext(ctx).Where("app_id = ? AND staff_id = ?", appId, staffId).Delete(&model.FastgptReviewer{}).Error
+}
+
+// ReviewerAppIds 教师负责审核的应用
+func (u *review) ReviewerAppIds(ctx context.Context, staffId string) ([]string, error) {
+	var appIds []string
+	err := u.WithContext(ctx).Model(&model.FastgptReviewer{}).Where("staff_id = ?", staffId).Pluck("app_id", &appIds).Error
+	return appIds, err
+}
@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type appRevision struct {
+	*gorm.DB
+}
+
+func (u *appRevision) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptAppRevision{})
+}
+
+// Create 以应用当前最大版本号加一保存版本，API Key 为明文，写入前加密
+func (u *appRevision) Create(ctx context.Context, rev *model.FastgptAppRevision) error {
+	plain := rev.APIKey
+	enc, err := FastgptApp.encryptKey(plain)
+	if err != nil {
+		return err
+	}
+	rev.APIKey = enc
+	defer func() { rev.APIKey = plain }()
+
+	return u.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var version int
+		if err := tx.Model(&model.FastgptAppRevision{}).Where("app_id = ?", rev.AppId).
+			Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+			return err
+		}
+		rev.Version = version + 1
+		return tx.Create(rev).Error
+	})
+}
+
+// List 按版本倒序分页获取应用的历史版本
+func (u *appRevision) List(ctx context.Context, appId string, offset, limit int) ([]model.FastgptAppRevision, int64, error) {
+	var total int64
+	query := u.WithContext(ctx).Model(&model.FastgptAppRevision{}).Where("app_id = ?", appId)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var revisions []model.FastgptAppRevision
+	if err := query.Order("version DESC").Offset(offset).Limit(limit).Find(&revisions).Error; err != nil {
+		return nil, 0, err
+	}
+	return revisions, total, nil
+}
+
+// Get 获取指定版本并解密 API Key，不存在时返回 nil
+func (u *appRevision) Get(ctx context.Context, appId string, version int) (*model.FastgptAppRevision, error) {
+	var rev model.FastgptAppRevision
+	err := u.WithContext(ctx).Where("app_id = ? AND version = ?", appId, version).First(&rev).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	apiKey, err := FastgptApp.keyring.Decrypt(rev.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	rev.APIKey = apiKey
+	return &rev, nil
+}
@@ -0,0 +1,48 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type appSchedule struct {
+	*gorm.DB
+}
+
+func (u *appSchedule) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptAppSchedule{})
+}
+
+// ListByApp 获取应用的全部时段
+func (u *appSchedule) ListByApp(ctx context.Context, appId string) ([]model.FastgptAppSchedule, error) {
+	var schedules []model.FastgptAppSchedule
+	err := u.WithContext(ctx).Where("app_id = ?", appId).Order("type ASC, created_at ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+// Get 获取时段，不存在时返回 nil
+func (u *appSchedule) Get(ctx context.Context, id string) (*model.FastgptAppSchedule, error) {
+	var schedule model.FastgptAppSchedule
+	err := u.WithContext(ctx).Where("id = ?", id).First(&schedule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// Create 创建时段
+func (u *appSchedule) Create(ctx context.Context, schedule *model.FastgptAppSchedule) error {
+	return u.WithContext(ctx).Create(schedule).Error
+}
+
+// Delete 删除时段
+func (u *appSchedule) Delete(ctx context.Context, id string) error {
+	return u.WithContext(ctx).Where("id = ?", id).Delete(&model.FastgptAppSchedule{}).Error
+}
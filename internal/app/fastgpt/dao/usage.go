@@ -0,0 +1,133 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type usage struct {
+	*gorm.DB
+}
+
+// UsageFilter 用量统计条件，StartDay/EndDay 格式为 2006-01-02，均包含在内
+type UsageFilter struct {
+	AppId       string
+	SubjectName string
+	StartDay    string
+	EndDay      string
+}
+
+// DailyActiveRow 每天每个科目的活跃学生数与消息数
+type DailyActiveRow struct {
+	Day         string
+	SubjectName string
+	Students    int64
+	Messages    int64
+}
+
+// HourlyRow 各小时的消息数
+type HourlyRow struct {
+	Hour     int
+	Messages int64
+	Errors   int64
+}
+
+// ErrorRateRow 每个科目的调用次数、失败次数、转发前被拒绝的次数与平均耗时
+type ErrorRateRow struct {
+	SubjectName  string
+	AppId        string
+	Messages     int64
+	Errors       int64
+	Rejected     int64
+	AvgLatencyMs float64 // 不含被拒绝的调用
+}
+
+// TopUserRow 使用最多的用户
+type TopUserRow struct {
+	UserId      string
+	StaffId     string
+	Messages    int64
+	ActiveDays  int64
+	InputChars  int64
+	OutputChars int64
+}
+
+func (u *usage) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptUsageEvent{})
+}
+
+// Create 记录一次调用
+func (u *usage) Create(ctx context.Context, event *model.FastgptUsageEvent) error {
+	return u.WithContext(ctx).Create(event).Error
+}
+
+func (u *usage) query(ctx context.Context, f UsageFilter) *gorm.DB {
+	query := u.WithContext(ctx).Model(&model.FastgptUsageEvent{})
+	if f.AppId != "" {
+		query = query.Where("app_id = ?", f.AppId)
+	}
+	if f.SubjectName != "" {
+		query = query.Where("subject_name = ?", f.SubjectName)
+	}
+	if f.StartDay != "" {
+		query = query.Where("day >= ?", f.StartDay)
+	}
+	if f.EndDay != "" {
+		query = query.Where("day <= ?", f.EndDay)
+	}
+	return query
+}
+
+// DailyActive 按天、科目统计活跃学生数，不含转发前被拒绝的调用
+func (u *usage) DailyActive(ctx context.Context, f UsageFilter) ([]DailyActiveRow, error) {
+	var rows []DailyActiveRow
+	err := u.query(ctx, f).
+		Where("status NOT IN ?", model.UsageRejectedStatuses).
+		Select("day, subject_name, COUNT(DISTINCT user_id) AS students, COUNT(*) AS messages").
+		Group("day, subject_name").
+		Order("day, subject_name").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Hourly 按一天中的小时统计消息数，不含转发前被拒绝的调用
+func (u *usage) Hourly(ctx context.Context, f UsageFilter) ([]HourlyRow, error) {
+	var rows []HourlyRow
+	err := u.query(ctx, f).
+		Where("status NOT IN ?", model.UsageRejectedStatuses).
+		Select("hour, COUNT(*) AS messages, COUNT(*) FILTER (WHERE status = ?) AS errors", model.ChatStatusError).
+		Group("hour").
+		Order("hour").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ErrorRates 按科目统计失败次数、被拒绝次数与平均耗时
+func (u *usage) ErrorRates(ctx context.Context, f UsageFilter) ([]ErrorRateRow, error) {
+	var rows []ErrorRateRow
+	err := u.query(ctx, f).
+		Select("subject_name, app_id, COUNT(*) AS messages, COUNT(*) FILTER (WHERE status = ?) AS errors, "+
+			"COUNT(*) FILTER (WHERE status IN ?) AS rejected, COALESCE(AVG(latency_ms) FILTER (WHERE status NOT IN ?), 0) AS avg_latency_ms",
+			model.ChatStatusError, model.UsageRejectedStatuses, model.UsageRejectedStatuses).
+		Group("subject_name, app_id").
+		Order("subject_name").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// TopUsers 消息数最多的用户，不含转发前被拒绝的调用
+func (u *usage) TopUsers(ctx context.Context, f UsageFilter, limit int) ([]TopUserRow, error) {
+	var rows []TopUserRow
+	err := u.query(ctx, f).
+		Where("status NOT IN ?", model.UsageRejectedStatuses).
+		Select("user_id, MAX(staff_id) AS staff_id, COUNT(*) AS messages, COUNT(DISTINCT day) AS active_days, " +
+			"SUM(input_chars) AS input_chars, SUM(output_chars) AS output_chars").
+		Group("user_id").
+		Order("messages DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,52 @@
+package dao_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/fastgpttest"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+func TestUsage_ExcludeRejected(t *testing.T) {
+	fastgpttest.Setup(t)
+	ctx := context.Background()
+	events := []struct {
+		userId string
+		status string
+	}{
+		{"uid1", model.ChatStatusSuccess},
+		{"uid1", model.ChatStatusError},
+		{"uid2", model.UsageStatusRateLimited},
+		{"uid2", model.UsageStatusBlocked},
+		{"uid3", model.UsageStatusUnavailable},
+	}
+	for _, e := range events {
+		if err := dao.Usage.Create(ctx, &model.FastgptUsageEvent{
+			AppId: "app", SubjectName: "高等数学", UserId: e.userId, Status: e.status,
+			Day: "2026-03-02", Hour: 9, RequestAt: time.Now(), LatencyMs: 100,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f := dao.UsageFilter{AppId: "app"}
+
+	daily, err := dao.Usage.DailyActive(ctx, f)
+	if err != nil || len(daily) != 1 || daily[0].Students != 1 || daily[0].Messages != 2 {
+		t.Errorf("DailyActive = %+v, %v", daily, err)
+	}
+	hourly, err := dao.Usage.Hourly(ctx, f)
+	if err != nil || len(hourly) != 1 || hourly[0].Messages != 2 || hourly[0].Errors != 1 {
+		t.Errorf("Hourly = %+v, %v", hourly, err)
+	}
+	top, err := dao.Usage.TopUsers(ctx, f, 10)
+	if err != nil || len(top) != 1 || top[0].UserId != "uid1" {
+		t.Errorf("TopUsers = %+v, %v", top, err)
+	}
+	rates, err := dao.Usage.ErrorRates(ctx, f)
+	if err != nil || len(rates) != 1 || rates[0].Messages != 5 || rates[0].Errors != 1 || rates[0].Rejected != 3 {
+		t.Errorf("ErrorRates = %+v, %v", rates, err)
+	}
+}
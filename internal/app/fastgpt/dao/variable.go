@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type variableSetting struct {
+	*gorm.DB
+}
+
+func (u *variableSetting) Init(db *gorm.DB) (err error) {
+	u.DB = db
+	return db.AutoMigrate(&model.FastgptVariableSetting{})
+}
+
+// GetByAppId 获取应用的对话变量设置，未配置时返回 nil
+func (u *variableSetting) GetByAppId(ctx context.Context, appId string) (*model.FastgptVariableSetting, error) {
+	var setting model.FastgptVariableSetting
+	err := u.WithContext(ctx).Where("app_id = ?", appId).First(&setting).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// Save 保存对话变量设置
+func (u *variableSetting) Save(ctx context.Context, setting *model.FastgptVariableSetting) error {
+	return u.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "app_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"injected", "allowed_client", "updated_by", "updated_at"}),
+	}).Create(setting).Error
+}
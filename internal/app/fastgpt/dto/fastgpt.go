@@ -8,9 +8,9 @@ type ChatCompletionRequest struct {
 	Detail       bool                   `json:"detail"`
 	Variables    map[string]interface{} `json:"variables"`
 	Messages     []Message              `json:"messages" binding:"Required"`
-	CustomUid    string                 `json:"customUid"`
-	ShareId      string                 `json:"shareId"`
-	OutLinkUid   string                 `json:"outLinkUid"`
+	CustomUid    string                 `json:"customUid"`  // 由服务端按登录用户填充，传入的值被忽略
+	ShareId      string                 `json:"shareId"`    // 由服务端填充为应用的分享链接 ID，传入的值被忽略
+	OutLinkUid   string                 `json:"outLinkUid"` // 由服务端按登录用户填充，传入的值被忽略
 }
 
 type Message struct {
@@ -25,8 +25,8 @@ type GetHistoriesRequest struct {
 	Offset       int    `json:"offset"`
 	PageSize     int    `json:"pageSize"`
 	Source       string `json:"source"`
-	ShareId      string `json:"shareId"`
-	OutLinkUid   string `json:"outLinkUid"`
+	ShareId      string `json:"shareId"`    // 由服务端填充为应用的分享链接 ID
+	OutLinkUid   string `json:"outLinkUid"` // 由服务端按登录用户填充，只返回当前用户的会话
 }
 
 // UpdateHistoryRequest 更新聊天会话请求
@@ -125,6 +125,9 @@ type CreateAppRequest struct {
 	ShareId     string `json:"shareId"`
 	APIKey      string `json:"apiKey" binding:"Required"`
 	Description string `json:"description"`
+	Provider    string `json:"provider"` // fastgpt（默认）/ openai
+	BaseURL     string `json:"baseUrl"`  // OpenAI 兼容接口地址
+	Model       string `json:"model"`    // OpenAI 兼容接口使用的模型
 }
 
 // UpdateAppRequest 更新应用请求
@@ -136,6 +139,9 @@ type UpdateAppRequest struct {
 	APIKey      string `json:"apiKey"`
 	Description string `json:"description"`
 	Status      *int   `json:"status"`
+	Provider    string `json:"provider"`
+	BaseURL     string `json:"baseUrl"`
+	Model       string `json:"model"`
 }
 
 // DeleteAppRequest 删除应用请求
@@ -149,17 +155,25 @@ type GetAppListRequest struct {
 	Limit  int `json:"limit"`
 }
 
-// AppItem 应用列表项
+// AppItem 应用列表项，API Key 只返回脱敏值和指纹
 type AppItem struct {
-	ID          string `json:"id"`
-	AppName     string `json:"appName"`
-	AppId       string `json:"appId"`
-	ShareId     string `json:"shareId"`
-	APIKey      string `json:"apiKey"`
-	Description string `json:"description"`
-	CreatedBy   string `json:"createdBy"`
-	CreatedAt   string `json:"createdAt"`
-	UpdatedAt   string `json:"updatedAt"`
+	ID                string `json:"id"`
+	AppName           string `json:"appName"`
+	AppId             string `json:"appId"`
+	ShareId           string `json:"shareId"`
+	APIKeyMask        string `json:"apiKeyMask"`
+	APIKeyFingerprint string `json:"apiKeyFingerprint"`
+	Description       string `json:"description"`
+	Provider          string `json:"provider"`
+	BaseURL           string `json:"baseUrl"`
+	Model             string `json:"model"`
+	Status            int    `json:"status"` // 1 启用 0 停用
+	CreatedBy         string `json:"createdBy"`
+	CreatedAt         string `json:"createdAt"`
+	UpdatedAt         string `json:"updatedAt"`
+	HealthStatus      string `json:"healthStatus"`    // 健康检查状态，空表示尚未检查
+	HealthError       string `json:"healthError"`     // 最近一次检查失败的原因
+	HealthCheckedAt   string `json:"healthCheckedAt"` // 最近一次检查时间
 }
 
 // AppListResponse 应用列表响应
@@ -175,10 +189,159 @@ type CreateAppResponse struct {
 
 // SSEMessage SSE 消息结构（用于流式输出）
 type SSEMessage struct {
+	Id    string `json:"id,omitempty"` // 可续传的事件 ID，重连时作为 Last-Event-ID 带回
 	Data  string `json:"data"`
 	Event string `json:"event,omitempty"`
 }
 
+// EventId 实现 sse.Identifiable
+func (m *SSEMessage) EventId() string {
+	return m.Id
+}
+
+// SSEErrorData error 事件的 data 内容
+type SSEErrorData struct {
+	Error      string `json:"error"`
+	Code       int    `json:"code,omitempty"`
+	RetryAfter int    `json:"retryAfter,omitempty"` // 秒
+	// AvailableAt 应用暂不可用时预计恢复的时间
+	AvailableAt string `json:"availableAt,omitempty"`
+}
+
+// SSEQueueData queue 事件的 data 内容，应用的流式对话数已满时排队，位置变化时发送
+type SSEQueueData struct {
+	Position      int `json:"position"`      // 从 1 开始
+	EstimatedWait int `json:"estimatedWait"` // 预计等待秒数
+}
+
+// SSEStoppedData stopped 事件的 data 内容，用户停止生成后发送，随后流结束
+type SSEStoppedData struct {
+	ChatId string `json:"chatId"`
+}
+
+// StopChatRequest 停止流式生成请求
+type StopChatRequest struct {
+	ChatId string `json:"chatId" binding:"Required"`
+}
+
+// WebSocket 客户端指令类型
+const (
+	WSCommandChat       = "chat"       // 提问，request 与流式接口的请求体相同
+	WSCommandRegenerate = "regenerate" // 停止该会话进行中的回答后重新提问，不使用缓存的回答
+	WSCommandStop       = "stop"       // 停止生成，chatId 为空时停止当前连接上的生成
+	WSCommandResume     = "resume"     // 按 lastEventId 续传
+	WSCommandTyping     = "typing"     // 用户正在输入，仅用于保持连接
+	WSCommandPing       = "ping"       // 应用层心跳，服务端回复 pong 事件
+)
+
+// WSCommand WebSocket 客户端发送的指令，服务端推送的消息格式与 SSEMessage 相同
+type WSCommand struct {
+	Type        string                 `json:"type"`
+	Request     *ChatCompletionRequest `json:"request,omitempty"`
+	ChatId      string                 `json:"chatId,omitempty"`
+	LastEventId string                 `json:"lastEventId,omitempty"`
+}
+
+// QuotaExceededData 超出配额时返回的数据
+type QuotaExceededData struct {
+	RetryAfter int `json:"retryAfter"` // 秒
+}
+
+// === 对话配额相关 DTO ===
+
+// AppUnavailableData 应用停用或不在开放时间时返回的数据
+type AppUnavailableData struct {
+	AvailableAt string `json:"availableAt,omitempty"` // 预计恢复可用的时间，无法确定时为空
+}
+
+// GetAppQuotaRequest 获取应用配额请求
+type GetAppQuotaRequest struct {
+	ID string `json:"id" binding:"Required"`
+}
+
+// UpdateAppQuotaRequest 更新应用配额请求
+// 字段为空表示不修改，0 表示不限制，负数表示恢复为默认值
+type UpdateAppQuotaRequest struct {
+	ID                string `json:"id" binding:"Required"`
+	UserRPM           *int   `json:"userRpm"`
+	UserDailyMessages *int   `json:"userDailyMessages"`
+	AppConcurrency    *int   `json:"appConcurrency"`
+	AppStreams        *int   `json:"appStreams"`
+}
+
+// AppQuotaItem 配额
+type AppQuotaItem struct {
+	UserRPM           int `json:"userRpm"`
+	UserDailyMessages int `json:"userDailyMessages"`
+	AppConcurrency    int `json:"appConcurrency"`
+	AppStreams        int `json:"appStreams"`
+}
+
+// AppQuotaResponse 应用配额响应
+type AppQuotaResponse struct {
+	ID        string       `json:"id"`
+	Effective AppQuotaItem `json:"effective"`
+	Default   AppQuotaItem `json:"default"`
+}
+
+// === 回答缓存相关 DTO ===
+
+// AnswerCacheRequest 获取回答缓存设置或清除缓存请求
+type AnswerCacheRequest struct {
+	ID string `json:"id" binding:"Required"`
+}
+
+// UpdateAnswerCacheRequest 更新回答缓存设置，字段为空表示不修改
+// TTLSeconds 为 0 时使用默认值；Variables 为参与缓存键的变量名
+type UpdateAnswerCacheRequest struct {
+	ID         string   `json:"id" binding:"Required"`
+	Enabled    *bool    `json:"enabled"`
+	TTLSeconds *int     `json:"ttlSeconds"`
+	Variables  []string `json:"variables"`
+}
+
+// AnswerCacheResponse 回答缓存设置
+type AnswerCacheResponse struct {
+	ID         string   `json:"id"`
+	Enabled    bool     `json:"enabled"`
+	TTLSeconds int      `json:"ttlSeconds"` // 实际生效的缓存时间
+	Variables  []string `json:"variables"`
+}
+
+// InvalidateAnswerCacheResponse 清除回答缓存结果
+type InvalidateAnswerCacheResponse struct {
+	Removed int `json:"removed"`
+}
+
+// === 对话变量相关 DTO ===
+
+// AppVariablesRequest 获取对话变量设置请求
+type AppVariablesRequest struct {
+	ID string `json:"id" binding:"Required"`
+}
+
+// InjectedVariableItem 服务端注入的变量，Source 见 AppVariablesResponse.Sources
+type InjectedVariableItem struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// UpdateAppVariablesRequest 更新对话变量设置，字段为空表示不修改
+type UpdateAppVariablesRequest struct {
+	ID            string                 `json:"id" binding:"Required"`
+	Injected      []InjectedVariableItem `json:"injected"`
+	AllowedClient []string               `json:"allowedClient"`
+}
+
+// AppVariablesResponse 对话变量设置，未配置时客户端变量原样转发
+type AppVariablesResponse struct {
+	ID            string                 `json:"id"`
+	Configured    bool                   `json:"configured"`
+	Injected      []InjectedVariableItem `json:"injected"`
+	AllowedClient []string               `json:"allowedClient"`
+	Sources       []string               `json:"sources"` // 可选的取值来源
+}
+
 // GetCollectionQuoteRequest 获取集合引用请求
 type GetCollectionQuoteRequest struct {
 	FastgptAppId   string `json:"fastgptAppId" binding:"Required"` // 用于获取 API Key
@@ -189,13 +352,483 @@ type GetCollectionQuoteRequest struct {
 	ChatItemDataId string `json:"chatItemDataId"`
 	ChatId         string `json:"chatId"`
 	AppId          string `json:"appId"`
-	ShareId        string `json:"shareId"`
-	OutLinkUid     string `json:"outLinkUid"`
+	ShareId        string `json:"shareId"`    // 由服务端填充为应用的分享链接 ID
+	OutLinkUid     string `json:"outLinkUid"` // 由服务端按登录用户填充
 }
 
 // OutLinkInitRequest 外链聊天初始化请求
 type OutLinkInitRequest struct {
 	ChatId     string `form:"chatId"`
 	ShareId    string `form:"shareId" binding:"Required"`
-	OutLinkUid string `form:"outLinkUid"`
+	OutLinkUid string `form:"outLinkUid"` // 由服务端按登录用户填充
+}
+
+// === 本地聊天记录相关 DTO ===
+
+// ListChatRecordsRequest 查询本地聊天记录请求（管理员）
+// StartTime/EndTime 格式为 2006-01-02 15:04:05
+type ListChatRecordsRequest struct {
+	FastgptAppId string `json:"fastgptAppId"`
+	ChatId       string `json:"chatId"`
+	StaffId      string `json:"staffId"`
+	Status       string `json:"status"`
+	StartTime    string `json:"startTime"`
+	EndTime      string `json:"endTime"`
+	Offset       int    `json:"offset"`
+	Limit        int    `json:"limit"`
+}
+
+// ChatSessionItem 会话列表项
+type ChatSessionItem struct {
+	ID            string `json:"id"`
+	FastgptAppId  string `json:"fastgptAppId"`
+	ChatId        string `json:"chatId"`
+	UserId        string `json:"userId"`
+	StaffId       string `json:"staffId"`
+	Title         string `json:"title"`
+	MessageCount  int    `json:"messageCount"`
+	LastMessageAt string `json:"lastMessageAt"`
+}
+
+// ChatSessionListResponse 会话列表响应
+type ChatSessionListResponse struct {
+	Sessions []ChatSessionItem `json:"sessions"`
+	Total    int64             `json:"total"`
+}
+
+// ChatMessageItem 问答记录列表项
+type ChatMessageItem struct {
+	ID           string `json:"id"`
+	FastgptAppId string `json:"fastgptAppId"`
+	ChatId       string `json:"chatId"`
+	UserId       string `json:"userId"`
+	StaffId      string `json:"staffId"`
+	Question     string `json:"question"`
+	Answer       string `json:"answer"`
+	Stream       bool   `json:"stream"`
+	Status       string `json:"status"`
+	ErrorMsg     string `json:"errorMsg,omitempty"`
+	RequestAt    string `json:"requestAt"`
+	FinishedAt   string `json:"finishedAt,omitempty"`
+	LatencyMs    int64  `json:"latencyMs"`
+	FirstChunkMs int64  `json:"firstChunkMs"`
+}
+
+// ChatMessageListResponse 问答记录列表响应
+type ChatMessageListResponse struct {
+	Messages []ChatMessageItem `json:"messages"`
+	Total    int64             `json:"total"`
+}
+
+// === 内容安全相关 DTO ===
+
+// ListSensitiveWordsRequest 敏感词列表请求
+type ListSensitiveWordsRequest struct {
+	SubjectName *string `json:"subjectName"` // 为空不筛选，"" 表示只看全局
+	Keyword     string  `json:"keyword"`
+	Offset      int     `json:"offset"`
+	Limit       int     `json:"limit"`
+}
+
+// SensitiveWordItem 敏感词列表项
+type SensitiveWordItem struct {
+	ID          string `json:"id"`
+	Word        string `json:"word"`
+	SubjectName string `json:"subjectName"`
+	Action      string `json:"action"`
+	CreatedBy   string `json:"createdBy"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// SensitiveWordListResponse 敏感词列表响应
+type SensitiveWordListResponse struct {
+	Words []SensitiveWordItem `json:"words"`
+	Total int64               `json:"total"`
+}
+
+// CreateSensitiveWordsRequest 批量添加敏感词请求，已存在的词更新处理方式
+type CreateSensitiveWordsRequest struct {
+	Words       []string `json:"words" binding:"Required"`
+	SubjectName string   `json:"subjectName"` // 为空表示全局
+	Action      string   `json:"action"`      // block / mask / review，默认 mask
+}
+
+// DeleteSensitiveWordsRequest 删除敏感词请求
+type DeleteSensitiveWordsRequest struct {
+	Ids []string `json:"ids" binding:"Required"`
+}
+
+// ListModerationHitsRequest 命中记录列表请求
+type ListModerationHitsRequest struct {
+	FastgptAppId string `json:"fastgptAppId"`
+	StaffId      string `json:"staffId"`
+	Direction    string `json:"direction"` // input / output
+	Offset       int    `json:"offset"`
+	Limit        int    `json:"limit"`
+}
+
+// ModerationHitItem 命中记录列表项
+type ModerationHitItem struct {
+	ID           string `json:"id"`
+	FastgptAppId string `json:"fastgptAppId"`
+	ChatId       string `json:"chatId"`
+	UserId       string `json:"userId"`
+	StaffId      string `json:"staffId"`
+	Direction    string `json:"direction"`
+	Action       string `json:"action"`
+	Keywords     string `json:"keywords"`
+	Excerpt      string `json:"excerpt"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// ModerationHitListResponse 命中记录列表响应
+type ModerationHitListResponse struct {
+	Hits  []ModerationHitItem `json:"hits"`
+	Total int64               `json:"total"`
+}
+
+// ListDocumentsRequest 课程资料列表请求，按应用或科目筛选
+type ListDocumentsRequest struct {
+	FastgptAppId string `json:"fastgptAppId"`
+	SubjectName  string `json:"subjectName"`
+	DatasetId    string `json:"datasetId"`
+	Offset       int    `json:"offset"`
+	Limit        int    `json:"limit"`
+}
+
+// DocumentIdRequest 按ID查询或删除课程资料
+type DocumentIdRequest struct {
+	Id string `json:"id"`
+}
+
+// DocumentItem 课程资料，Progress 为处理进度百分比
+type DocumentItem struct {
+	ID           string `json:"id"`
+	FastgptAppId string `json:"fastgptAppId"`
+	SubjectName  string `json:"subjectName"`
+	DatasetId    string `json:"datasetId"`
+	CollectionId string `json:"collectionId"`
+	FileName     string `json:"fileName"`
+	FileSize     int64  `json:"fileSize"`
+	FileType     string `json:"fileType"`
+	Status       string `json:"status"` // processing / success / error
+	Stage        string `json:"stage"`  // extract / upload / push / done
+	TotalChunks  int    `json:"totalChunks"`
+	PushedChunks int    `json:"pushedChunks"`
+	Progress     int    `json:"progress"`
+	ErrorMsg     string `json:"errorMsg,omitempty"`
+	UploadedBy   string `json:"uploadedBy"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// DocumentListResponse 课程资料列表响应
+type DocumentListResponse struct {
+	Documents []DocumentItem `json:"documents"`
+	Total     int64          `json:"total"`
+}
+
+// AnalyticsRequest 用量统计请求（管理员）
+// StartDate/EndDate 格式为 2006-01-02，均包含在内，默认最近 30 天
+type AnalyticsRequest struct {
+	FastgptAppId string `json:"fastgptAppId"`
+	SubjectName  string `json:"subjectName"`
+	StartDate    string `json:"startDate"`
+	EndDate      string `json:"endDate"`
+	Limit        int    `json:"limit"` // 仅活跃用户排行使用，默认 20
+}
+
+// DailyActiveItem 每日活跃学生数
+type DailyActiveItem struct {
+	Date        string `json:"date"`
+	SubjectName string `json:"subjectName"`
+	Students    int64  `json:"students"`
+	Messages    int64  `json:"messages"`
+}
+
+// HourlyMessagesItem 一天中各小时的消息数
+type HourlyMessagesItem struct {
+	Hour     int   `json:"hour"`
+	Messages int64 `json:"messages"`
+	Errors   int64 `json:"errors"`
+}
+
+// ErrorRateItem 每个科目的失败率
+type ErrorRateItem struct {
+	SubjectName  string  `json:"subjectName"`
+	FastgptAppId string  `json:"fastgptAppId"`
+	Messages     int64   `json:"messages"`
+	Errors       int64   `json:"errors"`
+	ErrorRate    float64 `json:"errorRate"`
+	Rejected     int64   `json:"rejected"` // 超出配额、命中拦截词或不在开放时间而未转发的调用
+	AvgLatencyMs int64   `json:"avgLatencyMs"`
+}
+
+// TopUserItem 活跃用户排行
+type TopUserItem struct {
+	UserId      string `json:"userId"`
+	StaffId     string `json:"staffId"`
+	Messages    int64  `json:"messages"`
+	ActiveDays  int64  `json:"activeDays"`
+	InputChars  int64  `json:"inputChars"`
+	OutputChars int64  `json:"outputChars"`
+}
+
+// AnalyticsResponse 用量统计响应，Items 为对应指标的列表
+type AnalyticsResponse struct {
+	StartDate string      `json:"startDate"`
+	EndDate   string      `json:"endDate"`
+	Items     interface{} `json:"items"`
+}
+
+// CreateFeedbackRequest 评价一条回答，Rating 为 up 或 down
+type CreateFeedbackRequest struct {
+	FastgptAppId string `json:"fastgptAppId"`
+	ChatId       string `json:"chatId"`
+	DataId       string `json:"dataId"`
+	Rating       string `json:"rating"`
+	Reason       string `json:"reason"`
+}
+
+// ListFeedbackRequest 回答评价列表请求（管理员）
+type ListFeedbackRequest struct {
+	FastgptAppId string `json:"fastgptAppId"`
+	SubjectName  string `json:"subjectName"`
+	ChatId       string `json:"chatId"`
+	Rating       string `json:"rating"`
+	Offset       int    `json:"offset"`
+	Limit        int    `json:"limit"`
+}
+
+// FeedbackItem 回答评价列表项
+type FeedbackItem struct {
+	ID           string `json:"id"`
+	FastgptAppId string `json:"fastgptAppId"`
+	SubjectName  string `json:"subjectName"`
+	ChatId       string `json:"chatId"`
+	DataId       string `json:"dataId"`
+	UserId       string `json:"userId"`
+	StaffId      string `json:"staffId"`
+	Rating       string `json:"rating"`
+	Reason       string `json:"reason"`
+	Forwarded    bool   `json:"forwarded"`
+	ForwardError string `json:"forwardError,omitempty"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+// FeedbackListResponse 回答评价列表响应
+type FeedbackListResponse struct {
+	Feedbacks []FeedbackItem `json:"feedbacks"`
+	Total     int64          `json:"total"`
+}
+
+// ListAppSchedulesRequest 获取应用开放时段请求
+type ListAppSchedulesRequest struct {
+	AppId string `json:"appId" binding:"Required"` // 本系统应用ID
+}
+
+// CreateAppScheduleRequest 创建应用时段请求
+// type 为 open 时填写 weekdays、startTime、endTime；为 blackout 时填写 startAt、endAt
+type CreateAppScheduleRequest struct {
+	AppId     string `json:"appId" binding:"Required"`
+	Type      string `json:"type" binding:"Required"`
+	Weekdays  []int  `json:"weekdays"`  // 1-7 表示周一至周日
+	StartTime string `json:"startTime"` // HH:MM
+	EndTime   string `json:"endTime"`   // HH:MM，早于开始时间表示跨午夜
+	StartAt   string `json:"startAt"`   // 2006-01-02 15:04:05
+	EndAt     string `json:"endAt"`     // 2006-01-02 15:04:05
+	Message   string `json:"message"`   // 不可用时向学生展示的提示，为空时自动生成
+}
+
+// DeleteAppScheduleRequest 删除应用时段请求
+type DeleteAppScheduleRequest struct {
+	ID string `json:"id" binding:"Required"`
+}
+
+// AppScheduleItem 应用时段
+type AppScheduleItem struct {
+	ID        string `json:"id"`
+	AppId     string `json:"appId"`
+	Type      string `json:"type"`
+	Weekdays  []int  `json:"weekdays,omitempty"`
+	StartTime string `json:"startTime,omitempty"`
+	EndTime   string `json:"endTime,omitempty"`
+	StartAt   string `json:"startAt,omitempty"`
+	EndAt     string `json:"endAt,omitempty"`
+	Message   string `json:"message"`
+	CreatedBy string `json:"createdBy"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// AppScheduleListResponse 应用时段列表响应，附带应用当前是否可用
+type AppScheduleListResponse struct {
+	Schedules   []AppScheduleItem `json:"schedules"`
+	Available   bool              `json:"available"`
+	Message     string            `json:"message,omitempty"`
+	AvailableAt string            `json:"availableAt,omitempty"`
+}
+
+// ListAppRevisionsRequest 获取应用配置历史请求
+type ListAppRevisionsRequest struct {
+	AppId  string `json:"appId" binding:"Required"` // 本系统应用ID
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+// RollbackAppRequest 回滚应用配置请求
+type RollbackAppRequest struct {
+	AppId   string `json:"appId" binding:"Required"`
+	Version int    `json:"version" binding:"Required"`
+}
+
+// AppFieldChange 配置字段变化，API Key 为脱敏值与指纹
+type AppFieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// AppRevisionItem 应用配置的一个历史版本
+type AppRevisionItem struct {
+	Version   int              `json:"version"`
+	Action    string           `json:"action"` // create / update / delete / rollback
+	Changes   []AppFieldChange `json:"changes"`
+	Operator  string           `json:"operator"`
+	Comment   string           `json:"comment"`
+	CreatedAt string           `json:"createdAt"`
+}
+
+// AppRevisionListResponse 应用配置历史响应
+type AppRevisionListResponse struct {
+	Revisions []AppRevisionItem `json:"revisions"`
+	Total     int64             `json:"total"`
+}
+
+// ExportChatRequest 导出会话请求，Format 为 markdown、json 或 xlsx，默认 markdown
+type ExportChatRequest struct {
+	FastgptAppId string `json:"fastgptAppId"`
+	ChatId       string `json:"chatId"`
+	Format       string `json:"format"`
+}
+
+// ListReviewsRequest 审核队列请求，Mine 为 true 时只看指派给自己的
+type ListReviewsRequest struct {
+	FastgptAppId string `json:"fastgptAppId"`
+	Status       string `json:"status"` // pending / in_progress / resolved / dismissed
+	Source       string `json:"source"` // student / keyword / low_similarity
+	Mine         bool   `json:"mine"`
+	Offset       int    `json:"offset"`
+	Limit        int    `json:"limit"`
+}
+
+// ReviewIdRequest 按ID操作审核项
+type ReviewIdRequest struct {
+	ID string `json:"id" binding:"Required"`
+}
+
+// AssignReviewRequest 指派审核项，Assignee 为空时指派给自己
+type AssignReviewRequest struct {
+	ID       string `json:"id" binding:"Required"`
+	Assignee string `json:"assignee"` // 学工号
+}
+
+// CommentReviewRequest 评论审核项
+type CommentReviewRequest struct {
+	ID      string `json:"id" binding:"Required"`
+	Content string `json:"content" binding:"Required"`
+}
+
+// ResolveReviewRequest 给出修正回答，填写 CollectionId 时同时写入 FastGPT 知识库
+// 非管理员只能写入应用上传过课程资料的知识库中的集合
+type ResolveReviewRequest struct {
+	ID              string `json:"id" binding:"Required"`
+	CorrectedAnswer string `json:"correctedAnswer" binding:"Required"`
+	CollectionId    string `json:"collectionId"`
+}
+
+// DismissReviewRequest 忽略审核项，Comment 会作为评论保存
+type DismissReviewRequest struct {
+	ID      string `json:"id" binding:"Required"`
+	Comment string `json:"comment"`
+}
+
+// PushReviewRequest 将已解决审核项的修正回答写入 FastGPT 知识库
+type PushReviewRequest struct {
+	ID           string `json:"id" binding:"Required"`
+	CollectionId string `json:"collectionId"` // 为空时使用上次写入的集合，非管理员只能使用应用知识库中的集合
+}
+
+// ReviewerRequest 应用审核教师请求（管理员），list 时只需 FastgptAppId
+type ReviewerRequest struct {
+	FastgptAppId string `json:"fastgptAppId" binding:"Required"`
+	StaffId      string `json:"staffId"`
+}
+
+// ReviewItem 审核项
+type ReviewItem struct {
+	ID              string   `json:"id"`
+	FastgptAppId    string   `json:"fastgptAppId"`
+	SubjectName     string   `json:"subjectName"`
+	ChatId          string   `json:"chatId"`
+	DataId          string   `json:"dataId,omitempty"`
+	StaffId         string   `json:"staffId"`
+	Question        string   `json:"question"`
+	Answer          string   `json:"answer"`
+	Sources         []string `json:"sources"`
+	Reason          string   `json:"reason"`
+	QuoteScore      *float64 `json:"quoteScore,omitempty"`
+	FlagCount       int      `json:"flagCount"`
+	Status          string   `json:"status"`
+	Assignee        string   `json:"assignee"`
+	CorrectedAnswer string   `json:"correctedAnswer,omitempty"`
+	CollectionId    string   `json:"collectionId,omitempty"`
+	PushedAt        string   `json:"pushedAt,omitempty"`
+	PushError       string   `json:"pushError,omitempty"`
+	ResolvedBy      string   `json:"resolvedBy,omitempty"`
+	ResolvedAt      string   `json:"resolvedAt,omitempty"`
+	CreatedAt       string   `json:"createdAt"`
+	UpdatedAt       string   `json:"updatedAt"`
+}
+
+// ReviewComment 审核评论
+type ReviewComment struct {
+	ID        string `json:"id"`
+	StaffId   string `json:"staffId"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// ReviewDetailResponse 审核项详情
+type ReviewDetailResponse struct {
+	ReviewItem
+	Comments []ReviewComment `json:"comments"`
+}
+
+// ReviewListResponse 审核队列响应
+type ReviewListResponse struct {
+	Reviews []ReviewItem `json:"reviews"`
+	Total   int64        `json:"total"`
+}
+
+// ReviewerItem 审核教师
+type ReviewerItem struct {
+	StaffId   string `json:"staffId"`
+	CreatedBy string `json:"createdBy"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// QueueStatItem 应用的流式对话排队统计，计数为当前实例启动以来的累计值
+type QueueStatItem struct {
+	FastgptAppId  string `json:"fastgptAppId"`
+	AppName       string `json:"appName"`
+	Limit         int    `json:"limit"`  // AppStreams，未设置时为 AppConcurrency，0 表示不限制
+	Active        int    `json:"active"` // 进行中的流式对话
+	Waiting       int    `json:"waiting"`
+	PeakWaiting   int    `json:"peakWaiting"`
+	Admitted      int64  `json:"admitted"`
+	Queued        int64  `json:"queued"`
+	TimedOut      int64  `json:"timedOut"`
+	Rejected      int64  `json:"rejected"`
+	Cancelled     int64  `json:"cancelled"`
+	AvgWaitMs     int64  `json:"avgWaitMs"`
+	AvgDurationMs int64  `json:"avgDurationMs"`
 }
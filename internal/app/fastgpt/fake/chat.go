@@ -0,0 +1,401 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type chatMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type chatRequest struct {
+	ChatId     string                 `json:"chatId"`
+	Stream     bool                   `json:"stream"`
+	Detail     bool                   `json:"detail"`
+	Variables  map[string]interface{} `json:"variables"`
+	Messages   []chatMessage          `json:"messages"`
+	ShareId    string                 `json:"shareId"`
+	OutLinkUid string                 `json:"outLinkUid"`
+}
+
+// question 最后一条用户消息的文本，兼容字符串与多段内容两种格式
+func (req *chatRequest) question() string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		m := req.Messages[i]
+		if m.Role != "user" {
+			continue
+		}
+		switch content := m.Content.(type) {
+		case string:
+			return content
+		case []interface{}:
+			text := ""
+			for _, part := range content {
+				if p, ok := part.(map[string]interface{}); ok && p["type"] == "text" {
+					t, _ := p["text"].(string)
+					text += t
+				}
+			}
+			return text
+		}
+	}
+	return ""
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "messages is empty")
+		return
+	}
+
+	s.mu.Lock()
+	question := req.question()
+	answer := s.answer(question)
+	chunkRunes, chunkDelay := s.chunkRunes, s.chunkDelay
+	dataId := s.recordChat(r, &req, question, answer)
+	s.mu.Unlock()
+
+	if !req.Stream {
+		resp := map[string]interface{}{
+			"id":    req.ChatId,
+			"model": "",
+			"usage": map[string]int{"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 1},
+			"choices": []map[string]interface{}{{
+				"message":       map[string]string{"role": "assistant", "content": answer},
+				"finish_reason": "stop",
+				"index":         0,
+			}},
+		}
+		if req.Detail {
+			resp["responseData"] = []interface{}{}
+			resp["dataId"] = dataId
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	send := func(delta map[string]string, finishReason interface{}) {
+		chunk, _ := json.Marshal(map[string]interface{}{
+			"id":      "",
+			"object":  "",
+			"created": 0,
+			"model":   "",
+			"choices": []map[string]interface{}{{
+				"delta":         delta,
+				"index":         0,
+				"finish_reason": finishReason,
+			}},
+		})
+		if req.Detail {
+			fmt.Fprint(w, "event: answer\n")
+		}
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	limit := cutoff(r.Context())
+	runes := []rune(answer)
+	sent := 0
+	for start := 0; start < len(runes); start += chunkRunes {
+		if limit > 0 && sent >= limit {
+			// 中止连接，模拟上游中途断开
+			panic(http.ErrAbortHandler)
+		}
+		if chunkDelay > 0 && sent > 0 {
+			select {
+			case <-time.After(chunkDelay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		end := start + chunkRunes
+		if end > len(runes) {
+			end = len(runes)
+		}
+		send(map[string]string{"role": "assistant", "content": string(runes[start:end])}, nil)
+		sent++
+	}
+	if limit > 0 && sent >= limit {
+		panic(http.ErrAbortHandler)
+	}
+	send(map[string]string{}, "stop")
+	if req.Detail {
+		fmt.Fprint(w, "event: answer\n")
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// recordChat 带 chatId 的对话写入会话记录，返回回答的 dataId
+func (s *Server) recordChat(r *http.Request, req *chatRequest, question, answer string) string {
+	st := s.store
+	dataId := st.nextId()
+	if req.ChatId == "" {
+		return dataId
+	}
+	appId := st.appIdFor(r)
+	if sh, ok := st.shares[req.ShareId]; ok {
+		appId = sh.appId
+	}
+	c, ok := st.chats[req.ChatId]
+	if !ok {
+		title := []rune(question)
+		if len(title) > 20 {
+			title = title[:20]
+		}
+		c = &chat{
+			chatId:     req.ChatId,
+			appId:      appId,
+			title:      string(title),
+			shareId:    req.ShareId,
+			outLinkUid: req.OutLinkUid,
+		}
+		st.chats[req.ChatId] = c
+	}
+	now := time.Now()
+	c.updateTime = now
+	c.records = append(c.records,
+		&record{id: st.nextId(), dataId: st.nextId(), obj: "Human", content: question, time: now},
+		&record{id: st.nextId(), dataId: dataId, obj: "AI", content: answer, time: now},
+	)
+	return dataId
+}
+
+func (s *Server) handleGetHistories(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AppId      string `json:"appId"`
+		Offset     int    `json:"offset"`
+		PageSize   int    `json:"pageSize"`
+		ShareId    string `json:"shareId"`
+		OutLinkUid string `json:"outLinkUid"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	appId := req.AppId
+	if appId == "" {
+		appId = s.store.appIdFor(r)
+	}
+	if sh, ok := s.store.shares[req.ShareId]; ok {
+		appId = sh.appId
+	}
+	var chats []*chat
+	for _, c := range s.store.chats {
+		if c.appId != appId || (req.OutLinkUid != "" && c.outLinkUid != req.OutLinkUid) {
+			continue
+		}
+		chats = append(chats, c)
+	}
+	sort.Slice(chats, func(i, j int) bool {
+		if chats[i].top != chats[j].top {
+			return chats[i].top
+		}
+		return chats[i].updateTime.After(chats[j].updateTime)
+	})
+
+	start, end := page(len(chats), req.Offset, req.PageSize)
+	list := make([]map[string]interface{}, 0, end-start)
+	for _, c := range chats[start:end] {
+		list = append(list, map[string]interface{}{
+			"chatId":      c.chatId,
+			"appId":       c.appId,
+			"title":       c.title,
+			"customTitle": c.customTitle,
+			"top":         c.top,
+			"updateTime":  c.updateTime,
+		})
+	}
+	writeData(w, map[string]interface{}{"list": list, "total": len(chats)})
+}
+
+func (s *Server) handleUpdateHistory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AppId       string `json:"appId"`
+		ChatId      string `json:"chatId"`
+		CustomTitle string `json:"customTitle"`
+		Top         *bool  `json:"top"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.store.chats[req.ChatId]
+	if !ok || c.appId != req.AppId {
+		writeError(w, http.StatusNotFound, "chat not exist")
+		return
+	}
+	if req.CustomTitle != "" {
+		c.customTitle = req.CustomTitle
+	}
+	if req.Top != nil {
+		c.top = *req.Top
+	}
+	c.updateTime = time.Now()
+	writeData(w, nil)
+}
+
+func (s *Server) handleDelHistory(w http.ResponseWriter, r *http.Request) {
+	chatId := r.URL.Query().Get("chatId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.store.chats[chatId]
+	if !ok {
+		writeData(w, nil)
+		return
+	}
+	if outLinkUid := r.URL.Query().Get("outLinkUid"); outLinkUid != "" && c.outLinkUid != outLinkUid {
+		writeError(w, http.StatusForbidden, "unAuthChat")
+		return
+	}
+	delete(s.store.chats, chatId)
+	writeData(w, nil)
+}
+
+func (s *Server) handleGetPaginationRecords(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AppId    string `json:"appId"`
+		ChatId   string `json:"chatId"`
+		Offset   int    `json:"offset"`
+		PageSize int    `json:"pageSize"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []*record
+	if c, ok := s.store.chats[req.ChatId]; ok && (req.AppId == "" || c.appId == req.AppId) {
+		records = c.records
+	}
+	start, end := page(len(records), req.Offset, req.PageSize)
+	list := make([]map[string]interface{}, 0, end-start)
+	for _, rec := range records[start:end] {
+		item := map[string]interface{}{
+			"_id":    rec.id,
+			"dataId": rec.dataId,
+			"obj":    rec.obj,
+			"time":   rec.time,
+			"value": []map[string]interface{}{{
+				"type": "text",
+				"text": map[string]string{"content": rec.content},
+			}},
+		}
+		if rec.goodFeedback != "" {
+			item["userGoodFeedback"] = rec.goodFeedback
+		}
+		if rec.badFeedback != "" {
+			item["userBadFeedback"] = rec.badFeedback
+		}
+		list = append(list, item)
+	}
+	writeData(w, map[string]interface{}{"list": list, "total": len(records)})
+}
+
+func (s *Server) handleUpdateUserFeedback(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AppId            string `json:"appId"`
+		ChatId           string `json:"chatId"`
+		DataId           string `json:"dataId"`
+		UserGoodFeedback string `json:"userGoodFeedback"`
+		UserBadFeedback  string `json:"userBadFeedback"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.store.chats[req.ChatId]
+	if !ok {
+		writeError(w, http.StatusNotFound, "chat not exist")
+		return
+	}
+	for _, rec := range c.records {
+		if rec.dataId == req.DataId {
+			rec.goodFeedback, rec.badFeedback = req.UserGoodFeedback, req.UserBadFeedback
+			writeData(w, nil)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "chat item not exist")
+}
+
+func (s *Server) handleGetCollectionQuote(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CollectionId string `json:"collectionId"`
+		PageSize     int    `json:"pageSize"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []map[string]interface{}{}
+	if col, ok := s.store.collections[req.CollectionId]; ok {
+		_, end := page(len(col.chunks), 0, req.PageSize)
+		for i, c := range col.chunks[:end] {
+			list = append(list, map[string]interface{}{
+				"_id":        c.id,
+				"q":          c.q,
+				"a":          c.a,
+				"chunkIndex": i,
+			})
+		}
+	}
+	writeData(w, map[string]interface{}{"list": list, "hasMorePrev": false, "hasMoreNext": false})
+}
+
+func (s *Server) handleOutLinkInit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh, ok := s.store.shares[query.Get("shareId")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "share not exist")
+		return
+	}
+	title := "新对话"
+	if c, ok := s.store.chats[query.Get("chatId")]; ok {
+		title = c.title
+	}
+	writeData(w, map[string]interface{}{
+		"chatId":     query.Get("chatId"),
+		"appId":      sh.appId,
+		"title":      title,
+		"userAvatar": "/icon/human.svg",
+		"variables":  map[string]interface{}{},
+		"app": map[string]interface{}{
+			"name":       sh.name,
+			"avatar":     "/icon/logo.svg",
+			"intro":      "",
+			"type":       "simple",
+			"chatConfig": map[string]interface{}{},
+		},
+	})
+}
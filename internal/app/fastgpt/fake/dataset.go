@@ -0,0 +1,346 @@
+package fake
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *Server) handleDatasetCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ParentId *string `json:"parentId"`
+		Type     string  `json:"type"`
+		Name     string  `json:"name"`
+		Intro    string  `json:"intro"`
+		Avatar   string  `json:"avatar"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is empty")
+		return
+	}
+	if req.Type == "" {
+		req.Type = "dataset"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := &dataset{
+		id:         s.store.nextId(),
+		name:       req.Name,
+		intro:      req.Intro,
+		typ:        req.Type,
+		avatar:     req.Avatar,
+		createTime: time.Now(),
+	}
+	if req.ParentId != nil {
+		d.parentId = *req.ParentId
+	}
+	s.store.datasets[d.id] = d
+	writeData(w, d.id)
+}
+
+func (s *Server) handleDatasetList(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ParentId string `json:"parentId"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []map[string]interface{}{}
+	for _, d := range s.sortedDatasets() {
+		if d.parentId == req.ParentId {
+			list = append(list, datasetItem(d))
+		}
+	}
+	writeData(w, list)
+}
+
+func (s *Server) handleDatasetDetail(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.store.datasets[r.URL.Query().Get("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Dataset not exist")
+		return
+	}
+	writeData(w, datasetItem(d))
+}
+
+func (s *Server) handleDatasetDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.store.datasets[id]; !ok {
+		writeError(w, http.StatusNotFound, "Dataset not exist")
+		return
+	}
+	delete(s.store.datasets, id)
+	for colId, col := range s.store.collections {
+		if col.datasetId == id {
+			delete(s.store.collections, colId)
+		}
+	}
+	writeData(w, nil)
+}
+
+func (s *Server) sortedDatasets() []*dataset {
+	list := make([]*dataset, 0, len(s.store.datasets))
+	for _, d := range s.store.datasets {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].id < list[j].id })
+	return list
+}
+
+func datasetItem(d *dataset) map[string]interface{} {
+	return map[string]interface{}{
+		"_id":         d.id,
+		"parentId":    d.parentId,
+		"name":        d.name,
+		"intro":       d.intro,
+		"type":        d.typ,
+		"avatar":      d.avatar,
+		"vectorModel": map[string]string{"model": "fake-embedding"},
+		"createTime":  d.createTime,
+	}
+}
+
+// createCollection 在知识库中创建集合并写入数据块，知识库不存在时返回 false
+func (s *Server) createCollection(w http.ResponseWriter, datasetId, name, typ string, chunks []string) (*collection, bool) {
+	if _, ok := s.store.datasets[datasetId]; !ok {
+		writeError(w, http.StatusNotFound, "Dataset not exist")
+		return nil, false
+	}
+	col := &collection{
+		id:         s.store.nextId(),
+		datasetId:  datasetId,
+		name:       name,
+		typ:        typ,
+		createTime: time.Now(),
+	}
+	for _, q := range chunks {
+		col.chunks = append(col.chunks, chunk{id: s.store.nextId(), q: q})
+	}
+	s.store.collections[col.id] = col
+	return col, true
+}
+
+// writeCollectionResult 以 create/text 等接口的格式返回集合 ID 与写入数量
+func writeCollectionResult(w http.ResponseWriter, col *collection) {
+	writeData(w, map[string]interface{}{
+		"collectionId": col.id,
+		"results": map[string]interface{}{
+			"insertLen": len(col.chunks),
+			"overToken": []interface{}{},
+			"repeat":    []interface{}{},
+			"error":     []interface{}{},
+		},
+	})
+}
+
+func (s *Server) handleCollectionCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DatasetId string `json:"datasetId"`
+		Name      string `json:"name"`
+		Type      string `json:"type"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if col, ok := s.createCollection(w, req.DatasetId, req.Name, req.Type, nil); ok {
+		writeData(w, col.id)
+	}
+}
+
+func (s *Server) handleCollectionCreateText(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DatasetId string `json:"datasetId"`
+		Name      string `json:"name"`
+		Text      string `json:"text"`
+		ChunkSize int    `json:"chunkSize"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if col, ok := s.createCollection(w, req.DatasetId, req.Name, "virtual", splitText(req.Text, req.ChunkSize)); ok {
+		writeCollectionResult(w, col)
+	}
+}
+
+func (s *Server) handleCollectionCreateLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DatasetId string `json:"datasetId"`
+		Link      string `json:"link"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Link == "" {
+		writeError(w, http.StatusBadRequest, "link is empty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if col, ok := s.createCollection(w, req.DatasetId, req.Link, "link", []string{req.Link}); ok {
+		writeCollectionResult(w, col)
+	}
+}
+
+func (s *Server) handleCollectionCreateLocalFile(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file is empty")
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var meta struct {
+		DatasetId string `json:"datasetId"`
+		ChunkSize int    `json:"chunkSize"`
+	}
+	if err := json.Unmarshal([]byte(r.FormValue("data")), &meta); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid data")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 不解析文件格式，按文本切分即可满足导入流程
+	if col, ok := s.createCollection(w, meta.DatasetId, header.Filename, "file", splitText(string(content), meta.ChunkSize)); ok {
+		writeCollectionResult(w, col)
+	}
+}
+
+// handleCollectionDetail 与 FastGPT 一致，datasetId 展开为知识库对象
+func (s *Server) handleCollectionDetail(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col, ok := s.store.collections[r.URL.Query().Get("id")]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Collection not exist")
+		return
+	}
+	var datasetId interface{} = col.datasetId
+	if d, ok := s.store.datasets[col.datasetId]; ok {
+		datasetId = datasetItem(d)
+	}
+	writeData(w, map[string]interface{}{
+		"_id":        col.id,
+		"datasetId":  datasetId,
+		"name":       col.name,
+		"type":       col.typ,
+		"createTime": col.createTime,
+	})
+}
+
+func (s *Server) handleCollectionDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.store.collections[id]; !ok {
+		writeError(w, http.StatusNotFound, "Collection not exist")
+		return
+	}
+	delete(s.store.collections, id)
+	writeData(w, nil)
+}
+
+func (s *Server) handlePushData(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CollectionId string `json:"collectionId"`
+		Data         []struct {
+			Q string `json:"q"`
+			A string `json:"a"`
+		} `json:"data"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col, ok := s.store.collections[req.CollectionId]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Collection not exist")
+		return
+	}
+	for _, d := range req.Data {
+		col.chunks = append(col.chunks, chunk{id: s.store.nextId(), q: d.Q, a: d.A})
+	}
+	writeData(w, map[string]interface{}{
+		"insertLen": len(req.Data),
+		"overToken": []interface{}{},
+		"repeat":    []interface{}{},
+		"error":     []interface{}{},
+	})
+}
+
+// handleSearchTest 返回包含检索词的数据块，不做向量检索
+func (s *Server) handleSearchTest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DatasetId string `json:"datasetId"`
+		Text      string `json:"text"`
+		Limit     int    `json:"limit"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.store.datasets[req.DatasetId]; !ok {
+		writeError(w, http.StatusNotFound, "Dataset not exist")
+		return
+	}
+	list := []map[string]interface{}{}
+	for _, col := range s.store.collections {
+		if col.datasetId != req.DatasetId {
+			continue
+		}
+		for _, c := range col.chunks {
+			if req.Text == "" || !strings.Contains(c.q+c.a, req.Text) {
+				continue
+			}
+			list = append(list, map[string]interface{}{
+				"id":           c.id,
+				"q":            c.q,
+				"a":            c.a,
+				"datasetId":    col.datasetId,
+				"collectionId": col.id,
+				"sourceName":   col.name,
+				"score":        []map[string]interface{}{{"type": "embedding", "value": 0.9, "index": 0}},
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i]["id"].(string) < list[j]["id"].(string) })
+	writeData(w, map[string]interface{}{
+		"list":        list,
+		"duration":    "0.001s",
+		"searchMode":  "embedding",
+		"limit":       req.Limit,
+		"similarity":  0,
+		"usingReRank": false,
+	})
+}
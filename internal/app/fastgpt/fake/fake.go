@@ -0,0 +1,330 @@
+// Package fake 进程内的 FastGPT 模拟服务，用于测试与本地开发
+//
+// Server 实现了本系统调用的 FastGPT 接口：对话（含 SSE 流式）、会话历史、聊天记录、
+// 知识库、集合、外链初始化与图片。回答内容可以自定义，也可以为指定接口注入失败，
+// 所有收到的请求都会被记录，便于断言转发结果。
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Failure 为某个接口注入的失败
+type Failure struct {
+	Status int           // 返回的 HTTP 状态码，为 0 时不改写响应
+	Body   string        // 响应体，为空时返回 FastGPT 格式的错误
+	Delay  time.Duration // 响应前等待的时间，可用于模拟超时
+	// AfterChunks 流式对话发送指定数量的数据块后断开连接，不发送 [DONE]
+	AfterChunks int
+}
+
+// Request 收到的请求
+type Request struct {
+	Method string
+	Path   string
+	Query  map[string]string
+	Header http.Header
+	Body   []byte
+}
+
+// JSON 将请求体解析到 v
+func (r Request) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// AnswerFunc 根据用户的最后一条提问生成回答
+type AnswerFunc func(question string) string
+
+// Server FastGPT 模拟服务，零值不可用，需通过 New 创建
+type Server struct {
+	mu sync.Mutex
+
+	apiKey     string
+	answer     AnswerFunc
+	chunkRunes int
+	chunkDelay time.Duration
+
+	failures map[string][]Failure
+	handlers map[string]http.HandlerFunc
+	requests []Request
+
+	store *store
+	mux   *http.ServeMux
+	http  *httptest.Server
+}
+
+// New 创建模拟服务，默认回答为“收到：”加上提问
+func New() *Server {
+	s := &Server{
+		answer:     func(q string) string { return "收到：" + q },
+		chunkRunes: 4,
+		failures:   map[string][]Failure{},
+		handlers:   map[string]http.HandlerFunc{},
+		store:      newStore(),
+	}
+	s.routes()
+	return s
+}
+
+// Start 在本机随机端口启动服务，返回可作为 FastGPT.BaseURL 的地址
+func (s *Server) Start() string {
+	s.http = httptest.NewServer(s)
+	return s.URL()
+}
+
+// URL 服务地址，包含 /api 前缀；未启动时返回空字符串
+func (s *Server) URL() string {
+	if s.http == nil {
+		return ""
+	}
+	return s.http.URL + "/api"
+}
+
+// Close 停止服务
+func (s *Server) Close() {
+	if s.http != nil {
+		s.http.Close()
+	}
+}
+
+// SetAPIKey 要求请求携带指定的 API Key，为空时不校验
+func (s *Server) SetAPIKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKey = key
+}
+
+// SetAnswer 固定的回答内容
+func (s *Server) SetAnswer(answer string) {
+	s.SetAnswerFunc(func(string) string { return answer })
+}
+
+// SetAnswerFunc 自定义回答
+func (s *Server) SetAnswerFunc(fn AnswerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.answer = fn
+}
+
+// SetStreaming 流式回答每个数据块的字数与数据块间隔
+func (s *Server) SetStreaming(chunkRunes int, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if chunkRunes > 0 {
+		s.chunkRunes = chunkRunes
+	}
+	s.chunkDelay = delay
+}
+
+// Fail 为接口注入一次失败，多次调用按顺序依次生效，path 不含 /api 前缀
+func (s *Server) Fail(path string, f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], f)
+}
+
+// FailN 为接口注入 n 次相同的失败
+func (s *Server) FailN(path string, n int, f Failure) {
+	for i := 0; i < n; i++ {
+		s.Fail(path, f)
+	}
+}
+
+// Handle 以自定义处理函数替换接口的默认实现，path 不含 /api 前缀
+func (s *Server) Handle(path string, h http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[path] = h
+}
+
+// AddImage 添加可通过 /system/img/{id} 获取的图片
+func (s *Server) AddImage(id string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.images[id] = data
+}
+
+// AddShare 添加外链，outLink/init 与外链会话历史按 shareId 找到对应应用
+func (s *Server) AddShare(shareId, appId, appName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.shares[shareId] = share{appId: appId, name: appName}
+}
+
+// Requests 收到的请求，path 为空时返回全部
+func (s *Server) Requests(path string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []Request
+	for _, r := range s.requests {
+		if path == "" || r.Path == path {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// LastRequest 接口最近收到的请求
+func (s *Server) LastRequest(path string) (Request, bool) {
+	reqs := s.Requests(path)
+	if len(reqs) == 0 {
+		return Request{}, false
+	}
+	return reqs[len(reqs)-1], true
+}
+
+// Reset 清空数据、请求记录、注入的失败与自定义处理函数，保留回答设置
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = map[string][]Failure{}
+	s.handlers = map[string]http.HandlerFunc{}
+	s.requests = nil
+	s.store = newStore()
+}
+
+// ServeHTTP 处理 /api 前缀下的请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api")
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+	query := map[string]string{}
+	for key := range r.URL.Query() {
+		query[key] = r.URL.Query().Get(key)
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   path,
+		Query:  query,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	var failure *Failure
+	if queue := s.failures[path]; len(queue) > 0 {
+		failure = &queue[0]
+		s.failures[path] = queue[1:]
+	}
+	custom := s.handlers[path]
+	apiKey := s.apiKey
+	s.mu.Unlock()
+
+	if failure != nil && failure.Delay > 0 {
+		select {
+		case <-time.After(failure.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if failure != nil && failure.Status != 0 {
+		writeFailure(w, failure)
+		return
+	}
+	if apiKey != "" && !strings.HasPrefix(path, "/system/img/") && r.Header.Get("Authorization") != "Bearer "+apiKey {
+		writeError(w, http.StatusUnauthorized, "unAuthApiKey")
+		return
+	}
+	if custom != nil {
+		custom(w, r)
+		return
+	}
+
+	if failure != nil && failure.AfterChunks > 0 {
+		r = r.WithContext(withCutoff(r.Context(), failure.AfterChunks))
+	}
+	r.URL.Path = path
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() {
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	s.mux.HandleFunc("POST /core/chat/getHistories", s.handleGetHistories)
+	s.mux.HandleFunc("POST /core/chat/history/updateHistory", s.handleUpdateHistory)
+	s.mux.HandleFunc("DELETE /core/chat/delHistory", s.handleDelHistory)
+	s.mux.HandleFunc("POST /core/chat/getPaginationRecords", s.handleGetPaginationRecords)
+	s.mux.HandleFunc("POST /core/chat/feedback/updateUserFeedback", s.handleUpdateUserFeedback)
+	s.mux.HandleFunc("POST /core/chat/quote/getCollectionQuote", s.handleGetCollectionQuote)
+	s.mux.HandleFunc("GET /core/chat/outLink/init", s.handleOutLinkInit)
+
+	s.mux.HandleFunc("POST /core/dataset/create", s.handleDatasetCreate)
+	s.mux.HandleFunc("POST /core/dataset/list", s.handleDatasetList)
+	s.mux.HandleFunc("GET /core/dataset/detail", s.handleDatasetDetail)
+	s.mux.HandleFunc("DELETE /core/dataset/delete", s.handleDatasetDelete)
+	s.mux.HandleFunc("POST /core/dataset/collection/create", s.handleCollectionCreate)
+	s.mux.HandleFunc("POST /core/dataset/collection/create/text", s.handleCollectionCreateText)
+	s.mux.HandleFunc("POST /core/dataset/collection/create/link", s.handleCollectionCreateLink)
+	s.mux.HandleFunc("POST /core/dataset/collection/create/localFile", s.handleCollectionCreateLocalFile)
+	s.mux.HandleFunc("GET /core/dataset/collection/detail", s.handleCollectionDetail)
+	s.mux.HandleFunc("DELETE /core/dataset/collection/delete", s.handleCollectionDelete)
+	s.mux.HandleFunc("POST /core/dataset/data/pushData", s.handlePushData)
+	s.mux.HandleFunc("POST /core/dataset/searchTest", s.handleSearchTest)
+
+	s.mux.HandleFunc("GET /system/img/{imageId}", s.handleImage)
+}
+
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	data, ok := s.store.images[r.PathValue("imageId")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "image not exist")
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	_, _ = w.Write(data)
+}
+
+func writeFailure(w http.ResponseWriter, f *Failure) {
+	if f.Body == "" {
+		writeError(w, f.Status, http.StatusText(f.Status))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.Status)
+	_, _ = w.Write([]byte(f.Body))
+}
+
+// writeData 以 FastGPT 的响应格式返回 data
+func writeData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"code":       http.StatusOK,
+		"statusText": "",
+		"message":    "",
+		"data":       data,
+	})
+}
+
+// writeError 以 FastGPT 的错误格式返回
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"code":       status,
+		"statusText": message,
+		"message":    message,
+		"data":       nil,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// decode 解析请求体，失败时返回 400
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %v", err))
+		return false
+	}
+	return true
+}
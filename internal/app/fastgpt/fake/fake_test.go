@@ -0,0 +1,151 @@
+package fake_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"HelpStudent/internal/app/fastgpt/fake"
+	"HelpStudent/internal/app/fastgpt/service"
+
+	"github.com/tidwall/gjson"
+)
+
+func newFake(t *testing.T) (*fake.Server, *service.FastGPTClient) {
+	t.Helper()
+	srv := fake.New()
+	baseURL := srv.Start()
+	t.Cleanup(srv.Close)
+	srv.SetAPIKey("fake-key")
+	srv.BindKey("fake-key", "app1")
+	return srv, service.NewFastGPTClient(baseURL, "fake-key")
+}
+
+func TestChatAndHistories(t *testing.T) {
+	srv, client := newFake(t)
+	srv.SetAnswer("你好，同学")
+
+	body, status, err := client.ForwardRequest(context.Background(), http.MethodPost, "/v1/chat/completions", map[string]interface{}{
+		"chatId":   "c1",
+		"messages": []map[string]string{{"role": "user", "content": "在吗"}},
+	})
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("chat: status=%d err=%v", status, err)
+	}
+	if got := gjson.GetBytes(body, "choices.0.message.content").String(); got != "你好，同学" {
+		t.Errorf("answer = %q", got)
+	}
+
+	body, _, _ = client.ForwardRequest(context.Background(), http.MethodPost, "/core/chat/getHistories", map[string]interface{}{"appId": "app1"})
+	if gjson.GetBytes(body, "data.total").Int() != 1 || gjson.GetBytes(body, "data.list.0.chatId").String() != "c1" {
+		t.Errorf("histories = %s", body)
+	}
+	body, _, _ = client.ForwardRequest(context.Background(), http.MethodPost, "/core/chat/getPaginationRecords", map[string]interface{}{"appId": "app1", "chatId": "c1"})
+	if gjson.GetBytes(body, "data.list.1.value.0.text.content").String() != "你好，同学" {
+		t.Errorf("records = %s", body)
+	}
+
+	// API Key 错误
+	_, status, _ = service.NewFastGPTClient(srv.URL(), "wrong").ForwardRequest(context.Background(), http.MethodPost, "/core/chat/getHistories", map[string]interface{}{})
+	if status != http.StatusUnauthorized {
+		t.Errorf("wrong key status = %d", status)
+	}
+}
+
+func TestStreamAndFailures(t *testing.T) {
+	srv, client := newFake(t)
+	srv.SetAnswer("一二三四五六七八")
+	srv.SetStreaming(2, 0)
+	req := map[string]interface{}{
+		"stream":   true,
+		"messages": []map[string]string{{"role": "user", "content": "数数"}},
+	}
+
+	resp, err := client.ForwardStreamRequest(context.Background(), http.MethodPost, "/v1/chat/completions", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := service.NewStreamReader(resp)
+	answer, done := "", false
+	for {
+		line, ok := stream.Read()
+		if !ok {
+			break
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+		}
+		answer += gjson.Get(data, "choices.0.delta.content").String()
+	}
+	stream.Close()
+	if answer != "一二三四五六七八" || !done {
+		t.Errorf("stream answer = %q done=%v", answer, done)
+	}
+
+	// 发送两个数据块后断开
+	srv.Fail("/v1/chat/completions", fake.Failure{AfterChunks: 2})
+	resp, err = client.ForwardStreamRequest(context.Background(), http.MethodPost, "/v1/chat/completions", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Error("interrupted stream should return an error")
+	}
+	resp.Body.Close()
+
+	// 注入的失败只生效一次
+	srv.Fail("/core/dataset/list", fake.Failure{Status: http.StatusInternalServerError})
+	_, status, _ := client.ForwardRequest(context.Background(), http.MethodPost, "/core/dataset/list", map[string]interface{}{})
+	if status != http.StatusInternalServerError {
+		t.Errorf("injected failure status = %d", status)
+	}
+	_, status, _ = client.ForwardRequest(context.Background(), http.MethodPost, "/core/dataset/list", map[string]interface{}{})
+	if status != http.StatusOK {
+		t.Errorf("status after failure = %d", status)
+	}
+	if n := len(srv.Requests("/core/dataset/list")); n != 2 {
+		t.Errorf("recorded %d requests", n)
+	}
+}
+
+func TestDatasetImport(t *testing.T) {
+	srv, client := newFake(t)
+	ctx := context.Background()
+
+	body, _, _ := client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/create", map[string]interface{}{"name": "高数"})
+	datasetId := gjson.GetBytes(body, "data").String()
+
+	body, _, _ = client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/collection/create", map[string]interface{}{
+		"datasetId": datasetId, "name": "讲义.docx", "type": "virtual",
+	})
+	collectionId := gjson.GetBytes(body, "data").String()
+	_, status, _ := client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/data/pushData", map[string]interface{}{
+		"collectionId": collectionId,
+		"data":         []map[string]string{{"q": "极限的定义"}, {"q": "导数的定义"}},
+	})
+	if status != http.StatusOK || len(srv.Collection(collectionId)) != 2 {
+		t.Fatalf("pushData status=%d chunks=%v", status, srv.Collection(collectionId))
+	}
+
+	body, _, _ = client.ForwardMultipart(ctx, "/core/dataset/collection/create/localFile", "file", "a.pdf",
+		[]byte("第一段\n\n第二段"), map[string]string{"data": `{"datasetId":"` + datasetId + `"}`})
+	if gjson.GetBytes(body, "data.results.insertLen").Int() != 2 {
+		t.Errorf("localFile = %s", body)
+	}
+
+	body, _, _ = client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/searchTest", map[string]interface{}{"datasetId": datasetId, "text": "导数"})
+	if gjson.GetBytes(body, "data.list.#").Int() != 1 {
+		t.Errorf("searchTest = %s", body)
+	}
+
+	_, status, _ = client.ForwardRequestWithQuery(ctx, http.MethodDelete, "/core/dataset/collection/delete", map[string]string{"id": collectionId})
+	if status != http.StatusOK || srv.Collection(collectionId) != nil {
+		t.Errorf("delete collection status=%d", status)
+	}
+}
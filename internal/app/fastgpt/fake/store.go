@@ -0,0 +1,184 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// store 模拟服务的内存数据，读写时需持有 Server.mu
+type store struct {
+	seq         int
+	keys        map[string]string // API Key 对应的 FastGPT appId
+	chats       map[string]*chat
+	datasets    map[string]*dataset
+	collections map[string]*collection
+	images      map[string][]byte
+	shares      map[string]share
+}
+
+type chat struct {
+	chatId      string
+	appId       string
+	title       string
+	customTitle string
+	top         bool
+	shareId     string
+	outLinkUid  string
+	updateTime  time.Time
+	records     []*record
+}
+
+type record struct {
+	id           string
+	dataId       string
+	obj          string // Human / AI
+	content      string
+	time         time.Time
+	goodFeedback string
+	badFeedback  string
+}
+
+type dataset struct {
+	id         string
+	parentId   string
+	name       string
+	intro      string
+	typ        string
+	avatar     string
+	createTime time.Time
+}
+
+type collection struct {
+	id         string
+	datasetId  string
+	name       string
+	typ        string
+	createTime time.Time
+	chunks     []chunk
+}
+
+type chunk struct {
+	id string
+	q  string
+	a  string
+}
+
+type share struct {
+	appId string
+	name  string
+}
+
+func newStore() *store {
+	return &store{
+		keys:        map[string]string{},
+		chats:       map[string]*chat{},
+		datasets:    map[string]*dataset{},
+		collections: map[string]*collection{},
+		images:      map[string][]byte{},
+		shares:      map[string]share{},
+	}
+}
+
+// nextId 生成与 MongoDB ObjectId 等长的 ID
+func (st *store) nextId() string {
+	st.seq++
+	return fmt.Sprintf("%024x", st.seq)
+}
+
+// appIdFor 请求的 API Key 对应的 appId，未绑定时直接以 API Key 作为 appId
+func (st *store) appIdFor(r *http.Request) string {
+	key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if appId, ok := st.keys[key]; ok {
+		return appId
+	}
+	return key
+}
+
+// BindKey 将 API Key 绑定到 FastGPT appId，对话记录归入该应用
+func (s *Server) BindKey(apiKey, appId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.keys[apiKey] = appId
+}
+
+// Chat 会话的问答内容，依次为提问与回答，会话不存在时返回 nil
+func (s *Server) Chat(chatId string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.store.chats[chatId]
+	if !ok {
+		return nil
+	}
+	contents := make([]string, 0, len(c.records))
+	for _, rec := range c.records {
+		contents = append(contents, rec.content)
+	}
+	return contents
+}
+
+// Collection 集合中的数据块内容，集合不存在时返回 nil
+func (s *Server) Collection(collectionId string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col, ok := s.store.collections[collectionId]
+	if !ok {
+		return nil
+	}
+	qs := make([]string, 0, len(col.chunks))
+	for _, c := range col.chunks {
+		qs = append(qs, c.q)
+	}
+	return qs
+}
+
+// cutoffKey 流式对话在发送若干数据块后断开
+type cutoffKey struct{}
+
+func withCutoff(ctx context.Context, chunks int) context.Context {
+	return context.WithValue(ctx, cutoffKey{}, chunks)
+}
+
+func cutoff(ctx context.Context) int {
+	n, _ := ctx.Value(cutoffKey{}).(int)
+	return n
+}
+
+// page 按 offset 与 pageSize 截取，pageSize 为 0 时默认 20
+func page(total, offset, pageSize int) (int, int) {
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := offset + pageSize
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+// splitText 按字数切分文本
+func splitText(text string, size int) []string {
+	if size <= 0 {
+		size = 512
+	}
+	var chunks []string
+	for _, para := range strings.Split(text, "\n\n") {
+		runes := []rune(strings.TrimSpace(para))
+		for start := 0; start < len(runes); start += size {
+			end := start + size
+			if end > len(runes) {
+				end = len(runes)
+			}
+			chunks = append(chunks, string(runes[start:end]))
+		}
+	}
+	return chunks
+}
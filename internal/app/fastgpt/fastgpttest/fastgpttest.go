@@ -0,0 +1,121 @@
+// Package fastgpttest 测试用的运行环境：内存数据库、配置、日志与 FastGPT 模拟服务
+package fastgpttest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"HelpStudent/config"
+	"HelpStudent/core/logx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/fake"
+	"HelpStudent/internal/app/fastgpt/model"
+	managerDAO "HelpStudent/internal/app/managers/dao"
+	managerModel "HelpStudent/internal/app/managers/model"
+	subjectDAO "HelpStudent/internal/app/subject/dao"
+	subjectModel "HelpStudent/internal/app/subject/model"
+	userDAO "HelpStudent/internal/app/users/dao"
+	"HelpStudent/pkg/utils/crypto"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// APIKey 测试应用使用的 API Key，模拟服务只接受该 Key
+const APIKey = "fastgpt-test-key"
+
+var dbSeq atomic.Int64
+
+// Env 测试运行环境
+type Env struct {
+	DB     *gorm.DB
+	Server *fake.Server
+}
+
+// Setup 创建独立的内存数据库并初始化各模块的 DAO，启动 FastGPT 模拟服务并指向它的配置
+// 各 DAO 为包级变量，使用 Setup 的测试不能并行执行
+func Setup(t testing.TB) *Env {
+	t.Helper()
+	if logx.SystemLogger == nil {
+		logx.SystemLogger = logx.Setup()
+	}
+	if logx.ServiceLogger == nil {
+		logx.ServiceLogger = logx.Setup()
+	}
+
+	dsn := fmt.Sprintf("file:fastgpttest%d?mode=memory&cache=shared", dbSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	keyring, err := crypto.NewKeyring()
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	if err := dao.InitPG(db, keyring); err != nil {
+		t.Fatalf("init fastgpt dao: %v", err)
+	}
+	if err := managerDAO.InitPG(db); err != nil {
+		t.Fatalf("init managers dao: %v", err)
+	}
+	if err := subjectDAO.InitPG(db); err != nil {
+		t.Fatalf("init subject dao: %v", err)
+	}
+	if err := userDAO.InitPG(db); err != nil {
+		t.Fatalf("init users dao: %v", err)
+	}
+
+	srv := fake.New()
+	srv.SetAPIKey(APIKey)
+	baseURL := srv.Start()
+	t.Cleanup(srv.Close)
+
+	cfg := &config.GlobalConfig{MODE: "debug"}
+	cfg.Auth.Secret = "fastgpttest"
+	cfg.FastGPT.BaseURL = baseURL
+	prev := config.GetConfig()
+	config.SetConfig(cfg)
+	t.Cleanup(func() { config.SetConfig(prev) })
+
+	return &Env{DB: db, Server: srv}
+}
+
+// AddManager 添加管理员
+func (e *Env) AddManager(t testing.TB, staffId string) {
+	t.Helper()
+	if err := e.DB.Create(&managerModel.Managers{StaffId: staffId}).Error; err != nil {
+		t.Fatalf("add manager: %v", err)
+	}
+}
+
+// Enroll 为用户添加选课记录，uid 为空时模拟 Excel 导入的只有学号的关联
+func (e *Env) Enroll(t testing.TB, uid, staffId, subjectName string) {
+	t.Helper()
+	if err := e.DB.Create(&subjectModel.UserSubject{UserId: uid, StaffId: staffId, SubjectName: subjectName}).Error; err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+}
+
+// CreateApp 创建一个 FastGPT 应用，未指定的 API Key 使用 APIKey，并在模拟服务中绑定
+func (e *Env) CreateApp(t testing.TB, app *model.FastgptApp) *model.FastgptApp {
+	t.Helper()
+	if app.APIKey == "" {
+		app.APIKey = APIKey
+	}
+	if err := dao.FastgptApp.CreateApp(app); err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+	e.Server.BindKey(app.APIKey, app.AppId)
+	if app.ShareId != "" {
+		e.Server.AddShare(app.ShareId, app.AppId, app.AppName)
+	}
+	return app
+}
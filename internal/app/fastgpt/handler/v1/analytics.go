@@ -0,0 +1,253 @@
+package v1
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+const (
+	dateLayout           = "2006-01-02"
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 366
+)
+
+// parseUsageFilter 解析统计条件，未指定日期时统计最近 30 天，失败时直接写入响应
+func parseUsageFilter(r flamego.Render, authInfo auth.Info, req dto.AnalyticsRequest) (dao.UsageFilter, bool) {
+	f := dao.UsageFilter{AppId: req.FastgptAppId, SubjectName: req.SubjectName}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法查看统计数据")
+		return f, false
+	}
+
+	end := time.Now()
+	if req.EndDate != "" {
+		t, err := time.ParseInLocation(dateLayout, req.EndDate, time.Local)
+		if err != nil {
+			response.HTTPFail(r, 400001, "结束日期格式错误，应为 2006-01-02")
+			return f, false
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, 1-defaultAnalyticsDays)
+	if req.StartDate != "" {
+		t, err := time.ParseInLocation(dateLayout, req.StartDate, time.Local)
+		if err != nil {
+			response.HTTPFail(r, 400001, "开始日期格式错误，应为 2006-01-02")
+			return f, false
+		}
+		start = t
+	}
+	if start.After(end) {
+		response.HTTPFail(r, 400001, "开始日期不能晚于结束日期")
+		return f, false
+	}
+	if end.Sub(start) > maxAnalyticsDays*24*time.Hour {
+		response.HTTPFail(r, 400001, "统计区间不能超过一年")
+		return f, false
+	}
+
+	f.StartDay = start.Format(dateLayout)
+	f.EndDay = end.Format(dateLayout)
+	return f, true
+}
+
+func analyticsResponse(r flamego.Render, f dao.UsageFilter, items interface{}) {
+	response.HTTPSuccess(r, dto.AnalyticsResponse{
+		StartDate: f.StartDay,
+		EndDate:   f.EndDay,
+		Items:     items,
+	})
+}
+
+// HandleAnalyticsDailyActive 每个科目每天的活跃学生数（管理员）
+func HandleAnalyticsDailyActive(c flamego.Context, r flamego.Render, req dto.AnalyticsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	f, ok := parseUsageFilter(r, authInfo, req)
+	if !ok {
+		return
+	}
+
+	rows, err := dao.Usage.DailyActive(c.Request().Context(), f)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	items := make([]dto.DailyActiveItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, dto.DailyActiveItem{
+			Date:        row.Day,
+			SubjectName: row.SubjectName,
+			Students:    row.Students,
+			Messages:    row.Messages,
+		})
+	}
+	analyticsResponse(r, f, items)
+}
+
+// HandleAnalyticsHourly 一天中各小时的消息数（管理员），返回 0-23 点共 24 项
+func HandleAnalyticsHourly(c flamego.Context, r flamego.Render, req dto.AnalyticsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	f, ok := parseUsageFilter(r, authInfo, req)
+	if !ok {
+		return
+	}
+
+	rows, err := dao.Usage.Hourly(c.Request().Context(), f)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	items := make([]dto.HourlyMessagesItem, 0, 24)
+	for _, row := range service.FillHours(rows) {
+		items = append(items, dto.HourlyMessagesItem{
+			Hour:     row.Hour,
+			Messages: row.Messages,
+			Errors:   row.Errors,
+		})
+	}
+	analyticsResponse(r, f, items)
+}
+
+// HandleAnalyticsErrorRate 每个科目的失败率与平均耗时（管理员）
+func HandleAnalyticsErrorRate(c flamego.Context, r flamego.Render, req dto.AnalyticsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	f, ok := parseUsageFilter(r, authInfo, req)
+	if !ok {
+		return
+	}
+
+	rows, err := dao.Usage.ErrorRates(c.Request().Context(), f)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	items := make([]dto.ErrorRateItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, dto.ErrorRateItem{
+			SubjectName:  row.SubjectName,
+			FastgptAppId: row.AppId,
+			Messages:     row.Messages,
+			Errors:       row.Errors,
+			ErrorRate:    service.ErrorRate(row.Errors, row.Messages),
+			Rejected:     row.Rejected,
+			AvgLatencyMs: int64(math.Round(row.AvgLatencyMs)),
+		})
+	}
+	analyticsResponse(r, f, items)
+}
+
+// HandleAnalyticsTopUsers 消息数最多的用户（管理员）
+func HandleAnalyticsTopUsers(c flamego.Context, r flamego.Render, req dto.AnalyticsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	f, ok := parseUsageFilter(r, authInfo, req)
+	if !ok {
+		return
+	}
+
+	_, limit := normalizePage(0, req.Limit)
+	rows, err := dao.Usage.TopUsers(c.Request().Context(), f, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	items := make([]dto.TopUserItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, dto.TopUserItem{
+			UserId:      row.UserId,
+			StaffId:     row.StaffId,
+			Messages:    row.Messages,
+			ActiveDays:  row.ActiveDays,
+			InputChars:  row.InputChars,
+			OutputChars: row.OutputChars,
+		})
+	}
+	analyticsResponse(r, f, items)
+}
+
+// HandleAnalyticsExport 导出统计报表为 Excel（管理员）
+func HandleAnalyticsExport(c flamego.Context, r flamego.Render, req dto.AnalyticsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	f, ok := parseUsageFilter(r, authInfo, req)
+	if !ok {
+		return
+	}
+
+	_, limit := normalizePage(0, req.Limit)
+	report, err := service.BuildUsageReport(c.Request().Context(), f, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	w := c.ResponseWriter()
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", "attachment; filename=usage_"+f.StartDay+"_"+f.EndDay+".xlsx")
+	w.Header().Set("Content-Transfer-Encoding", "binary")
+	w.WriteHeader(http.StatusOK)
+	if err := report.WriteXLSX(w); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), "写入统计报表失败", err)
+	}
+}
+
+// HandleAnalyticsQueue 当前实例各应用的流式对话排队情况（管理员）
+func HandleAnalyticsQueue(c flamego.Context, r flamego.Render, authInfo auth.Info) {
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法查看统计数据")
+		return
+	}
+
+	stats := service.StreamQueue.Stats()
+	items := make([]dto.QueueStatItem, 0, len(stats))
+	for _, s := range stats {
+		item := dto.QueueStatItem{
+			FastgptAppId:  s.AppId,
+			Limit:         s.Limit,
+			Active:        s.Active,
+			Waiting:       s.Waiting,
+			PeakWaiting:   s.PeakWaiting,
+			Admitted:      s.Admitted,
+			Queued:        s.Queued,
+			TimedOut:      s.TimedOut,
+			Rejected:      s.Rejected,
+			Cancelled:     s.Cancelled,
+			AvgWaitMs:     s.AvgWait.Milliseconds(),
+			AvgDurationMs: s.AvgDuration.Milliseconds(),
+		}
+		if app, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), s.AppId); err == nil {
+			item.AppName = app.AppName
+		}
+		items = append(items, item)
+	}
+	response.HTTPSuccess(r, items)
+}
@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"errors"
+	"strings"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+	"gorm.io/gorm"
+)
+
+func toAnswerCacheResponse(appId string, setting *model.FastgptAnswerCacheSetting) dto.AnswerCacheResponse {
+	resp := dto.AnswerCacheResponse{
+		ID:         appId,
+		TTLSeconds: service.AnswerCacheTTL(setting),
+		Variables:  []string{},
+	}
+	if setting != nil {
+		resp.Enabled = setting.Enabled
+		if names := service.CacheVariables(setting); names != nil {
+			resp.Variables = names
+		}
+	}
+	return resp
+}
+
+// HandleGetAnswerCache 获取应用的回答缓存设置
+func HandleGetAnswerCache(c flamego.Context, r flamego.Render, req dto.AnswerCacheRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法查看回答缓存设置")
+		return
+	}
+
+	setting, err := dao.AnswerCache.GetByAppId(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, toAnswerCacheResponse(req.ID, setting))
+}
+
+// HandleUpdateAnswerCache 更新应用的回答缓存设置，设置变化后清除已缓存的回答
+func HandleUpdateAnswerCache(c flamego.Context, r flamego.Render, req dto.UpdateAnswerCacheRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法修改回答缓存设置")
+		return
+	}
+	if req.TTLSeconds != nil && *req.TTLSeconds < 0 {
+		response.HTTPFail(r, 400001, "缓存时间不能为负数")
+		return
+	}
+
+	// 检查应用是否存在
+	_, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.HTTPFail(r, 404001, "应用不存在")
+			return
+		}
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	setting, err := dao.AnswerCache.GetByAppId(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	if setting == nil {
+		setting = &model.FastgptAnswerCacheSetting{AppId: req.ID}
+	}
+	if req.Enabled != nil {
+		setting.Enabled = *req.Enabled
+	}
+	if req.TTLSeconds != nil {
+		setting.TTLSeconds = *req.TTLSeconds
+	}
+	if req.Variables != nil {
+		var names []string
+		for _, name := range req.Variables {
+			if name = strings.TrimSpace(name); name != "" && !strings.Contains(name, ",") {
+				names = append(names, name)
+			}
+		}
+		setting.Variables = strings.Join(names, ",")
+	}
+	setting.UpdatedBy = authInfo.Uid
+
+	if err := dao.AnswerCache.Save(c.Request().Context(), setting); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	if _, err := service.InvalidateAnswerCache(req.ID); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+	}
+
+	response.HTTPSuccess(r, toAnswerCacheResponse(req.ID, setting))
+}
+
+// HandleInvalidateAnswerCache 清除应用已缓存的全部回答，知识库更新后使用
+func HandleInvalidateAnswerCache(c flamego.Context, r flamego.Render, req dto.AnswerCacheRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法清除回答缓存")
+		return
+	}
+
+	removed, err := service.InvalidateAnswerCache(req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, dto.InvalidateAnswerCacheResponse{Removed: removed})
+}
@@ -7,14 +7,67 @@ import (
 	"HelpStudent/internal/app/fastgpt/dao"
 	"HelpStudent/internal/app/fastgpt/dto"
 	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
 	dao2 "HelpStudent/internal/app/managers/dao"
 	"errors"
+	"strings"
 
 	"github.com/flamego/binding"
 	"github.com/flamego/flamego"
 	"gorm.io/gorm"
 )
 
+// toAppItem 转换为列表项，API Key 只返回脱敏值
+func toAppItem(app *model.FastgptApp) dto.AppItem {
+	item := dto.AppItem{
+		ID:                app.ID,
+		AppName:           app.AppName,
+		AppId:             app.AppId,
+		ShareId:           app.ShareId,
+		APIKeyMask:        service.MaskAPIKey(app.APIKey),
+		APIKeyFingerprint: service.APIKeyFingerprint(app.APIKey),
+		Description:       app.Description,
+		Provider:          providerName(app.Provider),
+		BaseURL:           app.BaseURL,
+		Model:             app.Model,
+		Status:            app.Status,
+		CreatedBy:         app.CreatedBy,
+		CreatedAt:         app.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:         app.UpdatedAt.Format("2006-01-02 15:04:05"),
+		HealthStatus:      app.HealthStatus,
+		HealthError:       app.HealthError,
+	}
+	if app.HealthCheckedAt != nil {
+		item.HealthCheckedAt = app.HealthCheckedAt.Format("2006-01-02 15:04:05")
+	}
+	return item
+}
+
+// providerName 未设置对话后端的历史应用视为 FastGPT
+func providerName(provider string) string {
+	if provider == "" {
+		return model.ProviderFastGPT
+	}
+	return provider
+}
+
+// validateProvider 校验对话后端配置，返回错误提示
+func validateProvider(provider, baseURL, modelName string) string {
+	switch providerName(provider) {
+	case model.ProviderFastGPT:
+		return ""
+	case model.ProviderOpenAI:
+		if baseURL == "" || modelName == "" {
+			return "OpenAI 兼容接口需要填写接口地址和模型"
+		}
+		if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+			return "接口地址需以 http:// 或 https:// 开头"
+		}
+		return ""
+	}
+	return "不支持的对话后端 " + provider
+}
+
 // HandleCreateApp 创建应用
 func HandleCreateApp(c flamego.Context, r flamego.Render, req dto.CreateAppRequest, errs binding.Errors, authInfo auth.Info) {
 	if errs != nil {
@@ -28,6 +81,11 @@ func HandleCreateApp(c flamego.Context, r flamego.Render, req dto.CreateAppReque
 		return
 	}
 
+	if msg := validateProvider(req.Provider, req.BaseURL, req.Model); msg != "" {
+		response.HTTPFail(r, 400001, msg)
+		return
+	}
+
 	// 检查 AppName 是否已存在
 	exists, err := dao.FastgptApp.CheckAppNameExists(req.AppName)
 	if err != nil {
@@ -48,6 +106,10 @@ func HandleCreateApp(c flamego.Context, r flamego.Render, req dto.CreateAppReque
 		APIKey:      req.APIKey,
 		Description: req.Description,
 		CreatedBy:   authInfo.Uid,
+		Provider:    providerName(req.Provider),
+		BaseURL:     req.BaseURL,
+		Model:       req.Model,
+		Status:      model.AppStatusEnabled,
 	}
 
 	if err := dao.FastgptApp.CreateApp(app); err != nil {
@@ -55,6 +117,7 @@ func HandleCreateApp(c flamego.Context, r flamego.Render, req dto.CreateAppReque
 		response.ServiceErr(r, err)
 		return
 	}
+	recordAppRevision(c.Request().Context(), model.RevisionCreate, nil, app, authInfo)
 
 	response.HTTPSuccess(r, dto.CreateAppResponse{Name: app.AppName})
 }
@@ -83,18 +146,8 @@ func HandleGetAppList(c flamego.Context, r flamego.Render, req dto.GetAppListReq
 
 	// 转换为 DTO
 	var appItems []dto.AppItem
-	for _, app := range apps {
-		appItems = append(appItems, dto.AppItem{
-			ID:          app.ID,
-			AppName:     app.AppName,
-			AppId:       app.AppId,
-			ShareId:     app.ShareId,
-			APIKey:      app.APIKey,
-			Description: app.Description,
-			CreatedBy:   app.CreatedBy,
-			CreatedAt:   app.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:   app.UpdatedAt.Format("2006-01-02 15:04:05"),
-		})
+	for i := range apps {
+		appItems = append(appItems, toAppItem(&apps[i]))
 	}
 
 	response.HTTPSuccess(r, dto.AppListResponse{
@@ -117,7 +170,7 @@ func HandleUpdateApp(c flamego.Context, r flamego.Render, req dto.UpdateAppReque
 	}
 
 	// 检查应用是否存在
-	_, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.ID)
+	current, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.ID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			response.HTTPFail(r, 404001, "应用不存在")
@@ -128,6 +181,22 @@ func HandleUpdateApp(c flamego.Context, r flamego.Render, req dto.UpdateAppReque
 		return
 	}
 
+	// 校验更新后的对话后端配置
+	provider, baseURL, modelName := current.Provider, current.BaseURL, current.Model
+	if req.Provider != "" {
+		provider = req.Provider
+	}
+	if req.BaseURL != "" {
+		baseURL = req.BaseURL
+	}
+	if req.Model != "" {
+		modelName = req.Model
+	}
+	if msg := validateProvider(provider, baseURL, modelName); msg != "" {
+		response.HTTPFail(r, 400001, msg)
+		return
+	}
+
 	// 构建更新数据
 	updates := make(map[string]interface{})
 	if req.AppName != "" {
@@ -145,6 +214,22 @@ func HandleUpdateApp(c flamego.Context, r flamego.Render, req dto.UpdateAppReque
 	if req.Description != "" {
 		updates["description"] = req.Description
 	}
+	if req.Provider != "" {
+		updates["provider"] = req.Provider
+	}
+	if req.BaseURL != "" {
+		updates["base_url"] = req.BaseURL
+	}
+	if req.Model != "" {
+		updates["model"] = req.Model
+	}
+	if req.Status != nil {
+		if *req.Status != model.AppStatusEnabled && *req.Status != model.AppStatusDisabled {
+			response.HTTPFail(r, 400001, "状态只能为 1（启用）或 0（停用）")
+			return
+		}
+		updates["status"] = *req.Status
+	}
 	if len(updates) == 0 {
 		response.HTTPFail(r, 400015, "没有需要更新的字段")
 		return
@@ -157,7 +242,15 @@ func HandleUpdateApp(c flamego.Context, r flamego.Render, req dto.UpdateAppReque
 		return
 	}
 
-	response.HTTPSuccess(r, nil)
+	app, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	recordAppRevision(c.Request().Context(), model.RevisionUpdate, current, app, authInfo)
+
+	response.HTTPSuccess(r, toAppItem(app))
 }
 
 // HandleDeleteApp 删除应用
@@ -174,7 +267,7 @@ func HandleDeleteApp(c flamego.Context, r flamego.Render, req dto.DeleteAppReque
 	}
 
 	// 检查应用是否存在
-	_, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.ID)
+	app, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.ID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			response.HTTPFail(r, 404001, "应用不存在")
@@ -191,6 +284,7 @@ func HandleDeleteApp(c flamego.Context, r flamego.Render, req dto.DeleteAppReque
 		response.ServiceErr(r, err)
 		return
 	}
+	recordAppRevision(c.Request().Context(), model.RevisionDelete, app, nil, authInfo)
 
 	response.HTTPSuccess(r, nil)
 }
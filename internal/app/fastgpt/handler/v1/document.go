@@ -0,0 +1,257 @@
+package v1
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/core/threadx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+// HandleUploadDocument 上传课程资料并导入 FastGPT 知识库（管理员）
+// multipart/form-data: fastgptAppId, datasetId, file
+// 文件保存后立即返回，解析与导入在后台进行，通过列表或详情接口查询进度
+func HandleUploadDocument(c flamego.Context, r flamego.Render, authInfo auth.Info) {
+	req := c.Request().Request
+	ctx := req.Context()
+	maxSize := service.DocumentMaxSize()
+	req.Body = http.MaxBytesReader(c.ResponseWriter(), req.Body, maxSize+1<<20)
+
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.HTTPFail(r, 413001, fmt.Sprintf("文件大小不能超过 %dMB", maxSize>>20))
+			return
+		}
+		response.HTTPFail(r, 400002, "获取上传文件失败")
+		return
+	}
+	defer func(file multipart.File) {
+		_ = file.Close()
+	}(file)
+
+	appId := req.FormValue("fastgptAppId")
+	datasetId := req.FormValue("datasetId")
+	if appId == "" || datasetId == "" {
+		response.HTTPFail(r, 400001, "缺少 fastgptAppId 或 datasetId")
+		return
+	}
+	fileType, err := service.DocumentType(header.Filename)
+	if err != nil {
+		response.HTTPFail(r, 400003, err.Error())
+		return
+	}
+	if header.Size > maxSize {
+		response.HTTPFail(r, 413001, fmt.Sprintf("文件大小不能超过 %dMB", maxSize>>20))
+		return
+	}
+
+	app, ok := getAuthorizedApp(c, r, authInfo, appId, service.AccessManage)
+	if !ok {
+		return
+	}
+	if !app.IsFastGPT() {
+		response.HTTPFail(r, 400017, "该应用未接入 FastGPT，不支持此接口")
+		return
+	}
+	storage, err := service.DocumentStorage()
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.HTTPFail(r, 400004, "读取文件内容失败")
+		return
+	}
+
+	// 先保存原始文件，FastGPT 导入失败时仍可重新处理
+	fileKey := service.DocumentFileKey(app.ID, fileType)
+	if _, err := storage.UploadFile(data, fileKey); err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	doc := &model.FastgptDocument{
+		AppId:       app.ID,
+		SubjectName: app.AppName,
+		DatasetId:   datasetId,
+		FileName:    header.Filename,
+		FileKey:     fileKey,
+		FileSize:    int64(len(data)),
+		FileType:    fileType,
+		Status:      model.DocumentProcessing,
+		UploadedBy:  authInfo.Uid,
+	}
+	if err := dao.Document.Create(ctx, doc); err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		_ = storage.DeleteFile(fileKey)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	threadx.GoSafe(func() {
+		service.ProcessDocument(app, doc, data)
+	})
+
+	response.HTTPSuccess(r, documentItem(doc))
+}
+
+// HandleListDocuments 课程资料列表（管理员），按应用或科目筛选
+func HandleListDocuments(c flamego.Context, r flamego.Render, req dto.ListDocumentsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if req.FastgptAppId == "" && req.SubjectName == "" {
+		response.HTTPFail(r, 400001, "缺少 fastgptAppId 或 subjectName")
+		return
+	}
+	if req.FastgptAppId != "" {
+		if _, ok := getAuthorizedApp(c, r, authInfo, req.FastgptAppId, service.AccessManage); !ok {
+			return
+		}
+	} else if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法查看课程资料")
+		return
+	}
+
+	offset, limit := normalizePage(req.Offset, req.Limit)
+	docs, total, err := dao.Document.List(c.Request().Context(), dao.DocumentFilter{
+		AppId:       req.FastgptAppId,
+		SubjectName: req.SubjectName,
+		DatasetId:   req.DatasetId,
+	}, offset, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	items := make([]dto.DocumentItem, 0, len(docs))
+	for i := range docs {
+		items = append(items, documentItem(&docs[i]))
+	}
+	response.HTTPSuccess(r, dto.DocumentListResponse{
+		Documents: items,
+		Total:     total,
+	})
+}
+
+// HandleGetDocument 课程资料详情与处理进度（管理员）
+func HandleGetDocument(c flamego.Context, r flamego.Render, req dto.DocumentIdRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	doc, _, ok := getAuthorizedDocument(c, r, authInfo, req.Id)
+	if !ok {
+		return
+	}
+	response.HTTPSuccess(r, documentItem(doc))
+}
+
+// HandleDeleteDocument 删除课程资料，同时删除 FastGPT 集合与原始文件（管理员）
+func HandleDeleteDocument(c flamego.Context, r flamego.Render, req dto.DocumentIdRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	doc, app, ok := getAuthorizedDocument(c, r, authInfo, req.Id)
+	if !ok {
+		return
+	}
+	if doc.Status == model.DocumentProcessing {
+		response.HTTPFail(r, 400015, "资料正在导入中，请稍后再删除")
+		return
+	}
+
+	ctx := c.Request().Context()
+	if doc.CollectionId != "" {
+		if err := service.DeleteDocumentCollection(ctx, app, doc.CollectionId); err != nil {
+			logx.SystemLogger.CtxError(ctx, err)
+			response.ServiceErr(r, err)
+			return
+		}
+	}
+	if storage, err := service.DocumentStorage(); err == nil {
+		if err := storage.DeleteFile(doc.FileKey); err != nil {
+			logx.SystemLogger.CtxError(ctx, err)
+		}
+	}
+	if err := dao.Document.Delete(ctx, doc.ID); err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, nil)
+}
+
+// getAuthorizedDocument 获取资料并校验所属应用的管理权限，失败时直接写入响应
+func getAuthorizedDocument(c flamego.Context, r flamego.Render, authInfo auth.Info, id string) (*model.FastgptDocument, *model.FastgptApp, bool) {
+	if id == "" {
+		response.HTTPFail(r, 400001, "缺少资料ID")
+		return nil, nil, false
+	}
+	doc, err := dao.Document.Get(c.Request().Context(), id)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return nil, nil, false
+	}
+	if doc == nil {
+		response.HTTPFail(r, 404001, "资料不存在")
+		return nil, nil, false
+	}
+	app, ok := getAuthorizedApp(c, r, authInfo, doc.AppId, service.AccessManage)
+	if !ok {
+		return nil, nil, false
+	}
+	return doc, app, true
+}
+
+func documentItem(doc *model.FastgptDocument) dto.DocumentItem {
+	progress := 0
+	switch {
+	case doc.Status == model.DocumentSuccess:
+		progress = 100
+	case doc.TotalChunks > 0:
+		progress = doc.PushedChunks * 100 / doc.TotalChunks
+	}
+	return dto.DocumentItem{
+		ID:           doc.ID,
+		FastgptAppId: doc.AppId,
+		SubjectName:  doc.SubjectName,
+		DatasetId:    doc.DatasetId,
+		CollectionId: doc.CollectionId,
+		FileName:     doc.FileName,
+		FileSize:     doc.FileSize,
+		FileType:     doc.FileType,
+		Status:       doc.Status,
+		Stage:        doc.Stage,
+		TotalChunks:  doc.TotalChunks,
+		PushedChunks: doc.PushedChunks,
+		Progress:     progress,
+		ErrorMsg:     doc.ErrorMsg,
+		UploadedBy:   doc.UploadedBy,
+		CreatedAt:    doc.CreatedAt.Format(timeLayout),
+	}
+}
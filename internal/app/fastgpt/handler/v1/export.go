@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/proxy"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+// HandleExportChat 导出会话为 Markdown、JSON 或 Excel，学生只能导出自己的会话，管理员可导出任意会话
+func HandleExportChat(c flamego.Context, r flamego.Render, req dto.ExportChatRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if req.FastgptAppId == "" || req.ChatId == "" {
+		response.HTTPFail(r, 400001, "缺少 fastgptAppId 或 chatId")
+		return
+	}
+	if req.Format == "" {
+		req.Format = service.ExportMarkdown
+	}
+	if !service.ValidExportFormat(req.Format) {
+		response.HTTPFail(r, 400001, "format 只能为 markdown、json 或 xlsx")
+		return
+	}
+
+	app, ok := getAuthorizedApp(c, r, authInfo, req.FastgptAppId, service.AccessChat)
+	if !ok {
+		return
+	}
+	if !app.IsFastGPT() {
+		response.HTTPFail(r, 400017, "该应用未接入 FastGPT，不支持此接口")
+		return
+	}
+	ctx := c.Request().Context()
+	session, err := dao.ChatRecord.GetSession(ctx, app.ID, req.ChatId)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+	if session == nil {
+		response.HTTPFail(r, 404001, "会话不存在")
+		return
+	}
+	if session.UserId != authInfo.Uid && !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "只能导出自己的会话")
+		return
+	}
+
+	export, err := service.ExportChat(ctx, app, req.ChatId)
+	if err != nil {
+		var ue *service.ExportUpstreamError
+		if errors.As(err, &ue) {
+			logx.SystemLogger.CtxError(ctx, "export chat: FastGPT API error: status=%d, body=%s", ue.Status, string(ue.Body))
+			code, msg, detail := proxy.MapUpstreamError(ue.Status, ue.Body)
+			response.HTTPFail(r, code, msg, detail)
+			return
+		}
+		proxy.WriteRequestError(c, r, err)
+		return
+	}
+	export.Title = session.Title
+
+	contentType, ext := service.ExportContentType(req.Format)
+	title := session.Title
+	if title == "" {
+		title = req.ChatId
+	}
+	filename := exportFilename(app.AppName, title, export.ExportedAt) + ext
+	w := c.ResponseWriter()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+	if err := export.Write(w, req.Format); err != nil {
+		logx.SystemLogger.CtxError(ctx, "写入会话导出文件失败", err)
+	}
+}
+
+// exportFilename 科目_会话标题_导出日期，去掉文件名中不允许的字符
+func exportFilename(appName, title string, at time.Time) string {
+	if runes := []rune(title); len(runes) > 30 {
+		title = string(runes[:30])
+	}
+	name := appName + "_" + title + "_" + at.Format("20060102")
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`\/:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
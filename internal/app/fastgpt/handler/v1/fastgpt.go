@@ -1,744 +1,456 @@
 package v1
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
-	"time"
+	"strconv"
 
-	"HelpStudent/config"
 	"HelpStudent/core/auth"
 	"HelpStudent/core/logx"
 	"HelpStudent/core/middleware/response"
+	"HelpStudent/core/threadx"
 	"HelpStudent/internal/app/fastgpt/dao"
 	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/proxy"
 	"HelpStudent/internal/app/fastgpt/service"
 
 	"github.com/flamego/binding"
 	"github.com/flamego/flamego"
-	"github.com/guonaihong/gout"
 )
 
-// getFastGPTClient 获取 FastGPT 客户端（使用指定的 API Key）
-func getFastGPTClient(apiKey string) *service.FastGPTClient {
-	cfg := config.GetConfig()
-	return service.NewFastGPTClient(cfg.FastGPT.BaseURL, apiKey)
-}
-
-// HandleGetImage 代理图片请求到 FastGPT
-// 路由: GET /api/system/img/:imageId
-func HandleGetImage(c flamego.Context, r flamego.Render) {
-	imageId := c.Param("imageId")
-	if imageId == "" {
-		response.HTTPFail(r, 400001, "缺少图片ID")
-		return
-	}
-
-	cfg := config.GetConfig()
-	imageURL := cfg.FastGPT.BaseURL + "/system/img/" + imageId
-
-	// 创建 HTTP 客户端请求
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	req, err := http.NewRequest("GET", imageURL, nil)
-	if err != nil {
-		response.ServiceErr(r, err)
-		return
-	}
-
-	// 设置请求头，保持与原始请求一致
-	req.Header.Set("Accept", "image/*,*/*")
-	req.Header.Set("User-Agent", c.Request().Header.Get("User-Agent"))
-
-	// 发送请求
-	resp, err := client.Do(req)
+// getAuthorizedApp 根据本系统应用 ID 获取应用并校验当前用户的访问权限，失败时直接写入响应
+func getAuthorizedApp(c flamego.Context, r flamego.Render, authInfo auth.Info, id string, level service.AccessLevel) (*model.FastgptApp, bool) {
+	app, err := dao.FastgptApp.GetAppByID(id)
 	if err != nil {
-		response.ServiceErr(r, err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
-	}
-
-	// 设置响应头
-	contentType := resp.Header.Get("Content-Type")
-	if contentType != "" {
-		c.ResponseWriter().Header().Set("Content-Type", contentType)
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.HTTPFail(r, 400013, "应用不存在")
+		return nil, false
 	}
-	contentLength := resp.Header.Get("Content-Length")
-	if contentLength != "" {
-		c.ResponseWriter().Header().Set("Content-Length", contentLength)
+	if !checkAppAccess(c, r, authInfo, app, level) {
+		return nil, false
 	}
-	// 设置缓存头
-	c.ResponseWriter().Header().Set("Cache-Control", "public, max-age=86400")
-
-	// 将图片内容写入响应
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	io.Copy(c.ResponseWriter(), resp.Body)
+	return app, true
 }
 
-// HandleChatCompletion 处理聊天补全请求
-func HandleChatCompletion(c flamego.Context, r flamego.Render, req dto.ChatCompletionRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
-		return
+// checkAppAccess 校验当前用户的应用访问权限，失败时直接写入响应
+func checkAppAccess(c flamego.Context, r flamego.Render, authInfo auth.Info, app *model.FastgptApp, level service.AccessLevel) bool {
+	err := service.CheckAppAccess(authInfo, app, level)
+	if err == nil {
+		return true
 	}
-	// TODO检查这个用户是否可以使用这个app
-
-	// 根据 fastgptAppId 获取对应的 API Key
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.HTTPFail(r, 400013, "应用不存在或已禁用")
-		return
+	if errors.Is(err, service.ErrAppForbidden) {
+		response.HTTPFail(r, 403001, "无权访问该应用")
+		return false
 	}
+	logx.SystemLogger.CtxError(c.Request().Context(), err)
+	response.ServiceErr(r, err)
+	return false
+}
 
-	// 如果是流式请求，返回提示使用流式接口
-	if req.Stream {
-		response.HTTPFail(r, 400014, "请使用流式接口 /v1/chat/completions/stream")
+// HandleGetImage 代理 FastGPT 图片，图片缓存在本地，重复请求不再访问 FastGPT
+// 路由: GET /api/system/img/:imageId
+func HandleGetImage(c flamego.Context, r flamego.Render) {
+	imageId := c.Param("imageId")
+	if !service.ValidImageId(imageId) {
+		response.HTTPFail(r, 400001, "图片ID格式不正确")
 		return
 	}
 
-	// 非流式请求
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/v1/chat/completions", req)
+	images, err := service.Images()
 	if err != nil {
 		logx.SystemLogger.CtxError(c.Request().Context(), err)
 		response.ServiceErr(r, err)
 		return
 	}
-
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
+	img, err := images.Get(c.Request().Context(), imageId)
+	switch {
+	case errors.Is(err, service.ErrImageNotFound):
+		response.HTTPFail(r, 404002, "图片不存在")
 		return
-	}
-
-	// 直接返回 FastGPT 的响应
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
-
-// sendSSEMessage 安全地发送 SSE 消息，捕获可能的 panic
-func sendSSEMessage(msg chan<- *dto.SSEMessage, message *dto.SSEMessage) (sent bool) {
-	defer func() {
-		if r := recover(); r != nil {
-			sent = false
-		}
-	}()
-	msg <- message
-	return true
-}
-
-// HandleStreamChatCompletion 处理流式聊天补全请求（使用 flamego/sse）
-func HandleStreamChatCompletion(c flamego.Context, req dto.ChatCompletionRequest, errs binding.Errors, authInfo auth.Info, msg chan<- *dto.SSEMessage) {
-	fmt.Println("========== 开始发送消息 ==========")
-
-	if errs != nil {
-		sendSSEMessage(msg, &dto.SSEMessage{Data: `{"error":"参数错误"}`, Event: "error"})
+	case errors.Is(err, service.ErrImageTooLarge):
+		response.HTTPFail(r, 413001, "图片超过大小限制")
 		return
-	}
-
-	// 根据 fastgptAppId 获取对应的 API Key
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		sendSSEMessage(msg, &dto.SSEMessage{Data: `{"error":"应用不存在或已禁用"}`, Event: "error"})
+	case errors.Is(err, service.ErrImageType):
+		response.HTTPFail(r, 415001, "不支持的图片类型")
 		return
-	}
-
-	// 强制设置为流式模式
-	req.Stream = true
-
-	// 发起流式请求
-	resp, err := getFastGPTClient(app.APIKey).ForwardStreamRequest("POST", "/v1/chat/completions", req)
-	if err != nil {
+	case err != nil:
 		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		sendSSEMessage(msg, &dto.SSEMessage{Data: `{"error":"请求失败"}`, Event: "error"})
+		response.HTTPFail(r, 500001, "获取图片失败")
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d", resp.StatusCode)
-		sendSSEMessage(msg, &dto.SSEMessage{Data: `{"error":"FastGPT API 调用失败"}`, Event: "error"})
+	header := c.ResponseWriter().Header()
+	header.Set("ETag", img.ETag)
+	header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", service.ImageMaxAge()))
+	if c.Request().Header.Get("If-None-Match") == img.ETag {
+		c.ResponseWriter().WriteHeader(http.StatusNotModified)
 		return
 	}
-
-	// 读取并转发流式响应
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// 跳过空行
-		if len(strings.TrimSpace(line)) == 0 {
-			continue
-		}
-
-		// 解析 SSE 格式数据
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			fmt.Printf("[SSE发送] %s\n", data)
-			if !sendSSEMessage(msg, &dto.SSEMessage{Data: data}) {
-				// 客户端已断开连接
-				fmt.Println("[SSE发送] 客户端已断开连接")
-				return
-			}
-
-			// 检查是否是结束标记
-			if data == "[DONE]" {
-				fmt.Println("========== 消息发送完成 ==========")
-				break
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), "Stream read error", err)
-	}
+	header.Set("Content-Type", img.ContentType)
+	header.Set("Content-Length", strconv.Itoa(len(img.Data)))
+	header.Set("X-Content-Type-Options", "nosniff")
+	c.ResponseWriter().WriteHeader(http.StatusOK)
+	_, _ = c.ResponseWriter().Write(img.Data)
 }
 
-// HandleGetHistories 获取聊天历史列表
-func HandleGetHistories(c flamego.Context, r flamego.Render, req dto.GetHistoriesRequest, errs binding.Errors, authInfo auth.Info) {
+// HandleChatCompletion 处理聊天补全请求
+func HandleChatCompletion(c flamego.Context, r flamego.Render, req dto.ChatCompletionRequest, errs binding.Errors, authInfo auth.Info) {
 	if errs != nil {
 		response.InValidParam(r, errs)
 		return
 	}
-
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
-	if err != nil {
-		response.ServiceErr(r, err)
+	app, ok := getAuthorizedApp(c, r, authInfo, req.FastgptAppId, service.AccessChat)
+	if !ok || !checkAvailability(c, r, authInfo, app, &req) {
 		return
 	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/chat/getHistories", req)
-	if err != nil {
-		response.ServiceErr(r, err)
-		return
-	}
-
-	if statusCode != http.StatusOK {
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
-	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
-
-// HandleUpdateHistory 更新聊天会话
-func HandleUpdateHistory(c flamego.Context, r flamego.Render, req dto.UpdateHistoryRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
+	if err := service.BindChatIdentity(authInfo, app, &req); err != nil {
+		response.HTTPFail(r, 400019, err.Error())
 		return
 	}
-
-	// 使用 appId 获取 API Key
-	app, err := dao.FastgptApp.GetAppByID(req.AppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/chat/history/updateHistory", req)
-	if err != nil {
+	if err := service.CheckChatContinue(c.Request().Context(), authInfo, app, req.ChatId); err != nil {
+		if errors.Is(err, service.ErrChatForbidden) {
+			response.HTTPFail(r, 403001, err.Error())
+			return
+		}
 		logx.SystemLogger.CtxError(c.Request().Context(), err)
 		response.ServiceErr(r, err)
 		return
 	}
 
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
-	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
-
-// HandleGetPaginationRecords 获取聊天记录
-func HandleGetPaginationRecords(c flamego.Context, r flamego.Render, req dto.GetPaginationRecordsRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
+	// 如果是流式请求，返回提示使用流式接口
+	if req.Stream {
+		response.HTTPFail(r, 400014, "请使用流式接口 /v1/chat/completions/stream")
 		return
 	}
 
-	// 使用 fastgptAppId 获取 API Key
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+	if err := service.ModerateInput(c.Request().Context(), authInfo, app, &req); err != nil {
+		if errors.Is(err, service.ErrContentBlocked) {
+			service.RecordRejectedUsage(c.Request().Context(), authInfo, app, &req, false, model.UsageStatusBlocked)
+		}
+		writeModerationError(c, r, err)
 		return
 	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/chat/getPaginationRecords", req)
-	if err != nil {
+	if err := service.ApplyVariables(c.Request().Context(), authInfo, app, &req); err != nil {
 		logx.SystemLogger.CtxError(c.Request().Context(), err)
 		response.ServiceErr(r, err)
 		return
 	}
 
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
+	release, ok := acquireChatQuota(c, r, authInfo, app, &req)
+	if !ok {
 		return
 	}
+	defer release()
 
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
-
-// HandleCreateDataset 创建数据集
-func HandleCreateDataset(c flamego.Context, r flamego.Render, req dto.DatasetCreateRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
-		return
-	}
-
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
+	// 回答缓存需在记录提问前判断，查询失败时不使用缓存
+	answerCache, err := service.AnswerCacheFor(c.Request().Context(), app, &req)
 	if err != nil {
 		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
 	}
 
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/dataset/create", req)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
-
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
-	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
-
-// HandleListDatasets 列出数据集
-func HandleListDatasets(c flamego.Context, r flamego.Render, req dto.DatasetListRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
-		return
-	}
+	transcript := service.StartTranscript(c.Request().Context(), authInfo, app, &req, false)
 
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
+	// 非流式请求
+	provider, err := service.NewProvider(app)
 	if err != nil {
 		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		transcript.Finish(model.ChatStatusError, err)
 		response.ServiceErr(r, err)
 		return
 	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/dataset/list", req)
+	respBody, statusCode, err := answerCache.Wrap(provider).Chat(c.Request().Context(), &req)
 	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+		transcript.Finish(model.ChatStatusError, err)
+		proxy.WriteRequestError(c, r, err)
 		return
 	}
 
 	if statusCode != http.StatusOK {
 		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
-	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
-
-// HandleGetDatasetDetail 获取数据集详情
-func HandleGetDatasetDetail(c flamego.Context, r flamego.Render, authInfo auth.Info) {
-	id := c.Query("id")
-	fastgptAppId := c.Query("fastgptAppId")
-	if id == "" || fastgptAppId == "" {
-		response.HTTPFail(r, 400001, "缺少必要参数")
-		return
-	}
-
-	app, err := dao.FastgptApp.GetAppByID(fastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+		transcript.Finish(model.ChatStatusError, fmt.Errorf("FastGPT API error: status=%d", statusCode))
+		code, msg, detail := proxy.MapUpstreamError(statusCode, respBody)
+		response.HTTPFail(r, code, msg, detail)
 		return
 	}
+	transcript.SetAnswer(respBody)
+	transcript.Finish(model.ChatStatusSuccess, nil)
 
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequestWithQuery("GET", "/core/dataset/detail", map[string]string{
-		"id": id,
-	})
+	// 回答打码，命中记录保留原文
+	maskedBody, keywords, err := service.MaskAnswer(c.Request().Context(), app, respBody)
 	if err != nil {
 		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
-
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
 	}
+	service.RecordOutputHit(c.Request().Context(), authInfo, app, req.ChatId, keywords, transcript.Answer())
+	respBody = maskedBody
 
+	// 直接返回 FastGPT 的响应
 	c.ResponseWriter().Header().Set("Content-Type", "application/json")
 	c.ResponseWriter().WriteHeader(http.StatusOK)
 	c.ResponseWriter().Write(respBody)
 }
 
-// HandleDeleteDataset 删除数据集
-func HandleDeleteDataset(c flamego.Context, r flamego.Render, authInfo auth.Info) {
-	id := c.Query("id")
-	fastgptAppId := c.Query("fastgptAppId")
-	if id == "" || fastgptAppId == "" {
-		response.HTTPFail(r, 400001, "缺少必要参数")
-		return
-	}
-
-	app, err := dao.FastgptApp.GetAppByID(fastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequestWithQuery("DELETE", "/core/dataset/delete", map[string]string{
-		"id": id,
-	})
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
-
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
+// sendSSEMessage 安全地发送 SSE 消息，捕获可能的 panic，客户端断开后不再阻塞
+func sendSSEMessage(ctx context.Context, msg chan<- *dto.SSEMessage, message *dto.SSEMessage) (sent bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			sent = false
+		}
+	}()
+	select {
+	case msg <- message:
+		return true
+	case <-ctx.Done():
+		return false
 	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
 }
 
-// HandleCreateCollectionText 从文本创建集合
-func HandleCreateCollectionText(c flamego.Context, r flamego.Render, req dto.CreateCollectionTextRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
-		return
-	}
+// sendSSEError 发送 error 事件
+func sendSSEError(ctx context.Context, msg chan<- *dto.SSEMessage, data dto.SSEErrorData) bool {
+	body, _ := json.Marshal(data)
+	return sendSSEMessage(ctx, msg, &dto.SSEMessage{Data: string(body), Event: "error"})
+}
 
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
+// HandleStreamChatCompletion 处理流式聊天补全请求（使用 flamego/sse）
+// 回答在后台生成，客户端断线后可带 Last-Event-ID 请求头重新请求本接口续传，不会重新提问
+// 调用停止接口后推送 stopped 事件并结束
+func HandleStreamChatCompletion(c flamego.Context, req dto.ChatCompletionRequest, errs binding.Errors, authInfo auth.Info, msg chan<- *dto.SSEMessage) {
+	ctx := c.Request().Context()
 
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/dataset/collection/create/text", req)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+	if errs != nil {
+		sendSSEMessage(ctx, msg, &dto.SSEMessage{Data: `{"error":"参数错误"}`, Event: "error"})
 		return
 	}
 
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
+	if lastEventId := c.Request().Header.Get("Last-Event-ID"); lastEventId != "" {
+		resumeStream(ctx, authInfo, lastEventId, msg)
 		return
 	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
+	streamChat(ctx, authInfo, req, false, msg)
 }
 
-// HandleCreateCollectionLink 从链接创建集合
-func HandleCreateCollectionLink(c flamego.Context, r flamego.Render, req dto.CreateCollectionLinkRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
-		return
-	}
-
+// streamChat 校验后开始一次后台生成，并把事件推送到 msg 直到生成结束或 ctx 取消
+// SSE 与 WebSocket 共用，skipCache 为 true 时不使用缓存的回答
+func streamChat(ctx context.Context, authInfo auth.Info, req dto.ChatCompletionRequest, skipCache bool, msg chan<- *dto.SSEMessage) {
+	// 根据 fastgptAppId 获取对应的 API Key
 	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
 	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+		logx.SystemLogger.CtxError(ctx, err)
+		sendSSEMessage(ctx, msg, &dto.SSEMessage{Data: `{"error":"应用不存在"}`, Event: "error"})
 		return
 	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/dataset/collection/create/link", req)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+	if err := service.CheckAppAccess(authInfo, app, service.AccessChat); err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		sendSSEMessage(ctx, msg, &dto.SSEMessage{Data: `{"error":"无权访问该应用"}`, Event: "error"})
 		return
 	}
-
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
+	if !checkStreamAvailability(ctx, msg, authInfo, app, &req) {
 		return
 	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
-
-// HandlePushData 推送数据到集合
-func HandlePushData(c flamego.Context, r flamego.Render, req dto.PushDataRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
+	if err := service.BindChatIdentity(authInfo, app, &req); err != nil {
+		sendSSEError(ctx, msg, dto.SSEErrorData{Error: err.Error(), Code: 400019})
 		return
 	}
-
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+	if err := service.CheckChatContinue(ctx, authInfo, app, req.ChatId); err != nil {
+		if errors.Is(err, service.ErrChatForbidden) {
+			sendSSEError(ctx, msg, dto.SSEErrorData{Error: err.Error(), Code: 403001})
+			return
+		}
+		logx.SystemLogger.CtxError(ctx, err)
+		sendSSEMessage(ctx, msg, &dto.SSEMessage{Data: `{"error":"请求失败"}`, Event: "error"})
 		return
 	}
 
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/dataset/data/pushData", req)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+	if err := service.ModerateInput(ctx, authInfo, app, &req); err != nil {
+		if errors.Is(err, service.ErrContentBlocked) {
+			service.RecordRejectedUsage(ctx, authInfo, app, &req, true, model.UsageStatusBlocked)
+			sendSSEError(ctx, msg, dto.SSEErrorData{Error: err.Error(), Code: 400016})
+			return
+		}
+		logx.SystemLogger.CtxError(ctx, err)
+		sendSSEMessage(ctx, msg, &dto.SSEMessage{Data: `{"error":"请求失败"}`, Event: "error"})
 		return
 	}
-
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
+	if err := service.ApplyVariables(ctx, authInfo, app, &req); err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		sendSSEMessage(ctx, msg, &dto.SSEMessage{Data: `{"error":"请求失败"}`, Event: "error"})
 		return
 	}
 
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
-
-// HandleSearchTest 搜索测试
-func HandleSearchTest(c flamego.Context, r flamego.Render, req dto.SearchTestRequest, errs binding.Errors, authInfo auth.Info) {
-	if errs != nil {
-		response.InValidParam(r, errs)
+	release, ok := acquireStreamQuota(ctx, msg, authInfo, app, &req)
+	if !ok {
 		return
 	}
 
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/dataset/searchTest", req)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
+	// 强制设置为流式模式
+	req.Stream = true
 
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
+	var answerCache *service.AnswerCache
+	if !skipCache {
+		answerCache, err = service.AnswerCacheFor(ctx, app, &req)
+		if err != nil {
+			logx.SystemLogger.CtxError(ctx, err)
+		}
 	}
 
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
+	transcript := service.StartTranscript(ctx, authInfo, app, &req, true)
+	stream := service.Streams.Start(ctx, authInfo.Uid, req.ChatId)
+	threadx.GoSafe(func() {
+		defer release()
+		generateStream(stream, authInfo, app, req, transcript, answerCache)
+	})
+	forwardStream(ctx, stream, 0, msg)
 }
 
-// getHDUHelpUserAvatar 从 HDUHelp API 获取用户头像
-func getHDUHelpUserAvatar(token string) string {
-	type HDUHelpUserResp struct {
-		Error int    `json:"error"`
-		Msg   string `json:"msg"`
-		Data  struct {
-			Avatar string `json:"avatar"`
-		} `json:"data"`
-	}
-
-	var resp HDUHelpUserResp
-
-	err := gout.GET("https://api.hduhelp.com/user/get").
-		SetHeader(gout.H{
-			"Authorization": "token " + token,
-		}).
-		SetTimeout(5 * time.Second).
-		BindJSON(&resp).
-		Do()
-
-	if err != nil {
-		logx.SystemLogger.Errorf("HDUHelp get user avatar error: %v", err)
-		return ""
+// resumeStream 按 Last-Event-ID 续传尚在宽限期内的生成
+func resumeStream(ctx context.Context, authInfo auth.Info, lastEventId string, msg chan<- *dto.SSEMessage) {
+	var stream *service.ChatStream
+	if streamId, seq, ok := service.ParseEventId(lastEventId); ok {
+		stream = service.Streams.Get(streamId)
+		if stream != nil && stream.UserId == authInfo.Uid {
+			forwardStream(ctx, stream, seq, msg)
+			return
+		}
 	}
+	sendSSEError(ctx, msg, dto.SSEErrorData{Error: "回答已过期，请重新提问", Code: 410001})
+}
 
-	if resp.Error != 0 {
-		logx.SystemLogger.Errorf("HDUHelp get user avatar failed: %s", resp.Msg)
-		return ""
-	}
+// forwardStream 将生成中序号大于 after 的事件推送给客户端
+func forwardStream(ctx context.Context, stream *service.ChatStream, after int, msg chan<- *dto.SSEMessage) {
+	stream.Subscribe(ctx, after, func(e service.StreamEvent) bool {
+		return sendSSEMessage(ctx, msg, &dto.SSEMessage{Id: stream.EventId(e.Seq), Data: e.Data, Event: e.Event})
+	})
+}
 
-	return resp.Data.Avatar
+// publishStreamError 向生成写入 error 事件
+func publishStreamError(stream *service.ChatStream, data dto.SSEErrorData) {
+	body, _ := json.Marshal(data)
+	stream.Publish("error", string(body))
 }
 
-// HandleOutLinkInit 外链聊天初始化
-// 路由: GET /fastgpt/core/chat/outLink/init?chatId=xxx&shareId=xxx&outLinkUid=xxx
-func HandleOutLinkInit(c flamego.Context, r flamego.Render, authInfo auth.Info) {
-	chatId := c.Query("chatId")
-	shareId := c.Query("shareId")
-	outLinkUid := c.Query("outLinkUid")
-	hduhelpToken := c.Query("hduhelpToken")
+// generateStream 请求 FastGPT 并把流式响应写入 stream，与客户端连接解耦
+// answerCache 不为 nil 时优先重放缓存的回答
+func generateStream(stream *service.ChatStream, authInfo auth.Info, app *model.FastgptApp, req dto.ChatCompletionRequest, transcript *service.Transcript, answerCache *service.AnswerCache) {
+	defer stream.Close()
+	ctx := stream.Context()
 
-	if shareId == "" {
-		response.HTTPFail(r, 400001, "缺少必要参数 shareId")
-		return
+	filter, err := service.NewOutputFilter(ctx, app)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
 	}
+	defer func() {
+		// 输出打码时保留的剩余内容
+		if rest, ok := filter.FlushChunk(); ok {
+			stream.Publish("", rest)
+		}
+		service.RecordOutputHit(ctx, authInfo, app, req.ChatId, filter.Keywords(), transcript.Answer())
+	}()
 
-	// 根据 shareId 获取对应的 API Key
-	app, err := dao.FastgptApp.GetAppByShareID(shareId)
+	provider, err := service.NewProvider(app)
 	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.HTTPFail(r, 400013, "应用不存在或已禁用")
+		logx.SystemLogger.CtxError(ctx, err)
+		transcript.Finish(model.ChatStatusError, err)
+		stream.Publish("error", `{"error":"请求失败"}`)
 		return
 	}
 
-	// 构建查询参数
-	queryParams := map[string]string{
-		"shareId": shareId,
-	}
-	if chatId != "" {
-		queryParams["chatId"] = chatId
-	}
-	if outLinkUid != "" {
-		queryParams["outLinkUid"] = outLinkUid
-	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequestWithQuery("GET", "/core/chat/outLink/init", queryParams)
+	// 发起流式请求
+	reader, err := answerCache.Wrap(provider).ChatStream(ctx, &req)
 	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+		if ctx.Err() != nil {
+			finishCancelledStream(stream, filter, transcript, req.ChatId)
+			return
+		}
+		logx.SystemLogger.CtxError(ctx, err)
+		transcript.Finish(model.ChatStatusError, err)
+		if se, ok := service.IsStatusError(err); ok {
+			logx.SystemLogger.CtxError(ctx, "FastGPT API error: status=%d, body=%s", se.StatusCode, string(se.Body))
+			stream.Publish("error", `{"error":"FastGPT API 调用失败"}`)
+			return
+		}
+		if errors.Is(err, service.ErrFastGPTUnavailable) {
+			publishStreamError(stream, dto.SSEErrorData{Error: err.Error(), Code: 503001})
+			return
+		}
+		stream.Publish("error", `{"error":"请求失败"}`)
 		return
 	}
+	defer reader.Close()
 
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
-	}
-
-	// 如果提供了 hduhelpToken，获取用户头像并替换 respBody 中的 userAvatar
-	if hduhelpToken != "" {
-		if avatar := getHDUHelpUserAvatar(hduhelpToken); avatar != "" {
-			// 解析 JSON 并替换 data.userAvatar 字段np
-			var result map[string]interface{}
-			if err := json.Unmarshal(respBody, &result); err == nil {
-				if data, ok := result["data"].(map[string]interface{}); ok {
-					data["userAvatar"] = avatar
-					if modifiedBody, err := json.Marshal(result); err == nil {
-						respBody = modifiedBody
-					}
-				}
+	// 读取并转发流式响应
+	for {
+		data, err := reader.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, service.ErrStreamIdleTimeout) {
+				logx.SystemLogger.CtxError(ctx, "Stream read error", err)
+				transcript.Finish(model.ChatStatusError, err)
+				publishStreamError(stream, dto.SSEErrorData{Error: err.Error(), Code: 504001})
+				return
 			}
+			if ctx.Err() != nil {
+				// 用户停止、宽限期内客户端未重连，或被同一会话的新提问取代
+				finishCancelledStream(stream, filter, transcript, req.ChatId)
+				return
+			}
+			// 包括未收到 [DONE] 就断开（io.ErrUnexpectedEOF），回答不完整，按失败处理
+			logx.SystemLogger.CtxError(ctx, "Stream read error", err)
+			transcript.Finish(model.ChatStatusError, err)
+			stream.Publish("error", `{"error":"请求失败"}`)
+			return
 		}
-	}
 
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
-}
+		transcript.AppendChunk(data)
 
-// HandleOutLinkDelHistory 外链删除聊天历史
-// 路由: DELETE /api/core/chat/delHistory?appId=xxx&chatId=xxx&shareId=xxx&outLinkUid=xxx
-func HandleOutLinkDelHistory(c flamego.Context, r flamego.Render, authInfo auth.Info) {
-	fastgptAppId := c.Query("FastgptAppId")
-	chatId := c.Query("chatId")
-	shareId := c.Query("shareId")
-	outLinkUid := c.Query("outLinkUid")
-
-	if shareId == "" || chatId == "" {
-		response.HTTPFail(r, 400001, "缺少必要参数 shareId 或 chatId")
-		return
-	}
-
-	// 根据 shareId 获取对应的 API Key
-	app, err := dao.FastgptApp.GetAppByID(fastgptAppId)
-	if err != nil {
-		response.HTTPFail(r, 400013, "应用不存在或已禁用")
-		return
-	}
-
-	// 构建查询参数
-	queryParams := map[string]string{
-		"chatId":  chatId,
-		"shareId": shareId,
-		"appId":   app.AppId,
-	}
-	if outLinkUid != "" {
-		queryParams["outLinkUid"] = outLinkUid
+		// 检查是否是结束标记
+		if data == "[DONE]" {
+			if rest, ok := filter.FlushChunk(); ok {
+				stream.Publish("", rest)
+			}
+			stream.Publish("", data)
+			break
+		}
+		if out, ok := filter.MaskChunk(data); ok {
+			stream.Publish("", out)
+		}
 	}
+	transcript.Finish(model.ChatStatusSuccess, nil)
+}
 
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequestWithQuery("DELETE", "/core/chat/delHistory", queryParams)
-	if err != nil {
-		response.ServiceErr(r, err)
+// finishCancelledStream 生成被取消时记录结束状态，用户主动停止时先推送打码保留的内容再发送 stopped 事件
+func finishCancelledStream(stream *service.ChatStream, filter *service.OutputFilter, transcript *service.Transcript, chatId string) {
+	if !stream.Stopped() {
+		transcript.Finish(model.ChatStatusAborted, nil)
 		return
 	}
-
-	if statusCode != http.StatusOK {
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
-		return
+	transcript.Finish(model.ChatStatusStopped, nil)
+	if rest, ok := filter.FlushChunk(); ok {
+		stream.Publish("", rest)
 	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
+	body, _ := json.Marshal(dto.SSEStoppedData{ChatId: chatId})
+	stream.Publish("stopped", string(body))
 }
 
-// HandleGetCollectionQuote 获取集合引用详情
-// 路由: POST /fastgpt/core/chat/quote/getCollectionQuote
-func HandleGetCollectionQuote(c flamego.Context, r flamego.Render, req dto.GetCollectionQuoteRequest, errs binding.Errors, authInfo auth.Info) {
+// HandleStopChatCompletion 停止当前用户在某会话中进行中的流式生成
+// 上游请求随之取消，已生成的部分回答会保留在聊天记录中，流式连接收到 stopped 事件后结束
+func HandleStopChatCompletion(c flamego.Context, r flamego.Render, req dto.StopChatRequest, errs binding.Errors, authInfo auth.Info) {
 	if errs != nil {
 		response.InValidParam(r, errs)
 		return
 	}
-
-	app, err := dao.FastgptApp.GetAppByID(req.FastgptAppId)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
+	if req.ChatId == "" {
+		response.HTTPFail(r, 400001, "缺少 chatId")
 		return
 	}
-
-	respBody, statusCode, err := getFastGPTClient(app.APIKey).ForwardRequest("POST", "/core/chat/quote/getCollectionQuote", req)
-	if err != nil {
-		logx.SystemLogger.CtxError(c.Request().Context(), err)
-		response.ServiceErr(r, err)
-		return
-	}
-
-	if statusCode != http.StatusOK {
-		logx.SystemLogger.CtxError(c.Request().Context(), "FastGPT API error: status=%d, body=%s", statusCode, string(respBody))
-		response.HTTPFail(r, 500001, "FastGPT API 调用失败")
+	if !service.Streams.Stop(authInfo.Uid, req.ChatId) {
+		response.HTTPFail(r, 404001, "没有进行中的回答")
 		return
 	}
-
-	c.ResponseWriter().Header().Set("Content-Type", "application/json")
-	c.ResponseWriter().WriteHeader(http.StatusOK)
-	c.ResponseWriter().Write(respBody)
+	response.HTTPSuccess(r, nil)
 }
@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/fastgpttest"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/proxy"
+	"HelpStudent/internal/app/fastgpt/service"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+// testApp 测试环境：一个已配置分享链接的应用，学生 uid1、uid2 均已选课
+type testApp struct {
+	env *fastgpttest.Env
+	app *model.FastgptApp
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	env := fastgpttest.Setup(t)
+	app := env.CreateApp(t, &model.FastgptApp{AppName: "高等数学", AppId: "app1", ShareId: "share1"})
+	env.Enroll(t, "uid1", "S001", "高等数学")
+	env.Enroll(t, "uid2", "S002", "高等数学")
+	return &testApp{env: env, app: app}
+}
+
+// as 以指定用户身份挂载与 router 相同的对话与转发接口
+func (ta *testApp) as(info auth.Info) *flamego.Flame {
+	f := flamego.New()
+	f.Use(flamego.Renderer())
+	f.Use(func(c flamego.Context) { c.Map(info) })
+	f.Group("/api", func() {
+		proxy.Register(f, OutLinkProxyRoutes)
+	})
+	f.Group("/fastgpt", func() {
+		f.Post("/v1/chat/completions", binding.JSON(dto.ChatCompletionRequest{}), HandleChatCompletion)
+		proxy.Register(f, ChatProxyRoutes)
+	})
+	return f
+}
+
+type testResponse struct {
+	status int
+	body   []byte
+}
+
+func (r testResponse) code(t *testing.T) int {
+	t.Helper()
+	var res struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(r.body, &res); err != nil {
+		t.Fatalf("decode response %q: %v", r.body, err)
+	}
+	return res.Code
+}
+
+func serve(f *flamego.Flame, method, target string, body interface{}) testResponse {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, req)
+	return testResponse{status: w.Code, body: w.Body.Bytes()}
+}
+
+// chat 以 info 对应的用户在 chatId 中提问，并带上伪造的 outLinkUid
+func (ta *testApp) chat(t *testing.T, info auth.Info, chatId, question string) testResponse {
+	t.Helper()
+	return serve(ta.as(info), http.MethodPost, "/fastgpt/v1/chat/completions", map[string]interface{}{
+		"fastgptAppId": ta.app.ID,
+		"chatId":       chatId,
+		"outLinkUid":   "forged",
+		"messages":     []map[string]string{{"role": "user", "content": question}},
+	})
+}
+
+func TestHandleChatCompletion(t *testing.T) {
+	ta := newTestApp(t)
+	student := auth.Info{Uid: "uid1", StaffId: "S001"}
+
+	resp := ta.chat(t, student, "chat1", "极限是什么")
+	if resp.status != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", resp.status, resp.body)
+	}
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(resp.body, &completion); err != nil || len(completion.Choices) == 0 {
+		t.Fatalf("decode completion %s: %v", resp.body, err)
+	}
+	if got := completion.Choices[0].Message.Content; got != "收到：极限是什么" {
+		t.Errorf("answer = %q", got)
+	}
+
+	fwd, ok := ta.env.Server.LastRequest("/v1/chat/completions")
+	if !ok {
+		t.Fatal("chat not forwarded")
+	}
+	var body map[string]interface{}
+	if err := fwd.JSON(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["outLinkUid"] != service.OutLinkUid("uid1") || body["customUid"] != service.OutLinkUid("uid1") {
+		t.Errorf("identity not derived server-side: %v", body)
+	}
+	// 请求未带 shareId 时也使用应用的分享链接
+	if body["shareId"] != "share1" {
+		t.Errorf("shareId = %v, want share1", body["shareId"])
+	}
+
+	// 其他用户不能在该会话中继续提问
+	other := ta.chat(t, auth.Info{Uid: "uid2", StaffId: "S002"}, "chat1", "继续")
+	if code := other.code(t); code != 403001 {
+		t.Errorf("other user continue chat: code = %d", code)
+	}
+	if n := len(ta.env.Server.Requests("/v1/chat/completions")); n != 1 {
+		t.Errorf("forwarded %d chats, want 1", n)
+	}
+}
+
+func TestChatWithoutShareId(t *testing.T) {
+	ta := newTestApp(t)
+	noShare := ta.env.CreateApp(t, &model.FastgptApp{AppName: "线性代数", AppId: "app2"})
+	ta.env.Enroll(t, "uid1", "S001", "线性代数")
+	f := ta.as(auth.Info{Uid: "uid1", StaffId: "S001"})
+
+	resp := serve(f, http.MethodPost, "/fastgpt/v1/chat/completions", map[string]interface{}{
+		"fastgptAppId": noShare.ID,
+		"messages":     []map[string]string{{"role": "user", "content": "矩阵"}},
+	})
+	if code := resp.code(t); code != 400019 {
+		t.Errorf("chat: code = %d, body = %s", code, resp.body)
+	}
+	resp = serve(f, http.MethodPost, "/fastgpt/core/chat/history/getHistories", map[string]interface{}{
+		"fastgptAppId": noShare.ID,
+	})
+	if code := resp.code(t); code != 400019 {
+		t.Errorf("getHistories: code = %d, body = %s", code, resp.body)
+	}
+	if reqs := ta.env.Server.Requests(""); len(reqs) != 0 {
+		t.Errorf("forwarded %d requests for app without shareId", len(reqs))
+	}
+}
@@ -0,0 +1,149 @@
+package v1
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/core/threadx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+const maxFeedbackReasonLength = 500
+
+// HandleCreateFeedback 评价一条回答，只能评价自己的会话
+func HandleCreateFeedback(c flamego.Context, r flamego.Render, req dto.CreateFeedbackRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if req.FastgptAppId == "" || req.ChatId == "" || req.DataId == "" {
+		response.HTTPFail(r, 400001, "缺少 fastgptAppId、chatId 或 dataId")
+		return
+	}
+	if req.Rating != model.FeedbackUp && req.Rating != model.FeedbackDown {
+		response.HTTPFail(r, 400001, "rating 只能为 up 或 down")
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(reason) > maxFeedbackReasonLength {
+		response.HTTPFail(r, 400001, "评价理由不能超过 500 个字符")
+		return
+	}
+
+	app, ok := getAuthorizedApp(c, r, authInfo, req.FastgptAppId, service.AccessChat)
+	if !ok {
+		return
+	}
+	ctx := c.Request().Context()
+	session, err := dao.ChatRecord.GetSession(ctx, app.ID, req.ChatId)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+	if session == nil {
+		response.HTTPFail(r, 404001, "会话不存在")
+		return
+	}
+	if session.UserId != authInfo.Uid {
+		response.HTTPFail(r, 403001, "只能评价自己的会话")
+		return
+	}
+
+	fb := &model.FastgptAnswerFeedback{
+		AppId:       app.ID,
+		SubjectName: app.AppName,
+		ChatId:      req.ChatId,
+		DataId:      req.DataId,
+		UserId:      authInfo.Uid,
+		StaffId:     authInfo.StaffId,
+		Rating:      req.Rating,
+		Reason:      reason,
+	}
+	if err := dao.Feedback.Save(ctx, fb); err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	// 同步失败不影响本地评价，结果记录在评价上供管理员查看
+	if service.ForwardFeedbackEnabled(app) {
+		threadx.GoSafe(func() {
+			if err := service.ForwardFeedback(context.Background(), app, fb); err != nil {
+				logx.SystemLogger.Errorf("forward feedback %s: %v", fb.DataId, err)
+			}
+		})
+	}
+	// 点踩的回答进入任课教师的审核队列
+	if fb.Rating == model.FeedbackDown {
+		threadx.GoSafe(func() {
+			if err := service.FlagStudentFeedback(context.Background(), app, fb); err != nil {
+				logx.SystemLogger.Errorf("flag feedback %s: %v", fb.DataId, err)
+			}
+		})
+	}
+
+	response.HTTPSuccess(r, nil)
+}
+
+// HandleListFeedback 回答评价列表（管理员），可按科目、应用与评价筛选
+func HandleListFeedback(c flamego.Context, r flamego.Render, req dto.ListFeedbackRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法查看回答评价")
+		return
+	}
+	if req.Rating != "" && req.Rating != model.FeedbackUp && req.Rating != model.FeedbackDown {
+		response.HTTPFail(r, 400001, "rating 只能为 up 或 down")
+		return
+	}
+
+	offset, limit := normalizePage(req.Offset, req.Limit)
+	feedbacks, total, err := dao.Feedback.List(c.Request().Context(), dao.FeedbackFilter{
+		AppId:       req.FastgptAppId,
+		SubjectName: req.SubjectName,
+		ChatId:      req.ChatId,
+		Rating:      req.Rating,
+	}, offset, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	items := make([]dto.FeedbackItem, 0, len(feedbacks))
+	for _, fb := range feedbacks {
+		items = append(items, dto.FeedbackItem{
+			ID:           fb.ID,
+			FastgptAppId: fb.AppId,
+			SubjectName:  fb.SubjectName,
+			ChatId:       fb.ChatId,
+			DataId:       fb.DataId,
+			UserId:       fb.UserId,
+			StaffId:      fb.StaffId,
+			Rating:       fb.Rating,
+			Reason:       fb.Reason,
+			Forwarded:    fb.Forwarded,
+			ForwardError: fb.ForwardError,
+			UpdatedAt:    fb.UpdatedAt.Format(timeLayout),
+		})
+	}
+
+	response.HTTPSuccess(r, dto.FeedbackListResponse{
+		Feedbacks: items,
+		Total:     total,
+	})
+}
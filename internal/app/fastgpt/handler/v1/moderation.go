@@ -0,0 +1,189 @@
+package v1
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+// writeModerationError 提问未通过内容检查时写入响应
+func writeModerationError(c flamego.Context, r flamego.Render, err error) {
+	if errors.Is(err, service.ErrContentBlocked) {
+		response.HTTPFail(r, 400016, err.Error())
+		return
+	}
+	logx.SystemLogger.CtxError(c.Request().Context(), err)
+	response.ServiceErr(r, err)
+}
+
+// HandleListSensitiveWords 敏感词列表（管理员）
+func HandleListSensitiveWords(c flamego.Context, r flamego.Render, req dto.ListSensitiveWordsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法管理敏感词")
+		return
+	}
+
+	offset, limit := normalizePage(req.Offset, req.Limit)
+	words, total, err := dao.Moderation.ListWords(c.Request().Context(), req.SubjectName, req.Keyword, offset, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	items := make([]dto.SensitiveWordItem, 0, len(words))
+	for _, w := range words {
+		items = append(items, dto.SensitiveWordItem{
+			ID:          w.ID,
+			Word:        w.Word,
+			SubjectName: w.SubjectName,
+			Action:      w.Action,
+			CreatedBy:   w.CreatedBy,
+			CreatedAt:   w.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	response.HTTPSuccess(r, dto.SensitiveWordListResponse{
+		Words: items,
+		Total: total,
+	})
+}
+
+// HandleCreateSensitiveWords 批量添加敏感词（管理员）
+func HandleCreateSensitiveWords(c flamego.Context, r flamego.Render, req dto.CreateSensitiveWordsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法管理敏感词")
+		return
+	}
+
+	action := req.Action
+	if action == "" {
+		action = model.ModerationMask
+	}
+	if action != model.ModerationBlock && action != model.ModerationMask && action != model.ModerationReview {
+		response.HTTPFail(r, 400001, "action 只能为 block、mask 或 review")
+		return
+	}
+
+	seen := map[string]bool{}
+	var words []model.FastgptSensitiveWord
+	for _, w := range req.Words {
+		w = strings.TrimSpace(w)
+		if w == "" || seen[w] {
+			continue
+		}
+		if utf8.RuneCountInString(w) > 100 {
+			response.HTTPFail(r, 400001, "敏感词长度不能超过 100 个字符")
+			return
+		}
+		seen[w] = true
+		words = append(words, model.FastgptSensitiveWord{
+			Word:        w,
+			SubjectName: strings.TrimSpace(req.SubjectName),
+			Action:      action,
+			CreatedBy:   authInfo.Uid,
+		})
+	}
+	if len(words) == 0 {
+		response.HTTPFail(r, 400001, "敏感词不能为空")
+		return
+	}
+
+	if err := dao.Moderation.SaveWords(c.Request().Context(), words); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	service.InvalidateModeration()
+
+	response.HTTPSuccess(r, nil)
+}
+
+// HandleDeleteSensitiveWords 删除敏感词（管理员）
+func HandleDeleteSensitiveWords(c flamego.Context, r flamego.Render, req dto.DeleteSensitiveWordsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法管理敏感词")
+		return
+	}
+	if len(req.Ids) == 0 {
+		response.HTTPFail(r, 400001, "缺少必要参数 ids")
+		return
+	}
+
+	if err := dao.Moderation.DeleteWords(c.Request().Context(), req.Ids); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	service.InvalidateModeration()
+
+	response.HTTPSuccess(r, nil)
+}
+
+// HandleListModerationHits 敏感词命中记录（管理员）
+func HandleListModerationHits(c flamego.Context, r flamego.Render, req dto.ListModerationHitsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法查看命中记录")
+		return
+	}
+
+	offset, limit := normalizePage(req.Offset, req.Limit)
+	hits, total, err := dao.Moderation.ListHits(c.Request().Context(), dao.HitFilter{
+		AppId:     req.FastgptAppId,
+		StaffId:   req.StaffId,
+		Direction: req.Direction,
+	}, offset, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	items := make([]dto.ModerationHitItem, 0, len(hits))
+	for _, h := range hits {
+		items = append(items, dto.ModerationHitItem{
+			ID:           h.ID,
+			FastgptAppId: h.AppId,
+			ChatId:       h.ChatId,
+			UserId:       h.UserId,
+			StaffId:      h.StaffId,
+			Direction:    h.Direction,
+			Action:       h.Action,
+			Keywords:     h.Keywords,
+			Excerpt:      h.Excerpt,
+			CreatedAt:    h.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	response.HTTPSuccess(r, dto.ModerationHitListResponse{
+		Hits:  items,
+		Total: total,
+	})
+}
@@ -0,0 +1,214 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"HelpStudent/core/logx"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/proxy"
+	"HelpStudent/internal/app/fastgpt/service"
+
+	"github.com/guonaihong/gout"
+)
+
+// ChatProxyRoutes 对话相关的 FastGPT 转发接口，挂载在 /fastgpt 下
+var ChatProxyRoutes = []proxy.Route{
+	{
+		Method: http.MethodPost, Path: "/core/chat/history/getHistories", Upstream: "/core/chat/getHistories",
+		Body: dto.GetHistoriesRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessChat,
+		Strip:  []string{"fastgptAppId"},
+		Before: []proxy.RequestHook{withOutLinkIdentity},
+	},
+	{
+		Method: http.MethodPost, Path: "/core/chat/history/updateHistory",
+		Body: dto.UpdateHistoryRequest{}, App: proxy.AppFromBody("appId"), Access: service.AccessChat,
+		Before: []proxy.RequestHook{requireChatOwner},
+	},
+	{
+		Method: http.MethodPost, Path: "/core/chat/getPaginationRecords",
+		Body: dto.GetPaginationRecordsRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessChat,
+		Strip:  []string{"fastgptAppId"},
+		Before: []proxy.RequestHook{withFastGPTAppId, requireChatOwner},
+	},
+	{
+		Method: http.MethodPost, Path: "/core/chat/quote/getCollectionQuote",
+		Body: dto.GetCollectionQuoteRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessChat,
+		Strip:  []string{"fastgptAppId"},
+		Before: []proxy.RequestHook{withOutLinkIdentity, requireChatOwner},
+	},
+	{
+		Method: http.MethodGet, Path: "/core/chat/outLink/init",
+		Query: []string{"shareId", "chatId"}, App: proxy.AppFromShareId("shareId"), Access: service.AccessChat,
+		Before: []proxy.RequestHook{withOutLinkIdentity, chatOwner(true)},
+		After:  []proxy.ResponseHook{replaceUserAvatar},
+	},
+}
+
+// DatasetProxyRoutes 知识库相关的 FastGPT 转发接口，挂载在 /fastgpt 下，仅管理员可用
+var DatasetProxyRoutes = []proxy.Route{
+	{
+		Method: http.MethodPost, Path: "/core/dataset/create",
+		Body: dto.DatasetCreateRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessManage,
+		Strip: []string{"fastgptAppId"},
+	},
+	{
+		Method: http.MethodPost, Path: "/core/dataset/list",
+		Body: dto.DatasetListRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessManage,
+		Strip: []string{"fastgptAppId"},
+	},
+	{
+		Method: http.MethodGet, Path: "/core/dataset/detail",
+		Query: []string{"id"}, Required: []string{"id"}, App: proxy.AppFromQuery("fastgptAppId"), Access: service.AccessManage,
+	},
+	{
+		Method: http.MethodDelete, Path: "/core/dataset/delete",
+		Query: []string{"id"}, Required: []string{"id"}, App: proxy.AppFromQuery("fastgptAppId"), Access: service.AccessManage,
+	},
+	{
+		Method: http.MethodPost, Path: "/core/dataset/collection/create/text",
+		Body: dto.CreateCollectionTextRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessManage,
+		Strip: []string{"fastgptAppId"},
+	},
+	{
+		Method: http.MethodPost, Path: "/core/dataset/collection/create/link",
+		Body: dto.CreateCollectionLinkRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessManage,
+		Strip: []string{"fastgptAppId"},
+	},
+	{
+		Method: http.MethodPost, Path: "/core/dataset/data/pushData",
+		Body: dto.PushDataRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessManage,
+		Strip: []string{"fastgptAppId"},
+	},
+	{
+		Method: http.MethodPost, Path: "/core/dataset/searchTest",
+		Body: dto.SearchTestRequest{}, App: proxy.AppFromBody("fastgptAppId"), Access: service.AccessManage,
+		Strip: []string{"fastgptAppId"},
+	},
+}
+
+// OutLinkProxyRoutes 外链相关的 FastGPT 转发接口，挂载在 /api 下
+var OutLinkProxyRoutes = []proxy.Route{
+	{
+		Method: http.MethodDelete, Path: "/core/chat/delHistory",
+		Query: []string{"chatId", "shareId"}, Required: []string{"shareId", "chatId"},
+		App: proxy.AppFromQuery("FastgptAppId"), Access: service.AccessChat,
+		Before: []proxy.RequestHook{withFastGPTAppId, withOutLinkIdentity, requireChatOwner},
+	},
+}
+
+// withFastGPTAppId 请求体或查询参数中使用 FastGPT 侧的 appId
+func withFastGPTAppId(req *proxy.Request) error {
+	if req.Body != nil {
+		req.Body["appId"] = req.App.AppId
+		return nil
+	}
+	req.Query["appId"] = req.App.AppId
+	return nil
+}
+
+// withOutLinkIdentity 使用由登录用户派生的 outLinkUid 与应用自己的分享链接，忽略客户端传入的值，
+// 避免查看其他用户的会话或借用其他应用的外链；应用未配置分享链接时拒绝请求
+func withOutLinkIdentity(req *proxy.Request) error {
+	if req.App.ShareId == "" {
+		return &proxy.Error{Code: 400019, Message: service.ErrNoShareId.Error()}
+	}
+	uid := service.OutLinkUid(req.Auth.Uid)
+	if req.Body != nil {
+		req.Body["outLinkUid"] = uid
+		req.Body["shareId"] = req.App.ShareId
+		return nil
+	}
+	req.Query["outLinkUid"] = uid
+	req.Query["shareId"] = req.App.ShareId
+	return nil
+}
+
+// requireChatOwner 请求中带有 chatId 时，会话须存在且属于当前用户
+var requireChatOwner = chatOwner(false)
+
+// chatOwner 校验请求中 chatId 对应的会话属于当前用户，allowNew 为 true 时放行本地与 FastGPT 中均无记录的新会话
+func chatOwner(allowNew bool) proxy.RequestHook {
+	return func(req *proxy.Request) error {
+		chatId := req.Query["chatId"]
+		if req.Body != nil {
+			chatId, _ = req.Body["chatId"].(string)
+		}
+		if chatId == "" {
+			return nil
+		}
+		ctx := req.Context.Request().Context()
+		var err error
+		if allowNew {
+			err = service.CheckChatContinue(ctx, req.Auth, req.App, chatId)
+		} else {
+			err = service.CheckChatOwner(ctx, req.Auth, req.App, chatId)
+		}
+		switch {
+		case errors.Is(err, service.ErrChatNotFound):
+			return &proxy.Error{Code: 404001, Message: err.Error()}
+		case errors.Is(err, service.ErrChatForbidden):
+			return &proxy.Error{Code: 403001, Message: err.Error()}
+		}
+		return err
+	}
+}
+
+// replaceUserAvatar 提供了 hduhelpToken 时，用 HDUHelp 头像替换 data.userAvatar
+func replaceUserAvatar(req *proxy.Request, resp *proxy.Response) error {
+	token := req.Context.Query("hduhelpToken")
+	if token == "" {
+		return nil
+	}
+	avatar := getHDUHelpUserAvatar(token)
+	if avatar == "" {
+		return nil
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return nil
+	}
+	if data, ok := result["data"].(map[string]interface{}); ok {
+		data["userAvatar"] = avatar
+		if modifiedBody, err := json.Marshal(result); err == nil {
+			resp.Body = modifiedBody
+		}
+	}
+	return nil
+}
+
+// getHDUHelpUserAvatar 从 HDUHelp API 获取用户头像
+func getHDUHelpUserAvatar(token string) string {
+	type HDUHelpUserResp struct {
+		Error int    `json:"error"`
+		Msg   string `json:"msg"`
+		Data  struct {
+			Avatar string `json:"avatar"`
+		} `json:"data"`
+	}
+
+	var resp HDUHelpUserResp
+
+	err := gout.GET("https://api.hduhelp.com/user/get").
+		SetHeader(gout.H{
+			"Authorization": "token " + token,
+		}).
+		SetTimeout(5 * time.Second).
+		BindJSON(&resp).
+		Do()
+
+	if err != nil {
+		logx.SystemLogger.Errorf("HDUHelp get user avatar error: %v", err)
+		return ""
+	}
+
+	if resp.Error != 0 {
+		logx.SystemLogger.Errorf("HDUHelp get user avatar failed: %s", resp.Msg)
+		return ""
+	}
+
+	return resp.Data.Avatar
+}
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/service"
+)
+
+func TestGetHistories(t *testing.T) {
+	ta := newTestApp(t)
+	student := auth.Info{Uid: "uid1", StaffId: "S001"}
+	if resp := ta.chat(t, student, "chat1", "极限是什么"); resp.status != http.StatusOK {
+		t.Fatalf("chat: %d %s", resp.status, resp.body)
+	}
+
+	histories := func(info auth.Info) []string {
+		t.Helper()
+		resp := serve(ta.as(info), http.MethodPost, "/fastgpt/core/chat/history/getHistories", map[string]interface{}{
+			"fastgptAppId": ta.app.ID,
+			"outLinkUid":   service.OutLinkUid("uid1"),
+		})
+		if resp.status != http.StatusOK {
+			t.Fatalf("getHistories: %d %s", resp.status, resp.body)
+		}
+		var res struct {
+			Data struct {
+				List []struct {
+					ChatId string `json:"chatId"`
+				} `json:"list"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(resp.body, &res); err != nil {
+			t.Fatal(err)
+		}
+		var chatIds []string
+		for _, item := range res.Data.List {
+			chatIds = append(chatIds, item.ChatId)
+		}
+		return chatIds
+	}
+
+	if got := histories(student); len(got) != 1 || got[0] != "chat1" {
+		t.Errorf("own histories = %v", got)
+	}
+	// 传入其他用户的 outLinkUid 无效，只能看到自己的会话
+	if got := histories(auth.Info{Uid: "uid2", StaffId: "S002"}); len(got) != 0 {
+		t.Errorf("other user histories = %v", got)
+	}
+
+	fwd, _ := ta.env.Server.LastRequest("/core/chat/getHistories")
+	var body map[string]interface{}
+	if err := fwd.JSON(&body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["fastgptAppId"]; ok {
+		t.Errorf("fastgptAppId forwarded: %v", body)
+	}
+	if body["outLinkUid"] != service.OutLinkUid("uid2") {
+		t.Errorf("outLinkUid = %v", body["outLinkUid"])
+	}
+	// 请求未带 shareId，转发时使用应用的分享链接
+	if body["shareId"] != "share1" {
+		t.Errorf("shareId = %v, want share1", body["shareId"])
+	}
+}
+
+func TestDelHistory_Owner(t *testing.T) {
+	ta := newTestApp(t)
+	student := auth.Info{Uid: "uid1", StaffId: "S001"}
+	if resp := ta.chat(t, student, "chat1", "极限是什么"); resp.status != http.StatusOK {
+		t.Fatalf("chat: %d %s", resp.status, resp.body)
+	}
+
+	target := "/api/core/chat/delHistory?" + url.Values{
+		"FastgptAppId": {ta.app.ID},
+		"shareId":      {"share1"},
+		"chatId":       {"chat1"},
+	}.Encode()
+
+	resp := serve(ta.as(auth.Info{Uid: "uid2", StaffId: "S002"}), http.MethodDelete, target, nil)
+	if code := resp.code(t); code != 403001 {
+		t.Fatalf("other user delHistory: code = %d, body = %s", code, resp.body)
+	}
+	if _, ok := ta.env.Server.LastRequest("/core/chat/delHistory"); ok {
+		t.Fatal("rejected delHistory was forwarded")
+	}
+	if ta.env.Server.Chat("chat1") == nil {
+		t.Fatal("chat deleted by other user")
+	}
+
+	resp = serve(ta.as(student), http.MethodDelete, target, nil)
+	if resp.status != http.StatusOK {
+		t.Fatalf("owner delHistory: %d %s", resp.status, resp.body)
+	}
+	fwd, ok := ta.env.Server.LastRequest("/core/chat/delHistory")
+	if !ok {
+		t.Fatal("delHistory not forwarded")
+	}
+	if fwd.Query["appId"] != "app1" || fwd.Query["outLinkUid"] != service.OutLinkUid("uid1") || fwd.Query["shareId"] != "share1" {
+		t.Errorf("forwarded query = %v", fwd.Query)
+	}
+	if ta.env.Server.Chat("chat1") != nil {
+		t.Error("chat not deleted")
+	}
+}
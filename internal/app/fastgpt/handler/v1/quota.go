@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"HelpStudent/config"
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+	"gorm.io/gorm"
+)
+
+// acquireChatQuota 占用对话配额，超出时直接写入响应
+func acquireChatQuota(c flamego.Context, r flamego.Render, authInfo auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest) (func(), bool) {
+	release, err := service.AcquireChatQuota(c.Request().Context(), authInfo, app)
+	if err == nil {
+		return release, true
+	}
+
+	var qe *service.QuotaError
+	if errors.As(err, &qe) {
+		service.RecordRejectedUsage(c.Request().Context(), authInfo, app, req, false, model.UsageStatusRateLimited)
+		c.ResponseWriter().Header().Set("Retry-After", strconv.Itoa(qe.RetryAfterSeconds()))
+		response.HTTPFailWithData(r, 429001, qe.Message, dto.QuotaExceededData{RetryAfter: qe.RetryAfterSeconds()})
+		return nil, false
+	}
+	logx.SystemLogger.CtxError(c.Request().Context(), err)
+	response.ServiceErr(r, err)
+	return nil, false
+}
+
+// acquireStreamQuota 占用对话配额，排队期间发送 queue 事件告知位置，超出时发送 SSE error 事件
+func acquireStreamQuota(ctx context.Context, msg chan<- *dto.SSEMessage, authInfo auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest) (func(), bool) {
+	release, err := service.AcquireStreamQuota(ctx, authInfo, app, func(s service.QueueStatus) {
+		body, _ := json.Marshal(dto.SSEQueueData{
+			Position:      s.Position,
+			EstimatedWait: int(s.EstimatedWait / time.Second),
+		})
+		sendSSEMessage(ctx, msg, &dto.SSEMessage{Data: string(body), Event: "queue"})
+	})
+	if err == nil {
+		return release, true
+	}
+	if ctx.Err() != nil {
+		// 排队期间客户端离开
+		service.RecordRejectedUsage(ctx, authInfo, app, req, true, model.ChatStatusAborted)
+		return nil, false
+	}
+
+	data := dto.SSEErrorData{Error: "请求失败"}
+	var qe *service.QuotaError
+	if errors.As(err, &qe) {
+		service.RecordRejectedUsage(ctx, authInfo, app, req, true, model.UsageStatusRateLimited)
+		data = dto.SSEErrorData{Error: qe.Message, Code: 429001, RetryAfter: qe.RetryAfterSeconds()}
+	} else {
+		logx.SystemLogger.CtxError(ctx, err)
+	}
+	sendSSEError(ctx, msg, data)
+	return nil, false
+}
+
+func toQuotaItem(q config.Quota) dto.AppQuotaItem {
+	return dto.AppQuotaItem{
+		UserRPM:           q.UserRPM,
+		UserDailyMessages: q.UserDailyMessages,
+		AppConcurrency:    q.AppConcurrency,
+		AppStreams:        q.AppStreams,
+	}
+}
+
+// applyQuotaField 按请求修改单个配额字段，负数表示恢复默认值
+func applyQuotaField(dst **int, v *int) {
+	if v == nil {
+		return
+	}
+	if *v < 0 {
+		*dst = nil
+		return
+	}
+	val := *v
+	*dst = &val
+}
+
+// HandleGetAppQuota 获取应用配额
+func HandleGetAppQuota(c flamego.Context, r flamego.Render, req dto.GetAppQuotaRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法查看应用配额")
+		return
+	}
+
+	effective, err := service.EffectiveQuota(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, dto.AppQuotaResponse{
+		ID:        req.ID,
+		Effective: toQuotaItem(effective),
+		Default:   toQuotaItem(config.GetConfig().FastGPT.Quota),
+	})
+}
+
+// HandleUpdateAppQuota 更新应用配额
+func HandleUpdateAppQuota(c flamego.Context, r flamego.Render, req dto.UpdateAppQuotaRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法修改应用配额")
+		return
+	}
+
+	// 检查应用是否存在
+	_, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.HTTPFail(r, 404001, "应用不存在")
+			return
+		}
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	quota, err := dao.AppQuota.GetByAppId(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	if quota == nil {
+		quota = &model.FastgptAppQuota{AppId: req.ID}
+	}
+	applyQuotaField(&quota.UserRPM, req.UserRPM)
+	applyQuotaField(&quota.UserDailyMessages, req.UserDailyMessages)
+	applyQuotaField(&quota.AppConcurrency, req.AppConcurrency)
+	applyQuotaField(&quota.AppStreams, req.AppStreams)
+	quota.UpdatedBy = authInfo.Uid
+
+	if err := dao.AppQuota.Save(c.Request().Context(), quota); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	effective, err := service.EffectiveQuota(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, dto.AppQuotaResponse{
+		ID:        req.ID,
+		Effective: toQuotaItem(effective),
+		Default:   toQuotaItem(config.GetConfig().FastGPT.Quota),
+	})
+}
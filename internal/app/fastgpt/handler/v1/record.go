@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"time"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// parseRecordFilter 解析聊天记录查询条件
+func parseRecordFilter(req dto.ListChatRecordsRequest) (dao.RecordFilter, error) {
+	f := dao.RecordFilter{
+		AppId:   req.FastgptAppId,
+		ChatId:  req.ChatId,
+		StaffId: req.StaffId,
+		Status:  req.Status,
+	}
+	if req.StartTime != "" {
+		t, err := time.ParseInLocation(timeLayout, req.StartTime, time.Local)
+		if err != nil {
+			return f, err
+		}
+		f.Start = &t
+	}
+	if req.EndTime != "" {
+		t, err := time.ParseInLocation(timeLayout, req.EndTime, time.Local)
+		if err != nil {
+			return f, err
+		}
+		f.End = &t
+	}
+	return f, nil
+}
+
+// normalizePage 修正分页参数
+func normalizePage(offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return offset, limit
+}
+
+// HandleListChatSessions 查询本地镜像的会话列表（管理员）
+func HandleListChatSessions(c flamego.Context, r flamego.Render, req dto.ListChatRecordsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法查看聊天记录")
+		return
+	}
+
+	filter, err := parseRecordFilter(req)
+	if err != nil {
+		response.HTTPFail(r, 400001, "时间格式错误，应为 "+timeLayout)
+		return
+	}
+	offset, limit := normalizePage(req.Offset, req.Limit)
+
+	sessions, total, err := dao.ChatRecord.ListSessions(c.Request().Context(), filter, offset, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	items := make([]dto.ChatSessionItem, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, dto.ChatSessionItem{
+			ID:            s.ID,
+			FastgptAppId:  s.AppId,
+			ChatId:        s.ChatId,
+			UserId:        s.UserId,
+			StaffId:       s.StaffId,
+			Title:         s.Title,
+			MessageCount:  s.MessageCount,
+			LastMessageAt: s.LastMessageAt.Format(timeLayout),
+		})
+	}
+
+	response.HTTPSuccess(r, dto.ChatSessionListResponse{
+		Sessions: items,
+		Total:    total,
+	})
+}
+
+// HandleListChatMessages 查询本地镜像的问答记录（管理员）
+func HandleListChatMessages(c flamego.Context, r flamego.Render, req dto.ListChatRecordsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 403001, "非管理员无法查看聊天记录")
+		return
+	}
+
+	filter, err := parseRecordFilter(req)
+	if err != nil {
+		response.HTTPFail(r, 400001, "时间格式错误，应为 "+timeLayout)
+		return
+	}
+	offset, limit := normalizePage(req.Offset, req.Limit)
+
+	messages, total, err := dao.ChatRecord.ListMessages(c.Request().Context(), filter, offset, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	items := make([]dto.ChatMessageItem, 0, len(messages))
+	for _, m := range messages {
+		item := dto.ChatMessageItem{
+			ID:           m.ID,
+			FastgptAppId: m.AppId,
+			ChatId:       m.ChatId,
+			UserId:       m.UserId,
+			StaffId:      m.StaffId,
+			Question:     m.Question,
+			Answer:       m.Answer,
+			Stream:       m.Stream,
+			Status:       m.Status,
+			ErrorMsg:     m.ErrorMsg,
+			RequestAt:    m.RequestAt.Format(timeLayout),
+			LatencyMs:    m.LatencyMs,
+			FirstChunkMs: m.FirstChunkMs,
+		}
+		if m.FinishedAt != nil {
+			item.FinishedAt = m.FinishedAt.Format(timeLayout)
+		}
+		items = append(items, item)
+	}
+
+	response.HTTPSuccess(r, dto.ChatMessageListResponse{
+		Messages: items,
+		Total:    total,
+	})
+}
@@ -0,0 +1,510 @@
+package v1
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+	"gorm.io/gorm"
+)
+
+// openReviewStatuses 可以指派、解决或忽略的状态
+var openReviewStatuses = []string{model.ReviewPending, model.ReviewInProgress}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(timeLayout)
+}
+
+func toReviewItem(rv *model.FastgptReview) dto.ReviewItem {
+	sources := []string{}
+	for _, s := range strings.Split(rv.Sources, ",") {
+		if s != "" {
+			sources = append(sources, s)
+		}
+	}
+	return dto.ReviewItem{
+		ID:              rv.ID,
+		FastgptAppId:    rv.AppId,
+		SubjectName:     rv.SubjectName,
+		ChatId:          rv.ChatId,
+		DataId:          rv.DataId,
+		StaffId:         rv.StaffId,
+		Question:        rv.Question,
+		Answer:          rv.Answer,
+		Sources:         sources,
+		Reason:          rv.Reason,
+		QuoteScore:      rv.QuoteScore,
+		FlagCount:       rv.FlagCount,
+		Status:          rv.Status,
+		Assignee:        rv.Assignee,
+		CorrectedAnswer: rv.CorrectedAnswer,
+		CollectionId:    rv.CollectionId,
+		PushedAt:        formatOptionalTime(rv.PushedAt),
+		PushError:       rv.PushError,
+		ResolvedBy:      rv.ResolvedBy,
+		ResolvedAt:      formatOptionalTime(rv.ResolvedAt),
+		CreatedAt:       rv.CreatedAt.Format(timeLayout),
+		UpdatedAt:       rv.UpdatedAt.Format(timeLayout),
+	}
+}
+
+// getReviewableItem 获取审核项并检查当前用户是否为该科目的审核教师或管理员
+func getReviewableItem(c flamego.Context, r flamego.Render, authInfo auth.Info, id string) (*model.FastgptReview, bool) {
+	ctx := c.Request().Context()
+	rv, err := dao.Review.Get(ctx, id)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return nil, false
+	}
+	if rv == nil {
+		response.HTTPFail(r, 404001, "审核项不存在")
+		return nil, false
+	}
+	ok, err := service.CanReview(ctx, authInfo, rv.AppId)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return nil, false
+	}
+	if !ok {
+		response.HTTPFail(r, 403001, "不是该科目的审核教师")
+		return nil, false
+	}
+	return rv, true
+}
+
+// transitionReview 按状态更新审核项并返回最新内容，状态不允许时返回 400018
+func transitionReview(c flamego.Context, r flamego.Render, id string, updates map[string]interface{}) (*model.FastgptReview, bool) {
+	ctx := c.Request().Context()
+	updated, err := dao.Review.Transition(ctx, id, openReviewStatuses, updates)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return nil, false
+	}
+	if !updated {
+		response.HTTPFail(r, 400018, "审核项已处理，不能再修改")
+		return nil, false
+	}
+	return getReviewAfterUpdate(c, r, id)
+}
+
+// getReviewAfterUpdate 更新后重新获取审核项
+func getReviewAfterUpdate(c flamego.Context, r flamego.Render, id string) (*model.FastgptReview, bool) {
+	rv, err := dao.Review.Get(c.Request().Context(), id)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return nil, false
+	}
+	if rv == nil {
+		response.HTTPFail(r, 404001, "审核项不存在")
+		return nil, false
+	}
+	return rv, true
+}
+
+// pushReviewCorrection 写入知识库，应用不存在、未接入 FastGPT 或集合不属于应用的知识库时直接返回错误响应
+// 写入 FastGPT 失败时结果记录在审核项上，由调用方返回
+func pushReviewCorrection(c flamego.Context, r flamego.Render, authInfo auth.Info, rv *model.FastgptReview, collectionId string) bool {
+	ctx := c.Request().Context()
+	if rv.Question == "" {
+		response.HTTPFail(r, 400001, "审核项缺少原始提问，无法写入知识库")
+		return false
+	}
+	app, err := dao.FastgptApp.GetAppByPrimaryID(ctx, rv.AppId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.HTTPFail(r, 404001, "应用不存在")
+			return false
+		}
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return false
+	}
+	if !app.IsFastGPT() {
+		response.HTTPFail(r, 400017, "该应用未接入 FastGPT，不能写入知识库")
+		return false
+	}
+	// 上次已写入的集合无需再次校验
+	if collectionId != rv.CollectionId {
+		if err := service.CheckReviewCollection(ctx, authInfo, app, collectionId); err != nil {
+			if errors.Is(err, service.ErrCollectionForbidden) {
+				response.HTTPFail(r, 403001, err.Error())
+				return false
+			}
+			logx.SystemLogger.CtxError(ctx, err)
+			response.ServiceErr(r, err)
+			return false
+		}
+	}
+	if err := service.PushCorrection(ctx, app, rv, collectionId); err != nil {
+		logx.SystemLogger.CtxError(ctx, "push review correction", rv.ID, err)
+	}
+	return true
+}
+
+// HandleListReviews 审核队列，教师只能看到自己负责科目的审核项
+func HandleListReviews(c flamego.Context, r flamego.Render, req dto.ListReviewsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	switch req.Status {
+	case "", model.ReviewPending, model.ReviewInProgress, model.ReviewResolved, model.ReviewDismissed:
+	default:
+		response.HTTPFail(r, 400001, "不支持的审核状态："+req.Status)
+		return
+	}
+	switch req.Source {
+	case "", model.ReviewSourceStudent, model.ReviewSourceKeyword, model.ReviewSourceLowSimilarity:
+	default:
+		response.HTTPFail(r, 400001, "不支持的标记来源："+req.Source)
+		return
+	}
+
+	ctx := c.Request().Context()
+	appIds, all, err := service.ReviewScope(ctx, authInfo)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+	f := dao.ReviewFilter{AppId: req.FastgptAppId, Status: req.Status, Source: req.Source}
+	if !all {
+		f.AppIds = appIds
+	}
+	if req.Mine {
+		f.Assignee = authInfo.StaffId
+	}
+
+	offset, limit := normalizePage(req.Offset, req.Limit)
+	reviews, total, err := dao.Review.List(ctx, f, offset, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	items := make([]dto.ReviewItem, 0, len(reviews))
+	for i := range reviews {
+		items = append(items, toReviewItem(&reviews[i]))
+	}
+	response.HTTPSuccess(r, dto.ReviewListResponse{
+		Reviews: items,
+		Total:   total,
+	})
+}
+
+// HandleGetReview 审核项详情及评论
+func HandleGetReview(c flamego.Context, r flamego.Render, req dto.ReviewIdRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	rv, ok := getReviewableItem(c, r, authInfo, req.ID)
+	if !ok {
+		return
+	}
+
+	comments, err := dao.Review.ListComments(c.Request().Context(), rv.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	detail := dto.ReviewDetailResponse{
+		ReviewItem: toReviewItem(rv),
+		Comments:   make([]dto.ReviewComment, 0, len(comments)),
+	}
+	for _, cm := range comments {
+		detail.Comments = append(detail.Comments, dto.ReviewComment{
+			ID:        cm.ID,
+			StaffId:   cm.StaffId,
+			Content:   cm.Content,
+			CreatedAt: cm.CreatedAt.Format(timeLayout),
+		})
+	}
+	response.HTTPSuccess(r, detail)
+}
+
+// HandleAssignReview 指派审核项，处理人须为该科目的审核教师或管理员
+func HandleAssignReview(c flamego.Context, r flamego.Render, req dto.AssignReviewRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	rv, ok := getReviewableItem(c, r, authInfo, req.ID)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request().Context()
+	assignee := strings.TrimSpace(req.Assignee)
+	if assignee == "" {
+		assignee = authInfo.StaffId
+	}
+	if assignee != authInfo.StaffId {
+		canReview, err := service.CanReview(ctx, auth.Info{StaffId: assignee}, rv.AppId)
+		if err != nil {
+			logx.SystemLogger.CtxError(ctx, err)
+			response.ServiceErr(r, err)
+			return
+		}
+		if !canReview {
+			response.HTTPFail(r, 400001, "处理人不是该科目的审核教师")
+			return
+		}
+	}
+
+	rv, ok = transitionReview(c, r, rv.ID, map[string]interface{}{
+		"status":   model.ReviewInProgress,
+		"assignee": assignee,
+	})
+	if !ok {
+		return
+	}
+	response.HTTPSuccess(r, toReviewItem(rv))
+}
+
+// HandleCommentReview 评论审核项
+func HandleCommentReview(c flamego.Context, r flamego.Render, req dto.CommentReviewRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		response.HTTPFail(r, 400001, "评论内容不能为空")
+		return
+	}
+	rv, ok := getReviewableItem(c, r, authInfo, req.ID)
+	if !ok {
+		return
+	}
+
+	comment := &model.FastgptReviewComment{
+		ReviewId: rv.ID,
+		UserId:   authInfo.Uid,
+		StaffId:  authInfo.StaffId,
+		Content:  content,
+	}
+	if err := dao.Review.AddComment(c.Request().Context(), comment); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	response.HTTPSuccess(r, dto.ReviewComment{
+		ID:        comment.ID,
+		StaffId:   comment.StaffId,
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt.Format(timeLayout),
+	})
+}
+
+// HandleResolveReview 给出修正回答，填写 collectionId 时写入 FastGPT 知识库
+// 写入失败不影响解决状态，错误记录在 pushError 中，可通过 push 接口重试
+func HandleResolveReview(c flamego.Context, r flamego.Render, req dto.ResolveReviewRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	corrected := strings.TrimSpace(req.CorrectedAnswer)
+	if corrected == "" {
+		response.HTTPFail(r, 400001, "修正回答不能为空")
+		return
+	}
+	rv, ok := getReviewableItem(c, r, authInfo, req.ID)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	rv, ok = transitionReview(c, r, rv.ID, map[string]interface{}{
+		"status":           model.ReviewResolved,
+		"corrected_answer": corrected,
+		"resolved_by":      authInfo.StaffId,
+		"resolved_at":      now,
+	})
+	if !ok {
+		return
+	}
+	if collectionId := strings.TrimSpace(req.CollectionId); collectionId != "" {
+		if !pushReviewCorrection(c, r, authInfo, rv, collectionId) {
+			return
+		}
+		if rv, ok = getReviewAfterUpdate(c, r, rv.ID); !ok {
+			return
+		}
+	}
+	response.HTTPSuccess(r, toReviewItem(rv))
+}
+
+// HandleDismissReview 回答无误时忽略审核项
+func HandleDismissReview(c flamego.Context, r flamego.Render, req dto.DismissReviewRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	rv, ok := getReviewableItem(c, r, authInfo, req.ID)
+	if !ok {
+		return
+	}
+
+	rv, ok = transitionReview(c, r, rv.ID, map[string]interface{}{
+		"status":      model.ReviewDismissed,
+		"resolved_by": authInfo.StaffId,
+		"resolved_at": time.Now(),
+	})
+	if !ok {
+		return
+	}
+	if content := strings.TrimSpace(req.Comment); content != "" {
+		err := dao.Review.AddComment(c.Request().Context(), &model.FastgptReviewComment{
+			ReviewId: rv.ID,
+			UserId:   authInfo.Uid,
+			StaffId:  authInfo.StaffId,
+			Content:  content,
+		})
+		if err != nil {
+			logx.SystemLogger.CtxError(c.Request().Context(), err)
+		}
+	}
+	response.HTTPSuccess(r, toReviewItem(rv))
+}
+
+// HandlePushReview 将已解决审核项的修正回答写入 FastGPT 知识库，用于首次写入或失败重试
+func HandlePushReview(c flamego.Context, r flamego.Render, req dto.PushReviewRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	rv, ok := getReviewableItem(c, r, authInfo, req.ID)
+	if !ok {
+		return
+	}
+	if rv.Status != model.ReviewResolved {
+		response.HTTPFail(r, 400018, "只有已解决的审核项可以写入知识库")
+		return
+	}
+	collectionId := strings.TrimSpace(req.CollectionId)
+	if collectionId == "" {
+		collectionId = rv.CollectionId
+	}
+	if collectionId == "" {
+		response.HTTPFail(r, 400001, "缺少 collectionId")
+		return
+	}
+
+	if !pushReviewCorrection(c, r, authInfo, rv, collectionId) {
+		return
+	}
+	if rv, ok = getReviewAfterUpdate(c, r, rv.ID); !ok {
+		return
+	}
+	response.HTTPSuccess(r, toReviewItem(rv))
+}
+
+// HandleListReviewers 应用的审核教师列表（管理员）
+func HandleListReviewers(c flamego.Context, r flamego.Render, req dto.ReviewerRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法查看审核教师")
+		return
+	}
+
+	reviewers, err := dao.Review.ListReviewers(c.Request().Context(), req.FastgptAppId)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	items := make([]dto.ReviewerItem, 0, len(reviewers))
+	for _, rv := range reviewers {
+		items = append(items, dto.ReviewerItem{
+			StaffId:   rv.StaffId,
+			CreatedBy: rv.CreatedBy,
+			CreatedAt: rv.CreatedAt.Format(timeLayout),
+		})
+	}
+	response.HTTPSuccess(r, items)
+}
+
+// HandleAddReviewer 添加应用的审核教师（管理员）
+func HandleAddReviewer(c flamego.Context, r flamego.Render, req dto.ReviewerRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法设置审核教师")
+		return
+	}
+	staffId := strings.TrimSpace(req.StaffId)
+	if staffId == "" {
+		response.HTTPFail(r, 400001, "缺少 staffId")
+		return
+	}
+
+	ctx := c.Request().Context()
+	if _, err := dao.FastgptApp.GetAppByPrimaryID(ctx, req.FastgptAppId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.HTTPFail(r, 404001, "应用不存在")
+			return
+		}
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+	err := dao.Review.AddReviewer(ctx, &model.FastgptReviewer{
+		AppId:     req.FastgptAppId,
+		StaffId:   staffId,
+		CreatedBy: authInfo.StaffId,
+	})
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, err)
+		response.ServiceErr(r, err)
+		return
+	}
+	response.HTTPSuccess(r, nil)
+}
+
+// HandleRemoveReviewer 移除应用的审核教师（管理员），已指派给该教师的审核项保持不变
+func HandleRemoveReviewer(c flamego.Context, r flamego.Render, req dto.ReviewerRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法设置审核教师")
+		return
+	}
+	if req.StaffId == "" {
+		response.HTTPFail(r, 400001, "缺少 staffId")
+		return
+	}
+
+	if err := dao.Review.RemoveReviewer(c.Request().Context(), req.FastgptAppId, req.StaffId); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	response.HTTPSuccess(r, nil)
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+// recordAppRevision 记录应用配置变更，变更已生效，记录失败只写日志
+func recordAppRevision(ctx context.Context, action string, before, after *model.FastgptApp, authInfo auth.Info) {
+	if err := service.RecordAppRevision(ctx, action, before, after, authInfo.Uid, ""); err != nil {
+		logx.SystemLogger.CtxError(ctx, "record app revision", err)
+	}
+}
+
+func toRevisionItem(rev *model.FastgptAppRevision) dto.AppRevisionItem {
+	changes := service.RevisionChanges(rev)
+	item := dto.AppRevisionItem{
+		Version:   rev.Version,
+		Action:    rev.Action,
+		Changes:   make([]dto.AppFieldChange, 0, len(changes)),
+		Operator:  rev.Operator,
+		Comment:   rev.Comment,
+		CreatedAt: rev.CreatedAt.Format(timeLayout),
+	}
+	for _, ch := range changes {
+		item.Changes = append(item.Changes, dto.AppFieldChange{Field: ch.Field, Old: ch.Old, New: ch.New})
+	}
+	return item
+}
+
+// HandleListAppRevisions 获取应用配置的历史版本，包括已删除的应用
+func HandleListAppRevisions(c flamego.Context, r flamego.Render, req dto.ListAppRevisionsRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法查看应用配置历史")
+		return
+	}
+
+	offset, limit := normalizePage(req.Offset, req.Limit)
+	revisions, total, err := dao.AppRevision.List(c.Request().Context(), req.AppId, offset, limit)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	items := make([]dto.AppRevisionItem, 0, len(revisions))
+	for i := range revisions {
+		items = append(items, toRevisionItem(&revisions[i]))
+	}
+	response.HTTPSuccess(r, dto.AppRevisionListResponse{Revisions: items, Total: total})
+}
+
+// HandleRollbackApp 将应用配置恢复为指定版本，可用于恢复误删的应用
+func HandleRollbackApp(c flamego.Context, r flamego.Render, req dto.RollbackAppRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法回滚应用配置")
+		return
+	}
+
+	app, err := service.RollbackApp(c.Request().Context(), req.AppId, req.Version, authInfo.Uid)
+	if err != nil {
+		if errors.Is(err, service.ErrRevisionNotFound) {
+			response.HTTPFail(r, 404001, "版本不存在")
+			return
+		}
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, toAppItem(app))
+}
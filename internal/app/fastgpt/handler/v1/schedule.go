@@ -0,0 +1,252 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+	"gorm.io/gorm"
+)
+
+// checkAvailability 应用停用或不在开放时间时学生不能对话，管理员不受限制，失败时直接写入响应
+func checkAvailability(c flamego.Context, r flamego.Render, authInfo auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest) bool {
+	if dao2.Managers.IsManager(authInfo.StaffId) {
+		return true
+	}
+	err := service.CheckAvailability(c.Request().Context(), app)
+	if err == nil {
+		return true
+	}
+
+	var ue *service.UnavailableError
+	if errors.As(err, &ue) {
+		service.RecordRejectedUsage(c.Request().Context(), authInfo, app, req, false, model.UsageStatusUnavailable)
+		response.HTTPFailWithData(r, 403002, ue.Message, dto.AppUnavailableData{AvailableAt: formatAvailableAt(ue)})
+		return false
+	}
+	logx.SystemLogger.CtxError(c.Request().Context(), err)
+	response.ServiceErr(r, err)
+	return false
+}
+
+// checkStreamAvailability 同 checkAvailability，不可用时发送 SSE error 事件
+func checkStreamAvailability(ctx context.Context, msg chan<- *dto.SSEMessage, authInfo auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest) bool {
+	if dao2.Managers.IsManager(authInfo.StaffId) {
+		return true
+	}
+	err := service.CheckAvailability(ctx, app)
+	if err == nil {
+		return true
+	}
+
+	data := dto.SSEErrorData{Error: "请求失败"}
+	var ue *service.UnavailableError
+	if errors.As(err, &ue) {
+		service.RecordRejectedUsage(ctx, authInfo, app, req, true, model.UsageStatusUnavailable)
+		data = dto.SSEErrorData{Error: ue.Message, Code: 403002, AvailableAt: formatAvailableAt(ue)}
+	} else {
+		logx.SystemLogger.CtxError(ctx, err)
+	}
+	sendSSEError(ctx, msg, data)
+	return false
+}
+
+func formatAvailableAt(ue *service.UnavailableError) string {
+	if ue.AvailableAt == nil {
+		return ""
+	}
+	return ue.AvailableAt.Format(timeLayout)
+}
+
+func toScheduleItem(s *model.FastgptAppSchedule) dto.AppScheduleItem {
+	item := dto.AppScheduleItem{
+		ID:        s.ID,
+		AppId:     s.AppId,
+		Type:      s.Type,
+		StartTime: s.StartTime,
+		EndTime:   s.EndTime,
+		Message:   s.Message,
+		CreatedBy: s.CreatedBy,
+		CreatedAt: s.CreatedAt.Format(timeLayout),
+	}
+	for _, part := range strings.Split(s.Weekdays, ",") {
+		if d, err := strconv.Atoi(part); err == nil {
+			item.Weekdays = append(item.Weekdays, d)
+		}
+	}
+	if s.StartAt != nil {
+		item.StartAt = s.StartAt.Format(timeLayout)
+	}
+	if s.EndAt != nil {
+		item.EndAt = s.EndAt.Format(timeLayout)
+	}
+	return item
+}
+
+// buildSchedule 校验请求并转换为时段，返回错误提示
+func buildSchedule(req *dto.CreateAppScheduleRequest) (*model.FastgptAppSchedule, string) {
+	schedule := &model.FastgptAppSchedule{
+		AppId:   req.AppId,
+		Type:    req.Type,
+		Message: strings.TrimSpace(req.Message),
+	}
+	switch req.Type {
+	case model.ScheduleOpen:
+		weekdays, err := service.FormatWeekdays(req.Weekdays)
+		if err != nil {
+			return nil, err.Error()
+		}
+		if !service.ValidClock(req.StartTime) || !service.ValidClock(req.EndTime) {
+			return nil, "开放时间格式应为 HH:MM"
+		}
+		if req.StartTime == req.EndTime {
+			return nil, "开始时间与结束时间不能相同"
+		}
+		schedule.Weekdays, schedule.StartTime, schedule.EndTime = weekdays, req.StartTime, req.EndTime
+	case model.ScheduleBlackout:
+		startAt, err1 := time.ParseInLocation(timeLayout, req.StartAt, time.Local)
+		endAt, err2 := time.ParseInLocation(timeLayout, req.EndAt, time.Local)
+		if err1 != nil || err2 != nil {
+			return nil, "暂停时间格式应为 2006-01-02 15:04:05"
+		}
+		if !endAt.After(startAt) {
+			return nil, "结束时间需晚于开始时间"
+		}
+		schedule.StartAt, schedule.EndAt = &startAt, &endAt
+	default:
+		return nil, "时段类型只能为 open 或 blackout"
+	}
+	return schedule, ""
+}
+
+// HandleListAppSchedules 获取应用的开放时段与暂停时段
+func HandleListAppSchedules(c flamego.Context, r flamego.Render, req dto.ListAppSchedulesRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法查看应用开放时间")
+		return
+	}
+
+	app, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.AppId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.HTTPFail(r, 404001, "应用不存在")
+			return
+		}
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	schedules, err := dao.AppSchedule.ListByApp(c.Request().Context(), req.AppId)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	resp := dto.AppScheduleListResponse{Schedules: make([]dto.AppScheduleItem, 0, len(schedules)), Available: true}
+	for i := range schedules {
+		resp.Schedules = append(resp.Schedules, toScheduleItem(&schedules[i]))
+	}
+
+	err = service.CheckAvailability(c.Request().Context(), app)
+	var ue *service.UnavailableError
+	switch {
+	case errors.As(err, &ue):
+		resp.Available, resp.Message, resp.AvailableAt = false, ue.Message, formatAvailableAt(ue)
+	case err != nil:
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, resp)
+}
+
+// HandleCreateAppSchedule 添加开放时段或暂停时段
+func HandleCreateAppSchedule(c flamego.Context, r flamego.Render, req dto.CreateAppScheduleRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法修改应用开放时间")
+		return
+	}
+
+	if _, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.AppId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.HTTPFail(r, 404001, "应用不存在")
+			return
+		}
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	schedule, msg := buildSchedule(&req)
+	if msg != "" {
+		response.HTTPFail(r, 400001, msg)
+		return
+	}
+	schedule.CreatedBy = authInfo.Uid
+	if err := dao.AppSchedule.Create(c.Request().Context(), schedule); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, toScheduleItem(schedule))
+}
+
+// HandleDeleteAppSchedule 删除时段
+func HandleDeleteAppSchedule(c flamego.Context, r flamego.Render, req dto.DeleteAppScheduleRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法修改应用开放时间")
+		return
+	}
+
+	schedule, err := dao.AppSchedule.Get(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	if schedule == nil {
+		response.HTTPFail(r, 404001, "时段不存在")
+		return
+	}
+	if err := dao.AppSchedule.Delete(c.Request().Context(), req.ID); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, nil)
+}
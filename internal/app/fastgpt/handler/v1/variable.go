@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+	dao2 "HelpStudent/internal/app/managers/dao"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+	"gorm.io/gorm"
+)
+
+func toAppVariablesResponse(appId string, setting *model.FastgptVariableSetting) dto.AppVariablesResponse {
+	resp := dto.AppVariablesResponse{
+		ID:            appId,
+		Injected:      []dto.InjectedVariableItem{},
+		AllowedClient: []string{},
+		Sources:       service.VariableSources,
+	}
+	if setting != nil {
+		resp.Configured = true
+		for _, v := range service.InjectedVariables(setting) {
+			resp.Injected = append(resp.Injected, dto.InjectedVariableItem{Name: v.Name, Source: v.Source})
+		}
+		if names := service.AllowedClientVariables(setting); names != nil {
+			resp.AllowedClient = names
+		}
+	}
+	return resp
+}
+
+// validVariableName 变量名不能为空或包含逗号
+func validVariableName(name string) bool {
+	return name != "" && !strings.Contains(name, ",")
+}
+
+// HandleGetAppVariables 获取应用的对话变量设置
+func HandleGetAppVariables(c flamego.Context, r flamego.Render, req dto.AppVariablesRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法查看对话变量设置")
+		return
+	}
+
+	setting, err := dao.Variables.GetByAppId(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, toAppVariablesResponse(req.ID, setting))
+}
+
+// HandleUpdateAppVariables 更新应用的对话变量设置，保存后只转发允许的客户端变量
+func HandleUpdateAppVariables(c flamego.Context, r flamego.Render, req dto.UpdateAppVariablesRequest, errs binding.Errors, authInfo auth.Info) {
+	if errs != nil {
+		response.InValidParam(r, errs)
+		return
+	}
+
+	// 检查是否是管理员
+	if !dao2.Managers.IsManager(authInfo.StaffId) {
+		response.HTTPFail(r, 400013, "非管理员无法修改对话变量设置")
+		return
+	}
+
+	// 检查应用是否存在
+	_, err := dao.FastgptApp.GetAppByPrimaryID(c.Request().Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.HTTPFail(r, 404001, "应用不存在")
+			return
+		}
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	setting, err := dao.Variables.GetByAppId(c.Request().Context(), req.ID)
+	if err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+	if setting == nil {
+		setting = &model.FastgptVariableSetting{AppId: req.ID}
+	}
+	if req.Injected != nil {
+		injected := make([]service.InjectedVariable, 0, len(req.Injected))
+		seen := map[string]bool{}
+		for _, v := range req.Injected {
+			name, source := strings.TrimSpace(v.Name), strings.TrimSpace(v.Source)
+			if !validVariableName(name) || seen[name] {
+				response.HTTPFail(r, 400001, "注入变量名不能为空、重复或包含逗号")
+				return
+			}
+			if !service.ValidVariableSource(source) {
+				response.HTTPFail(r, 400001, "不支持的变量来源："+source)
+				return
+			}
+			seen[name] = true
+			injected = append(injected, service.InjectedVariable{Name: name, Source: source})
+		}
+		data, err := json.Marshal(injected)
+		if err != nil {
+			response.ServiceErr(r, err)
+			return
+		}
+		setting.Injected = string(data)
+	}
+	if req.AllowedClient != nil {
+		var names []string
+		for _, name := range req.AllowedClient {
+			if name = strings.TrimSpace(name); validVariableName(name) {
+				names = append(names, name)
+			}
+		}
+		setting.AllowedClient = strings.Join(names, ",")
+	}
+	setting.UpdatedBy = authInfo.Uid
+
+	if err := dao.Variables.Save(c.Request().Context(), setting); err != nil {
+		logx.SystemLogger.CtxError(c.Request().Context(), err)
+		response.ServiceErr(r, err)
+		return
+	}
+
+	response.HTTPSuccess(r, toAppVariablesResponse(req.ID, setting))
+}
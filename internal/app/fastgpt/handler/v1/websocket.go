@@ -0,0 +1,216 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/threadx"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/service"
+
+	"github.com/flamego/flamego"
+	"golang.org/x/net/websocket"
+)
+
+// ChatSubprotocol 聊天 WebSocket 的子协议，通过子协议传递凭证时需同时声明，服务端以此应答握手
+const ChatSubprotocol = "fastgpt-chat"
+
+const (
+	// wsPingInterval 与 SSE 默认的 ping 间隔一致
+	wsPingInterval = 10 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	// wsMaxMessageBytes 客户端单条指令的最大长度
+	wsMaxMessageBytes = 1 << 20
+)
+
+// HandleChatWebSocket 聊天的 WebSocket 接口，与流式接口共用生成、续传与停止逻辑
+// 服务端推送 SSEMessage 格式的 JSON 文本帧，客户端发送 WSCommand；同一连接同时只进行一次生成
+func HandleChatWebSocket(c flamego.Context, authInfo auth.Info) {
+	server := websocket.Server{
+		// 凭证不依赖 Cookie，不校验 Origin
+		Handshake: func(config *websocket.Config, _ *http.Request) error {
+			var protocols []string
+			for _, p := range config.Protocol {
+				if p == ChatSubprotocol {
+					protocols = []string{ChatSubprotocol}
+				}
+			}
+			config.Protocol = protocols
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = wsMaxMessageBytes
+			newChatSocket(ws, authInfo).serve()
+		},
+	}
+	server.ServeHTTP(c.ResponseWriter(), c.Request().Request)
+}
+
+// chatSocket 一个聊天 WebSocket 连接
+type chatSocket struct {
+	ws       *websocket.Conn
+	authInfo auth.Info
+	ctx      context.Context
+	cancel   context.CancelFunc
+	out      chan *dto.SSEMessage
+
+	mu      sync.Mutex
+	chatId  string        // 当前生成的会话
+	running chan struct{} // 当前生成推送结束时关闭，空闲时为 nil
+}
+
+func newChatSocket(ws *websocket.Conn, authInfo auth.Info) *chatSocket {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	return &chatSocket{
+		ws:       ws,
+		authInfo: authInfo,
+		ctx:      ctx,
+		cancel:   cancel,
+		out:      make(chan *dto.SSEMessage),
+	}
+}
+
+// serve 读取客户端指令直到连接关闭，进行中的生成与 SSE 断线时一样在宽限期内可续传
+func (s *chatSocket) serve() {
+	defer s.cancel()
+	threadx.GoSafe(s.writeLoop)
+
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(s.ws, &data); err != nil {
+			return
+		}
+		var cmd dto.WSCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			s.sendError(dto.SSEErrorData{Error: "指令格式错误", Code: 400001})
+			continue
+		}
+		s.handle(cmd)
+	}
+}
+
+// writeLoop 串行写出消息并定时发送 ping 帧，写入失败时关闭连接
+func (s *chatSocket) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	defer s.ws.Close()
+
+	for {
+		var (
+			frame []byte
+			typ   byte = websocket.TextFrame
+		)
+		select {
+		case <-s.ctx.Done():
+			return
+		case m := <-s.out:
+			frame, _ = json.Marshal(m)
+		case <-ticker.C:
+			typ = websocket.PingFrame
+		}
+		_ = s.ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		s.ws.PayloadType = typ
+		if _, err := s.ws.Write(frame); err != nil {
+			logx.SystemLogger.Errorf("websocket write: %v", err)
+			s.cancel()
+			return
+		}
+	}
+}
+
+func (s *chatSocket) send(m *dto.SSEMessage) {
+	sendSSEMessage(s.ctx, s.out, m)
+}
+
+func (s *chatSocket) sendError(data dto.SSEErrorData) {
+	sendSSEError(s.ctx, s.out, data)
+}
+
+func (s *chatSocket) handle(cmd dto.WSCommand) {
+	switch cmd.Type {
+	case dto.WSCommandChat, dto.WSCommandRegenerate:
+		if cmd.Request == nil {
+			s.sendError(dto.SSEErrorData{Error: "缺少 request", Code: 400001})
+			return
+		}
+		regenerate := cmd.Type == dto.WSCommandRegenerate
+		if regenerate {
+			s.stopAndWait(cmd.Request.ChatId)
+		}
+		req := *cmd.Request
+		s.run(req.ChatId, func() {
+			streamChat(s.ctx, s.authInfo, req, regenerate, s.out)
+		})
+	case dto.WSCommandResume:
+		var chatId string
+		if streamId, _, ok := service.ParseEventId(cmd.LastEventId); ok {
+			if stream := service.Streams.Get(streamId); stream != nil && stream.UserId == s.authInfo.Uid {
+				chatId = stream.ChatId
+			}
+		}
+		s.run(chatId, func() {
+			resumeStream(s.ctx, s.authInfo, cmd.LastEventId, s.out)
+		})
+	case dto.WSCommandStop:
+		chatId := cmd.ChatId
+		if chatId == "" {
+			s.mu.Lock()
+			chatId = s.chatId
+			s.mu.Unlock()
+		}
+		if chatId == "" || !service.Streams.Stop(s.authInfo.Uid, chatId) {
+			s.sendError(dto.SSEErrorData{Error: "没有进行中的回答", Code: 404001})
+		}
+	case dto.WSCommandTyping:
+	case dto.WSCommandPing:
+		s.send(&dto.SSEMessage{Event: "pong", Data: "{}"})
+	default:
+		s.sendError(dto.SSEErrorData{Error: "不支持的指令：" + cmd.Type, Code: 400001})
+	}
+}
+
+// run 在后台推送一次生成，连接上已有生成时拒绝
+func (s *chatSocket) run(chatId string, fn func()) {
+	s.mu.Lock()
+	if s.running != nil {
+		s.mu.Unlock()
+		s.sendError(dto.SSEErrorData{Error: "上一条回答尚未结束", Code: 409001})
+		return
+	}
+	running := make(chan struct{})
+	s.running, s.chatId = running, chatId
+	s.mu.Unlock()
+
+	threadx.GoSafe(func() {
+		defer func() {
+			s.mu.Lock()
+			s.running, s.chatId = nil, ""
+			s.mu.Unlock()
+			close(running)
+		}()
+		fn()
+	})
+}
+
+// stopAndWait 停止该会话进行中的生成，当前连接正在推送该会话时等待推送结束
+func (s *chatSocket) stopAndWait(chatId string) {
+	service.Streams.Stop(s.authInfo.Uid, chatId)
+	s.mu.Lock()
+	running := s.running
+	if s.chatId != chatId {
+		running = nil
+	}
+	s.mu.Unlock()
+	if running == nil {
+		return
+	}
+	select {
+	case <-running:
+	case <-s.ctx.Done():
+	}
+}
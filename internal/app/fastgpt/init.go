@@ -1,10 +1,13 @@
 package fastgpt
 
 import (
+	"HelpStudent/config"
 	"HelpStudent/core/kernel"
+	"HelpStudent/core/logx"
 	"HelpStudent/internal/app"
 	"HelpStudent/internal/app/fastgpt/dao"
 	"HelpStudent/internal/app/fastgpt/router"
+	"HelpStudent/internal/app/fastgpt/service"
 	"context"
 	"sync"
 )
@@ -13,6 +16,8 @@ type (
 	Fastgpt struct {
 		Name string
 		app.UnimplementedModule
+
+		health *service.HealthMonitor
 	}
 )
 
@@ -21,7 +26,14 @@ func (p *Fastgpt) Info() string {
 }
 
 func (p *Fastgpt) PreInit(engine *kernel.Engine) error {
-	err := dao.InitPG(engine.MainPG.GetOrm())
+	keyring, err := service.NewKeyring(config.GetConfig().FastGPT)
+	if err != nil {
+		return err
+	}
+	if !keyring.Enabled() {
+		logx.SystemLogger.Warn("FastGPT.MasterKeys 未配置，API Key 将以明文存储")
+	}
+	err = dao.InitPG(engine.MainPG.GetOrm(), keyring)
 	if err != nil {
 		return err
 	}
@@ -43,11 +55,23 @@ func (p *Fastgpt) Load(engine *kernel.Engine) error {
 }
 
 func (p *Fastgpt) Start(engine *kernel.Engine) error {
+	// 后台导入任务不会跨进程恢复，重启前未完成的资料标记为失败
+	n, err := dao.Document.FailInterrupted(engine.Ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		logx.SystemLogger.Warnf("%d 份课程资料因服务重启导入中断", n)
+	}
+	p.health = service.StartHealthMonitor(engine.Ctx)
 	return nil
 }
 
 func (p *Fastgpt) Stop(wg *sync.WaitGroup, ctx context.Context) error {
 	defer wg.Done()
+	if err := p.health.Stop(ctx); err != nil {
+		return err
+	}
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
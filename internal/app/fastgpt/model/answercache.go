@@ -0,0 +1,15 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+)
+
+// FastgptAnswerCacheSetting 应用的回答缓存设置，未配置时不缓存
+type FastgptAnswerCacheSetting struct {
+	model.Base
+	AppId      string `gorm:"type:char(26);not null;uniqueIndex;comment:本系统应用ID"`
+	Enabled    bool   `gorm:"not null;default:false;comment:是否启用"`
+	TTLSeconds int    `gorm:"not null;default:0;comment:缓存时间（秒），0 使用默认值"`
+	Variables  string `gorm:"type:varchar(500);comment:参与缓存键的变量名，逗号分隔"`
+	UpdatedBy  string `gorm:"type:varchar(50);comment:最后修改者"`
+}
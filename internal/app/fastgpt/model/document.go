@@ -0,0 +1,39 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+)
+
+// 资料处理状态
+const (
+	DocumentProcessing = "processing"
+	DocumentSuccess    = "success"
+	DocumentError      = "error"
+)
+
+// 资料处理阶段
+const (
+	DocumentStageExtract = "extract" // 解析文本
+	DocumentStageUpload  = "upload"  // 上传文件到 FastGPT
+	DocumentStagePush    = "push"    // 推送分段数据
+	DocumentStageDone    = "done"
+)
+
+// FastgptDocument 上传到 FastGPT 知识库的课程资料
+type FastgptDocument struct {
+	model.Base
+	AppId        string `gorm:"type:char(26);not null;index;comment:本系统应用ID"`
+	SubjectName  string `gorm:"type:varchar(100);not null;index;comment:所属科目"`
+	DatasetId    string `gorm:"type:varchar(100);not null;comment:FastGPT知识库ID"`
+	CollectionId string `gorm:"type:varchar(100);comment:FastGPT集合ID"`
+	FileName     string `gorm:"type:varchar(255);not null;comment:原始文件名"`
+	FileKey      string `gorm:"type:varchar(500);not null;comment:原始文件存储路径"`
+	FileSize     int64  `gorm:"not null;default:0;comment:文件大小（字节）"`
+	FileType     string `gorm:"type:varchar(10);not null;comment:文件类型 pdf/docx/pptx/md/txt"`
+	Status       string `gorm:"type:varchar(20);not null;default:'processing';index;comment:processing/success/error"`
+	Stage        string `gorm:"type:varchar(20);comment:当前处理阶段"`
+	TotalChunks  int    `gorm:"not null;default:0;comment:分段总数"`
+	PushedChunks int    `gorm:"not null;default:0;comment:已推送分段数"`
+	ErrorMsg     string `gorm:"type:text;comment:失败原因"`
+	UploadedBy   string `gorm:"type:varchar(50);comment:上传者"`
+}
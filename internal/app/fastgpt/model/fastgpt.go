@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"HelpStudent/internal/model"
 
 	"gorm.io/gorm"
@@ -8,6 +10,22 @@ import (
 
 // 数据库模型
 
+// 对话后端
+const (
+	ProviderFastGPT = "fastgpt" // FastGPT 应用
+	ProviderOpenAI  = "openai"  // OpenAI 兼容接口，如 vLLM、Ollama
+)
+
+// 应用健康检查状态
+const (
+	AppHealthUnknown      = ""              // 尚未检查
+	AppHealthOK           = "ok"            // API Key 与分享链接均有效
+	AppHealthKeyInvalid   = "key_invalid"   // API Key 无效或已被撤销
+	AppHealthAppMissing   = "app_missing"   // FastGPT 中应用已不存在
+	AppHealthShareInvalid = "share_invalid" // 分享链接无效
+	AppHealthUnreachable  = "unreachable"   // FastGPT 无法访问
+)
+
 // FastgptApp FastGPT 应用配置
 type FastgptApp struct {
 	model.Base
@@ -15,7 +33,20 @@ type FastgptApp struct {
 	AppName     string         `gorm:"uniqueIndex:idx_app_name;not null;type:varchar(200);comment:应用名称"`
 	AppId       string         `gorm:"type:varchar(200);comment:FastGPT 应用ID"`
 	ShareId     string         `gorm:"type:varchar(100);comment:FastGPT分享链接ID"`
-	APIKey      string         `gorm:"not null;type:varchar(200);comment:FastGPT API密钥"`
+	APIKey      string         `gorm:"not null;type:text;comment:FastGPT API密钥（信封加密）"`
 	Description string         `gorm:"type:text;comment:应用描述"`
 	CreatedBy   string         `gorm:"type:varchar(50);comment:创建者"`
+	Provider    string         `gorm:"type:varchar(20);not null;default:'fastgpt';comment:对话后端 fastgpt / openai"`
+	BaseURL     string         `gorm:"type:varchar(255);comment:OpenAI 兼容接口地址，如 http://vllm:8000/v1"`
+	Model       string         `gorm:"type:varchar(100);comment:OpenAI 兼容接口使用的模型"`
+	Status      int            `gorm:"not null;default:1;comment:状态 1 启用 0 停用"`
+
+	HealthStatus    string     `gorm:"type:varchar(20);comment:健康检查状态"`
+	HealthError     string     `gorm:"type:text;comment:最近一次检查失败的原因"`
+	HealthCheckedAt *time.Time `gorm:"comment:最近一次检查时间"`
+}
+
+// IsFastGPT 是否由 FastGPT 提供对话，只有 FastGPT 应用支持历史、知识库等转发接口
+func (a *FastgptApp) IsFastGPT() bool {
+	return a.Provider == "" || a.Provider == ProviderFastGPT
 }
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+)
+
+// 回答评价
+const (
+	FeedbackUp   = "up"
+	FeedbackDown = "down"
+)
+
+// FastgptAnswerFeedback 用户对一条回答的评价，每个用户对同一回答只保留最后一次评价
+type FastgptAnswerFeedback struct {
+	model.Base
+	AppId        string `gorm:"type:char(26);not null;index;comment:本系统应用ID"`
+	SubjectName  string `gorm:"type:varchar(100);not null;index;comment:科目名称"`
+	ChatId       string `gorm:"type:varchar(100);not null;index;comment:FastGPT 会话ID"`
+	DataId       string `gorm:"type:varchar(100);not null;uniqueIndex:idx_answer_feedback;comment:FastGPT 回答ID"`
+	UserId       string `gorm:"type:char(26);not null;uniqueIndex:idx_answer_feedback"`
+	StaffId      string `gorm:"type:varchar(19);index"`
+	Rating       string `gorm:"type:varchar(10);not null;index;comment:up 赞 / down 踩"`
+	Reason       string `gorm:"type:text;comment:评价理由"`
+	Forwarded    bool   `gorm:"not null;default:false;comment:是否已同步到 FastGPT"`
+	ForwardError string `gorm:"type:text;comment:同步失败原因"`
+}
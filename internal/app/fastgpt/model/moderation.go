@@ -0,0 +1,41 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+)
+
+// 敏感词处理方式
+const (
+	ModerationBlock = "block" // 提问中出现时拒绝，回答中出现时打码
+	ModerationMask  = "mask"  // 打码
+	// ModerationReview 不拦截也不打码，回答中出现时提交人工审核
+	ModerationReview = "review"
+)
+
+// 命中方向
+const (
+	ModerationInput  = "input"
+	ModerationOutput = "output"
+)
+
+// FastgptSensitiveWord 敏感词，SubjectName 为空表示全局生效
+type FastgptSensitiveWord struct {
+	model.Base
+	Word        string `gorm:"type:varchar(100);not null;uniqueIndex:idx_sensitive_word;comment:敏感词"`
+	SubjectName string `gorm:"type:varchar(100);not null;default:'';uniqueIndex:idx_sensitive_word;comment:生效科目，空为全局"`
+	Action      string `gorm:"type:varchar(10);not null;default:'mask';comment:block 拒绝 / mask 打码 / review 审核"`
+	CreatedBy   string `gorm:"type:varchar(50);comment:创建者"`
+}
+
+// FastgptModerationHit 敏感词命中记录
+type FastgptModerationHit struct {
+	model.Base
+	AppId     string `gorm:"type:char(26);not null;index;comment:本系统应用ID"`
+	ChatId    string `gorm:"type:varchar(100);index"`
+	UserId    string `gorm:"type:char(26);index"`
+	StaffId   string `gorm:"type:varchar(19);index"`
+	Direction string `gorm:"type:varchar(10);not null;comment:input 提问 / output 回答"`
+	Action    string `gorm:"type:varchar(10);not null;comment:实际处理方式"`
+	Keywords  string `gorm:"type:text;comment:命中的敏感词，逗号分隔"`
+	Excerpt   string `gorm:"type:text;comment:处理前的内容片段"`
+}
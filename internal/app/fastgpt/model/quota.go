@@ -0,0 +1,16 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+)
+
+// FastgptAppQuota 应用级对话配额，字段为空时使用配置文件中的默认值，0 表示不限制
+type FastgptAppQuota struct {
+	model.Base
+	AppId             string `gorm:"type:char(26);not null;uniqueIndex;comment:本系统应用ID"`
+	UserRPM           *int   `gorm:"comment:每个用户每分钟请求数"`
+	UserDailyMessages *int   `gorm:"comment:每个用户每天消息数"`
+	AppConcurrency    *int   `gorm:"comment:应用同时进行的对话数"`
+	AppStreams        *int   `gorm:"comment:应用同时进行的流式对话数"`
+	UpdatedBy         string `gorm:"type:varchar(50);comment:最后修改者"`
+}
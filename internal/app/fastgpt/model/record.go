@@ -0,0 +1,45 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+	"time"
+)
+
+// 聊天记录状态
+const (
+	ChatStatusPending = "pending"
+	ChatStatusSuccess = "success"
+	ChatStatusError   = "error"
+	ChatStatusAborted = "aborted"
+	ChatStatusStopped = "stopped" // 用户主动停止，保留已生成的部分回答
+)
+
+// FastgptChatSession 本地镜像的聊天会话
+type FastgptChatSession struct {
+	model.Base
+	AppId         string    `gorm:"type:char(26);not null;uniqueIndex:idx_chat_session;comment:本系统应用ID"`
+	ChatId        string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_chat_session;comment:FastGPT 会话ID"`
+	UserId        string    `gorm:"type:char(26);not null;index"`
+	StaffId       string    `gorm:"type:varchar(19);index"`
+	Title         string    `gorm:"type:varchar(200);comment:首个问题"`
+	MessageCount  int       `gorm:"not null;default:0"`
+	LastMessageAt time.Time `gorm:"index"`
+}
+
+// FastgptChatMessage 本地镜像的一问一答
+type FastgptChatMessage struct {
+	model.Base
+	AppId        string     `gorm:"type:char(26);not null;index:idx_chat_message"`
+	ChatId       string     `gorm:"type:varchar(100);index:idx_chat_message"`
+	UserId       string     `gorm:"type:char(26);not null;index"`
+	StaffId      string     `gorm:"type:varchar(19);index"`
+	Question     string     `gorm:"type:text"`
+	Answer       string     `gorm:"type:text"`
+	Stream       bool       `gorm:"not null;default:false"`
+	Status       string     `gorm:"type:varchar(20);not null;index;comment:pending/success/error/aborted"`
+	ErrorMsg     string     `gorm:"type:text"`
+	RequestAt    time.Time  `gorm:"index"`
+	FinishedAt   *time.Time `gorm:""`
+	LatencyMs    int64      `gorm:"comment:总耗时（毫秒）"`
+	FirstChunkMs int64      `gorm:"comment:首个数据块耗时（毫秒），仅流式"`
+}
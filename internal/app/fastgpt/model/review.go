@@ -0,0 +1,66 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+	"time"
+)
+
+// 审核来源
+const (
+	ReviewSourceStudent       = "student"        // 学生点踩
+	ReviewSourceKeyword       = "keyword"        // 回答命中审核关键词
+	ReviewSourceLowSimilarity = "low_similarity" // 知识库引用相似度过低或没有引用
+)
+
+// 审核状态
+const (
+	ReviewPending    = "pending"     // 待处理
+	ReviewInProgress = "in_progress" // 已指派
+	ReviewResolved   = "resolved"    // 已给出修正回答
+	ReviewDismissed  = "dismissed"   // 回答无误，忽略
+)
+
+// FastgptReview 待人工审核的回答
+// 同一回答多次被标记时合并为一条，FlagKey 为 msg:<本地问答ID> 或 data:<chatId>/<dataId>
+type FastgptReview struct {
+	model.Base
+	AppId           string     `gorm:"type:char(26);not null;uniqueIndex:idx_review_flag;index:idx_review_queue;comment:本系统应用ID"`
+	FlagKey         string     `gorm:"type:varchar(220);not null;uniqueIndex:idx_review_flag"`
+	SubjectName     string     `gorm:"type:varchar(100);index"`
+	ChatId          string     `gorm:"type:varchar(100)"`
+	DataId          string     `gorm:"type:varchar(100);comment:FastGPT 回答记录ID"`
+	MessageId       string     `gorm:"type:char(26);comment:本地问答记录ID"`
+	UserId          string     `gorm:"type:char(26);comment:提问者"`
+	StaffId         string     `gorm:"type:varchar(19)"`
+	Question        string     `gorm:"type:text"`
+	Answer          string     `gorm:"type:text"`
+	Sources         string     `gorm:"type:varchar(100);comment:标记来源，逗号分隔"`
+	Reason          string     `gorm:"type:text;comment:标记原因，多次标记按行追加"`
+	QuoteScore      *float64   `gorm:"comment:最高引用相似度"`
+	FlagCount       int        `gorm:"not null;default:1"`
+	Status          string     `gorm:"type:varchar(20);not null;index:idx_review_queue"`
+	Assignee        string     `gorm:"type:varchar(19);index;comment:处理人学工号"`
+	CorrectedAnswer string     `gorm:"type:text"`
+	CollectionId    string     `gorm:"type:varchar(100);comment:修正回答写入的 FastGPT 集合"`
+	PushedAt        *time.Time `gorm:""`
+	PushError       string     `gorm:"type:text"`
+	ResolvedBy      string     `gorm:"type:varchar(19)"`
+	ResolvedAt      *time.Time `gorm:""`
+}
+
+// FastgptReviewComment 审核评论
+type FastgptReviewComment struct {
+	model.Base
+	ReviewId string `gorm:"type:char(26);not null;index"`
+	UserId   string `gorm:"type:char(26)"`
+	StaffId  string `gorm:"type:varchar(19)"`
+	Content  string `gorm:"type:text"`
+}
+
+// FastgptReviewer 应用的审核教师
+type FastgptReviewer struct {
+	model.Base
+	AppId     string `gorm:"type:char(26);not null;uniqueIndex:idx_reviewer"`
+	StaffId   string `gorm:"type:varchar(19);not null;uniqueIndex:idx_reviewer;index"`
+	CreatedBy string `gorm:"type:varchar(50)"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+)
+
+// 应用配置变更类型
+const (
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionDelete   = "delete"
+	RevisionRollback = "rollback"
+)
+
+// FastgptAppRevision 应用配置的一个历史版本，记录变更后的完整配置与字段变化
+// 删除操作记录删除前的配置，回滚到该版本即恢复应用
+type FastgptAppRevision struct {
+	model.Base
+	AppId    string `gorm:"type:char(26);not null;uniqueIndex:idx_app_revision;comment:本系统应用ID"`
+	Version  int    `gorm:"not null;uniqueIndex:idx_app_revision;comment:版本号，从 1 开始"`
+	Action   string `gorm:"type:varchar(20);not null;comment:create / update / delete / rollback"`
+	Snapshot string `gorm:"type:text;comment:变更后的配置 JSON，不含 API Key"`
+	APIKey   string `gorm:"type:text;comment:变更后的 API Key（信封加密）"`
+	Changes  string `gorm:"type:text;comment:字段变化 JSON，密钥已脱敏"`
+	Operator string `gorm:"type:varchar(50);comment:操作人"`
+	Comment  string `gorm:"type:varchar(200);comment:备注"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"HelpStudent/internal/model"
+)
+
+// 应用状态
+const (
+	AppStatusDisabled = 0 // 停用，学生无法对话
+	AppStatusEnabled  = 1 // 启用
+)
+
+// 开放时间类型
+const (
+	ScheduleOpen     = "open"     // 每周固定的开放时段，配置后仅在开放时段内可对话
+	ScheduleBlackout = "blackout" // 暂停时段，如考试期间，期间不可对话
+)
+
+// FastgptAppSchedule 应用的开放时段或暂停时段
+type FastgptAppSchedule struct {
+	model.Base
+	AppId     string     `gorm:"type:char(26);not null;index;comment:本系统应用ID"`
+	Type      string     `gorm:"type:varchar(20);not null;comment:open 每周开放时段 / blackout 暂停时段"`
+	Weekdays  string     `gorm:"type:varchar(20);comment:开放的星期，逗号分隔，1-7 表示周一至周日"`
+	StartTime string     `gorm:"type:char(5);comment:开放时段开始时间 HH:MM"`
+	EndTime   string     `gorm:"type:char(5);comment:开放时段结束时间 HH:MM，早于开始时间表示跨午夜"`
+	StartAt   *time.Time `gorm:"comment:暂停开始时间"`
+	EndAt     *time.Time `gorm:"comment:暂停结束时间"`
+	Message   string     `gorm:"type:varchar(500);comment:不可用时向学生展示的提示"`
+	CreatedBy string     `gorm:"type:varchar(50);comment:创建者"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+	"time"
+)
+
+// FastgptUsageEvent 一次对话调用的用量记录，用于统计分析
+// Day/Hour 按服务器本地时区在写入时计算，便于按天、按小时聚合
+type FastgptUsageEvent struct {
+	model.Base
+	AppId       string    `gorm:"type:char(26);not null;index;comment:本系统应用ID"`
+	SubjectName string    `gorm:"type:varchar(100);not null;index:idx_usage_subject_day;comment:科目名称"`
+	UserId      string    `gorm:"type:char(26);not null;index"`
+	StaffId     string    `gorm:"type:varchar(19)"`
+	Stream      bool      `gorm:"not null;default:false"`
+	Status      string    `gorm:"type:varchar(20);not null;comment:success/error/aborted/stopped，或转发前被拒绝的 rate_limited/blocked/unavailable"`
+	Day         string    `gorm:"type:char(10);not null;index:idx_usage_subject_day;comment:日期 2006-01-02"`
+	Hour        int       `gorm:"not null;comment:小时 0-23"`
+	RequestAt   time.Time `gorm:"not null"`
+	LatencyMs   int64     `gorm:"comment:总耗时（毫秒）"`
+	InputChars  int       `gorm:"comment:提问字数"`
+	OutputChars int       `gorm:"comment:回答字数"`
+}
+
+// 转发前被拒绝的调用的用量状态，排队期间离开记为 aborted
+const (
+	UsageStatusRateLimited = "rate_limited" // 超出对话配额或排队失败
+	UsageStatusBlocked     = "blocked"      // 提问命中拦截敏感词
+	UsageStatusUnavailable = "unavailable"  // 应用停用或不在开放时间
+)
+
+// UsageRejectedStatuses 转发前被拒绝的状态，活跃度、时段、用户排行与耗时统计中排除，只在失败率中单独计数
+var UsageRejectedStatuses = []string{UsageStatusRateLimited, UsageStatusBlocked, UsageStatusUnavailable}
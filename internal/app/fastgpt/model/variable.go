@@ -0,0 +1,27 @@
+package model
+
+import (
+	"HelpStudent/internal/model"
+)
+
+// 注入变量的取值来源
+const (
+	VariableSourceStaffId   = "staffId"   // 学号
+	VariableSourceName      = "name"      // 姓名
+	VariableSourceSubjects  = "subjects"  // 已选科目，以“、”连接
+	VariableSourceGrade     = "grade"     // 年级，由学号前两位推算
+	VariableSourceStaffType = "staffType" // 身份类型
+	VariableSourceUnitCode  = "unitCode"  // 所属单位代码
+	// VariableSourceAttrPrefix 以 attr. 开头时从第三方登录信息中取对应字段，可用字段见 service.VariableSources
+	VariableSourceAttrPrefix = "attr."
+)
+
+// FastgptVariableSetting 应用的对话变量设置，未配置时客户端变量原样转发
+// 配置后只转发 AllowedClient 中的客户端变量，Injected 中的变量由服务端填充并覆盖客户端同名变量
+type FastgptVariableSetting struct {
+	model.Base
+	AppId         string `gorm:"type:char(26);not null;uniqueIndex;comment:本系统应用ID"`
+	Injected      string `gorm:"type:text;comment:服务端注入的变量，JSON 数组 [{name,source}]"`
+	AllowedClient string `gorm:"type:varchar(500);comment:允许客户端传入的变量名，逗号分隔"`
+	UpdatedBy     string `gorm:"type:varchar(50);comment:最后修改者"`
+}
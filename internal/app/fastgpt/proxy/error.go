@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/tidwall/gjson"
+)
+
+// MapUpstreamError 将 FastGPT 的错误响应转换为本系统的错误码、提示与错误详情
+// FastGPT 的错误体一般为 {"code":xxx,"statusText":"...","message":"..."}
+func MapUpstreamError(status int, body []byte) (code int, msg string, detail string) {
+	detail = upstreamMessage(body)
+	if detail == "" {
+		detail = http.StatusText(status)
+	}
+
+	switch {
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return 400015, "FastGPT 请求参数错误", detail
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		// 应用密钥失效属于本系统配置问题，不向用户暴露为鉴权失败
+		return 500002, "FastGPT 应用密钥无效", detail
+	case status == http.StatusNotFound:
+		return 404002, "FastGPT 资源不存在", detail
+	case status == http.StatusTooManyRequests:
+		return 429002, "FastGPT 请求过于频繁，请稍后再试", detail
+	default:
+		return 500001, "FastGPT API 调用失败", detail
+	}
+}
+
+func upstreamMessage(body []byte) string {
+	if !gjson.ValidBytes(body) {
+		return ""
+	}
+	for _, path := range []string{"message", "error.message", "statusText", "error"} {
+		if v := gjson.GetBytes(body, path); v.Type == gjson.String && v.String() != "" {
+			return v.String()
+		}
+	}
+	return ""
+}
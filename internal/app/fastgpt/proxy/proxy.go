@@ -0,0 +1,229 @@
+// Package proxy 声明式的 FastGPT 接口转发
+//
+// 每个转发接口以 Route 声明一次：本系统路由、FastGPT 路径、如何取得应用、
+// 需要去掉的字段与访问级别，由 Register 统一完成参数校验、鉴权、转发与错误转换。
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"HelpStudent/config"
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/middleware/response"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+
+	"github.com/flamego/binding"
+	"github.com/flamego/flamego"
+)
+
+// Route 一个转发接口的声明
+type Route struct {
+	Method   string // 本系统与 FastGPT 使用的 HTTP 方法
+	Path     string // 本系统路由
+	Upstream string // FastGPT 路径，为空时与 Path 相同
+
+	// Body 请求体 DTO，非空时按 JSON 绑定并校验，转为对象后转发；为空时转发查询参数
+	Body interface{}
+	// Query 需要转发给 FastGPT 的查询参数
+	Query []string
+	// Required 必须提供的查询参数
+	Required []string
+	// Strip 转发前从请求体中去掉的字段，如 fastgptAppId
+	Strip []string
+
+	App    AppResolver         // 取得本次请求对应的应用
+	Access service.AccessLevel // 访问级别
+
+	Before []RequestHook  // 转发前调用，可修改请求体与查询参数
+	After  []ResponseHook // FastGPT 成功响应后调用，可修改响应体
+}
+
+// Request 转发中的请求
+type Request struct {
+	Context flamego.Context
+	Auth    auth.Info
+	App     *model.FastgptApp
+	Body    map[string]interface{}
+	Query   map[string]string
+}
+
+// Response FastGPT 的响应
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// RequestHook 请求钩子，返回错误时中止转发
+type RequestHook func(req *Request) error
+
+// ResponseHook 响应钩子，返回错误时中止响应
+type ResponseHook func(req *Request, resp *Response) error
+
+// Error 带错误码的错误，钩子或 AppResolver 返回时按该错误码响应
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Register 在当前路由组中注册转发接口，middlewares 在参数绑定前执行
+func Register(e *flamego.Flame, routes []Route, middlewares ...flamego.Handler) {
+	for _, route := range routes {
+		handlers := append([]flamego.Handler{}, middlewares...)
+		if route.Body != nil {
+			handlers = append(handlers, binding.JSON(route.Body))
+		}
+		handlers = append(handlers, route.handler())
+		e.Route(route.Method, route.Path, handlers)
+	}
+}
+
+func (route Route) upstream() string {
+	if route.Upstream != "" {
+		return route.Upstream
+	}
+	return route.Path
+}
+
+func (route Route) handler() flamego.Handler {
+	var bodyType reflect.Type
+	if route.Body != nil {
+		bodyType = reflect.TypeOf(route.Body)
+	}
+	errsType := reflect.TypeOf(binding.Errors{})
+
+	return func(c flamego.Context, r flamego.Render, authInfo auth.Info) {
+		ctx := c.Request().Context()
+		req := &Request{Context: c, Auth: authInfo, Query: map[string]string{}}
+
+		if bodyType != nil {
+			if errs, _ := c.Value(errsType).Interface().(binding.Errors); len(errs) > 0 {
+				response.InValidParam(r, errs)
+				return
+			}
+			body, err := toMap(c.Value(bodyType).Interface())
+			if err != nil {
+				response.ServiceErr(r, err)
+				return
+			}
+			req.Body = body
+		}
+		for _, name := range route.Required {
+			if c.Query(name) == "" {
+				response.HTTPFail(r, 400001, "缺少必要参数 "+name)
+				return
+			}
+		}
+		for _, name := range route.Query {
+			if v := c.Query(name); v != "" {
+				req.Query[name] = v
+			}
+		}
+
+		app, err := route.App(req)
+		if err != nil {
+			writeError(c, r, err, 400013, "应用不存在")
+			return
+		}
+		if err := service.CheckAppAccess(authInfo, app, route.Access); err != nil {
+			if errors.Is(err, service.ErrAppForbidden) {
+				response.HTTPFail(r, 403001, "无权访问该应用")
+				return
+			}
+			logx.SystemLogger.CtxError(ctx, err)
+			response.ServiceErr(r, err)
+			return
+		}
+		if !app.IsFastGPT() {
+			response.HTTPFail(r, 400017, "该应用未接入 FastGPT，不支持此接口")
+			return
+		}
+		req.App = app
+
+		for _, field := range route.Strip {
+			delete(req.Body, field)
+		}
+		for _, hook := range route.Before {
+			if err := hook(req); err != nil {
+				writeError(c, r, err, 0, "")
+				return
+			}
+		}
+
+		client := service.NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey)
+		var resp Response
+		if bodyType != nil {
+			resp.Body, resp.StatusCode, err = client.ForwardRequest(ctx, route.Method, route.upstream(), req.Body)
+		} else {
+			resp.Body, resp.StatusCode, err = client.ForwardRequestWithQuery(ctx, route.Method, route.upstream(), req.Query)
+		}
+		if err != nil {
+			WriteRequestError(c, r, err)
+			return
+		}
+		if resp.StatusCode != http.StatusOK {
+			logx.SystemLogger.CtxError(ctx, "FastGPT API error: status=%d, body=%s", resp.StatusCode, string(resp.Body))
+			code, msg, detail := MapUpstreamError(resp.StatusCode, resp.Body)
+			response.HTTPFail(r, code, msg, detail)
+			return
+		}
+
+		for _, hook := range route.After {
+			if err := hook(req, &resp); err != nil {
+				writeError(c, r, err, 0, "")
+				return
+			}
+		}
+
+		c.ResponseWriter().Header().Set("Content-Type", "application/json")
+		c.ResponseWriter().WriteHeader(http.StatusOK)
+		c.ResponseWriter().Write(resp.Body)
+	}
+}
+
+// WriteRequestError 请求 FastGPT 失败时写入响应，熔断打开时提示服务暂不可用
+func WriteRequestError(c flamego.Context, r flamego.Render, err error) {
+	logx.SystemLogger.CtxError(c.Request().Context(), err)
+	if errors.Is(err, service.ErrFastGPTUnavailable) {
+		response.HTTPFail(r, 503001, service.ErrFastGPTUnavailable.Error())
+		return
+	}
+	response.ServiceErr(r, err)
+}
+
+// writeError 按错误类型写入响应，code 为 0 时未知错误按内部异常处理
+func writeError(c flamego.Context, r flamego.Render, err error, code int, msg string) {
+	var e *Error
+	if errors.As(err, &e) {
+		response.HTTPFail(r, e.Code, e.Message)
+		return
+	}
+	logx.SystemLogger.CtxError(c.Request().Context(), err)
+	if code != 0 {
+		response.HTTPFail(r, code, msg)
+		return
+	}
+	response.ServiceErr(r, err)
+}
+
+// toMap 将绑定后的 DTO 转为可修改的对象
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request body: %w", err)
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("unmarshal request body: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,215 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/fake"
+	"HelpStudent/internal/app/fastgpt/fastgpttest"
+	"HelpStudent/internal/app/fastgpt/model"
+	"HelpStudent/internal/app/fastgpt/service"
+
+	"github.com/flamego/flamego"
+)
+
+type historiesBody struct {
+	AppId        string `json:"appId" validate:"required"`
+	FastgptAppId string `json:"fastgptAppId"`
+	Offset       int    `json:"offset"`
+	PageSize     int    `json:"pageSize"`
+}
+
+type result struct {
+	Code    int      `json:"code"`
+	Message string   `json:"message"`
+	Error   []string `json:"error"`
+}
+
+// newProxy 注册转发接口，请求以已选高等数学的学生身份发出
+func newProxy(t *testing.T, routes ...Route) (*fastgpttest.Env, *model.FastgptApp, *flamego.Flame) {
+	t.Helper()
+	env := fastgpttest.Setup(t)
+	app := env.CreateApp(t, &model.FastgptApp{AppName: "高等数学", AppId: "app1"})
+	env.Enroll(t, "uid1", "S001", "高等数学")
+
+	f := flamego.New()
+	f.Use(flamego.Renderer())
+	f.Use(func(c flamego.Context) { c.Map(auth.Info{Uid: "uid1", StaffId: "S001"}) })
+	Register(f, routes)
+	return env, app, f
+}
+
+func serve(t *testing.T, f *flamego.Flame, method, target string, body interface{}) (int, result) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, req)
+
+	var res result
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, res
+}
+
+func TestRegister_ForwardBody(t *testing.T) {
+	var order []string
+	env, app, f := newProxy(t, Route{
+		Method: http.MethodPost,
+		Path:   "/core/chat/getHistories",
+		Body:   historiesBody{},
+		Strip:  []string{"fastgptAppId"},
+		App:    AppFromBody("fastgptAppId"),
+		Before: []RequestHook{
+			func(req *Request) error {
+				order = append(order, "first")
+				req.Body["pageSize"] = 5
+				return nil
+			},
+			func(req *Request) error {
+				order = append(order, "second")
+				if req.Body["pageSize"] != 5 {
+					t.Errorf("second hook sees pageSize %v", req.Body["pageSize"])
+				}
+				if _, ok := req.Body["fastgptAppId"]; ok {
+					t.Error("fastgptAppId should be stripped before hooks run")
+				}
+				return nil
+			},
+		},
+		After: []ResponseHook{
+			func(req *Request, resp *Response) error {
+				order = append(order, "after")
+				return nil
+			},
+		},
+	})
+
+	status, res := serve(t, f, http.MethodPost, "/core/chat/getHistories",
+		map[string]interface{}{"appId": "app1", "fastgptAppId": app.ID, "pageSize": 20})
+	if status != http.StatusOK || res.Code != http.StatusOK {
+		t.Fatalf("status = %d, res = %+v", status, res)
+	}
+	if len(order) != 3 || order[0] != "first" || order[1] != "second" || order[2] != "after" {
+		t.Errorf("hook order = %v", order)
+	}
+
+	fwd, ok := env.Server.LastRequest("/core/chat/getHistories")
+	if !ok {
+		t.Fatal("request not forwarded")
+	}
+	var body map[string]interface{}
+	if err := fwd.JSON(&body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["fastgptAppId"]; ok {
+		t.Errorf("fastgptAppId not stripped: %v", body)
+	}
+	if body["appId"] != "app1" || body["pageSize"] != float64(5) {
+		t.Errorf("forwarded body = %v", body)
+	}
+	if fwd.Header.Get("Authorization") != "Bearer "+fastgpttest.APIKey {
+		t.Errorf("Authorization = %q", fwd.Header.Get("Authorization"))
+	}
+}
+
+func TestRegister_Rejected(t *testing.T) {
+	env, app, f := newProxy(t,
+		Route{
+			Method:   http.MethodDelete,
+			Path:     "/core/chat/delHistory",
+			Query:    []string{"chatId"},
+			Required: []string{"chatId"},
+			App:      AppFromQuery("fastgptAppId"),
+		},
+		Route{
+			Method: http.MethodPost,
+			Path:   "/core/dataset/list",
+			Body:   historiesBody{},
+			App:    AppFromBody("fastgptAppId"),
+			Access: service.AccessManage,
+		},
+		Route{
+			Method: http.MethodPost,
+			Path:   "/core/chat/getHistories",
+			Body:   historiesBody{},
+			App:    AppFromBody("fastgptAppId"),
+			Before: []RequestHook{
+				func(req *Request) error { return &Error{Code: 403002, Message: "hook rejected"} },
+				func(req *Request) error { return errors.New("second hook must not run") },
+			},
+		},
+	)
+
+	cases := []struct {
+		name   string
+		method string
+		target string
+		body   interface{}
+		code   int
+	}{
+		{"missing required query", http.MethodDelete, "/core/chat/delHistory?fastgptAppId=" + app.ID, nil, 400001},
+		{"missing app", http.MethodDelete, "/core/chat/delHistory?chatId=c1", nil, 400001},
+		{"unknown app", http.MethodDelete, "/core/chat/delHistory?chatId=c1&fastgptAppId=missing", nil, 400013},
+		{"invalid body", http.MethodPost, "/core/chat/getHistories", map[string]interface{}{"fastgptAppId": app.ID}, 400000},
+		{"manage access", http.MethodPost, "/core/dataset/list", map[string]interface{}{"appId": "app1", "fastgptAppId": app.ID}, 403001},
+		{"hook error", http.MethodPost, "/core/chat/getHistories", map[string]interface{}{"appId": "app1", "fastgptAppId": app.ID}, 403002},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			status, res := serve(t, f, c.method, c.target, c.body)
+			if res.Code != c.code || status != c.code/1000 {
+				t.Errorf("status = %d, res = %+v; want code %d", status, res, c.code)
+			}
+		})
+	}
+	if reqs := env.Server.Requests(""); len(reqs) != 0 {
+		t.Errorf("rejected requests were forwarded: %d", len(reqs))
+	}
+}
+
+func TestRegister_UpstreamError(t *testing.T) {
+	env, app, f := newProxy(t, Route{
+		Method: http.MethodPost,
+		Path:   "/core/chat/getHistories",
+		Body:   historiesBody{},
+		Strip:  []string{"fastgptAppId"},
+		App:    AppFromBody("fastgptAppId"),
+	})
+
+	cases := []struct {
+		name    string
+		failure fake.Failure
+		code    int
+		detail  string
+	}{
+		{"bad request", fake.Failure{Status: http.StatusBadRequest, Body: `{"code":400,"statusText":"","message":"appId is required"}`}, 400015, "appId is required"},
+		{"unauthorized", fake.Failure{Status: http.StatusUnauthorized}, 500002, "Unauthorized"},
+		{"not found", fake.Failure{Status: http.StatusNotFound, Body: `{"error":{"message":"chat not exist"}}`}, 404002, "chat not exist"},
+		{"too many requests", fake.Failure{Status: http.StatusTooManyRequests, Body: `{"statusText":"rate limited"}`}, 429002, "rate limited"},
+		{"server error", fake.Failure{Status: http.StatusInternalServerError, Body: `not json`}, 500001, "Internal Server Error"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			env.Server.Fail("/core/chat/getHistories", c.failure)
+			status, res := serve(t, f, http.MethodPost, "/core/chat/getHistories",
+				map[string]interface{}{"appId": "app1", "fastgptAppId": app.ID})
+			if res.Code != c.code || status != c.code/1000 {
+				t.Errorf("status = %d, code = %d; want %d", status, res.Code, c.code)
+			}
+			if len(res.Error) != 1 || res.Error[0] != c.detail {
+				t.Errorf("error = %v, want %q", res.Error, c.detail)
+			}
+		})
+	}
+}
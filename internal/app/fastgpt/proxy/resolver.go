@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"fmt"
+
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+// AppResolver 从请求中取得应用
+type AppResolver func(req *Request) (*model.FastgptApp, error)
+
+// AppFromBody 以请求体字段作为本系统应用 ID
+func AppFromBody(field string) AppResolver {
+	return func(req *Request) (*model.FastgptApp, error) {
+		id, _ := req.Body[field].(string)
+		if id == "" {
+			return nil, &Error{Code: 400001, Message: "缺少必要参数 " + field}
+		}
+		return dao.FastgptApp.GetAppByID(id)
+	}
+}
+
+// AppFromQuery 以查询参数作为本系统应用 ID
+func AppFromQuery(param string) AppResolver {
+	return func(req *Request) (*model.FastgptApp, error) {
+		id := req.Context.Query(param)
+		if id == "" {
+			return nil, &Error{Code: 400001, Message: "缺少必要参数 " + param}
+		}
+		return dao.FastgptApp.GetAppByID(id)
+	}
+}
+
+// AppFromShareId 以查询参数作为 FastGPT 分享链接 ID
+func AppFromShareId(param string) AppResolver {
+	return func(req *Request) (*model.FastgptApp, error) {
+		shareId := req.Context.Query(param)
+		if shareId == "" {
+			return nil, &Error{Code: 400001, Message: "缺少必要参数 " + param}
+		}
+		app, err := dao.FastgptApp.GetAppByShareID(shareId)
+		if err != nil {
+			return nil, fmt.Errorf("get app by share id %s: %w", shareId, err)
+		}
+		return app, nil
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"HelpStudent/core/middleware/web"
 	"HelpStudent/internal/app/fastgpt/dto"
 	handler "HelpStudent/internal/app/fastgpt/handler/v1"
+	"HelpStudent/internal/app/fastgpt/proxy"
 
 	"HelpStudent/core/middleware/sse"
 
@@ -19,40 +20,25 @@ func AppFastgptInit(e *flamego.Flame) {
 		e.Get("/system/img/{imageId}", handler.HandleGetImage)
 
 		// 外链删除聊天历史
-		e.Delete("/core/chat/delHistory", web.Authorization, handler.HandleOutLinkDelHistory)
+		proxy.Register(e, handler.OutLinkProxyRoutes, web.Authorization)
 	})
+	// Chat 接口 - WebSocket，浏览器可通过子协议或查询参数传递凭证
+	e.Get("/fastgpt/v1/chat/ws", web.WebSocketAuthorization, handler.HandleChatWebSocket)
+
 	e.Group("/fastgpt", func() {
 		// Chat 接口 - 非流式
 		e.Post("/v1/chat/completions", binding.JSON(dto.ChatCompletionRequest{}), handler.HandleChatCompletion)
 		// Chat 接口 - 流式输出（使用 flamego/sse）
 		e.Post("/v1/chat/completions/stream", binding.JSON(dto.ChatCompletionRequest{}), sse.Bind(dto.SSEMessage{}), handler.HandleStreamChatCompletion)
+		// 停止流式生成
+		e.Post("/v1/chat/completions/stop", binding.JSON(dto.StopChatRequest{}), handler.HandleStopChatCompletion)
 
-		// Chat History 接口
-		e.Group("/core/chat", func() {
-			e.Post("/history/getHistories", binding.JSON(dto.GetHistoriesRequest{}), handler.HandleGetHistories)
-			e.Post("/history/updateHistory", binding.JSON(dto.UpdateHistoryRequest{}), handler.HandleUpdateHistory)
-			e.Post("/getPaginationRecords", binding.JSON(dto.GetPaginationRecordsRequest{}), handler.HandleGetPaginationRecords)
-			e.Post("/quote/getCollectionQuote", binding.JSON(dto.GetCollectionQuoteRequest{}), handler.HandleGetCollectionQuote)
-			e.Get("/outLink/init", handler.HandleOutLinkInit)
-		})
+		// 导出会话
+		e.Post("/chat/export", binding.JSON(dto.ExportChatRequest{}), handler.HandleExportChat)
 
-		// Dataset 接口
-		e.Group("/core/dataset", func() {
-			e.Post("/create", binding.JSON(dto.DatasetCreateRequest{}), handler.HandleCreateDataset)
-			e.Post("/list", binding.JSON(dto.DatasetListRequest{}), handler.HandleListDatasets)
-			e.Get("/detail", handler.HandleGetDatasetDetail)
-			e.Delete("/delete", handler.HandleDeleteDataset)
-
-			// Collection 接口
-			e.Group("/collection", func() {
-				e.Post("/create/text", binding.JSON(dto.CreateCollectionTextRequest{}), handler.HandleCreateCollectionText)
-				e.Post("/create/link", binding.JSON(dto.CreateCollectionLinkRequest{}), handler.HandleCreateCollectionLink)
-			})
-
-			// Data 接口
-			e.Post("/data/pushData", binding.JSON(dto.PushDataRequest{}), handler.HandlePushData)
-			e.Post("/searchTest", binding.JSON(dto.SearchTestRequest{}), handler.HandleSearchTest)
-		})
+		// FastGPT 转发接口
+		proxy.Register(e, handler.ChatProxyRoutes)
+		proxy.Register(e, handler.DatasetProxyRoutes)
 
 		// App 管理接口
 		e.Group("/apps", func() {
@@ -60,6 +46,70 @@ func AppFastgptInit(e *flamego.Flame) {
 			e.Post("/list", binding.JSON(dto.GetAppListRequest{}), handler.HandleGetAppList)
 			e.Post("/update", binding.JSON(dto.UpdateAppRequest{}), handler.HandleUpdateApp)
 			e.Post("/delete", binding.JSON(dto.DeleteAppRequest{}), handler.HandleDeleteApp)
+			e.Post("/quota/get", binding.JSON(dto.GetAppQuotaRequest{}), handler.HandleGetAppQuota)
+			e.Post("/quota/update", binding.JSON(dto.UpdateAppQuotaRequest{}), handler.HandleUpdateAppQuota)
+			e.Post("/cache/get", binding.JSON(dto.AnswerCacheRequest{}), handler.HandleGetAnswerCache)
+			e.Post("/cache/update", binding.JSON(dto.UpdateAnswerCacheRequest{}), handler.HandleUpdateAnswerCache)
+			e.Post("/cache/invalidate", binding.JSON(dto.AnswerCacheRequest{}), handler.HandleInvalidateAnswerCache)
+			e.Post("/variables/get", binding.JSON(dto.AppVariablesRequest{}), handler.HandleGetAppVariables)
+			e.Post("/variables/update", binding.JSON(dto.UpdateAppVariablesRequest{}), handler.HandleUpdateAppVariables)
+			e.Post("/schedules/list", binding.JSON(dto.ListAppSchedulesRequest{}), handler.HandleListAppSchedules)
+			e.Post("/schedules/create", binding.JSON(dto.CreateAppScheduleRequest{}), handler.HandleCreateAppSchedule)
+			e.Post("/schedules/delete", binding.JSON(dto.DeleteAppScheduleRequest{}), handler.HandleDeleteAppSchedule)
+			e.Post("/revisions/list", binding.JSON(dto.ListAppRevisionsRequest{}), handler.HandleListAppRevisions)
+			e.Post("/revisions/rollback", binding.JSON(dto.RollbackAppRequest{}), handler.HandleRollbackApp)
+		})
+
+		// 内容安全接口（管理员）
+		e.Group("/moderation", func() {
+			e.Post("/words/list", binding.JSON(dto.ListSensitiveWordsRequest{}), handler.HandleListSensitiveWords)
+			e.Post("/words/create", binding.JSON(dto.CreateSensitiveWordsRequest{}), handler.HandleCreateSensitiveWords)
+			e.Post("/words/delete", binding.JSON(dto.DeleteSensitiveWordsRequest{}), handler.HandleDeleteSensitiveWords)
+			e.Post("/hits", binding.JSON(dto.ListModerationHitsRequest{}), handler.HandleListModerationHits)
+		})
+
+		// 回答评价接口
+		e.Group("/feedback", func() {
+			e.Post("/create", binding.JSON(dto.CreateFeedbackRequest{}), handler.HandleCreateFeedback)
+			e.Post("/list", binding.JSON(dto.ListFeedbackRequest{}), handler.HandleListFeedback)
+		})
+
+		// 回答审核队列（审核教师与管理员）
+		e.Group("/reviews", func() {
+			e.Post("/list", binding.JSON(dto.ListReviewsRequest{}), handler.HandleListReviews)
+			e.Post("/get", binding.JSON(dto.ReviewIdRequest{}), handler.HandleGetReview)
+			e.Post("/assign", binding.JSON(dto.AssignReviewRequest{}), handler.HandleAssignReview)
+			e.Post("/comment", binding.JSON(dto.CommentReviewRequest{}), handler.HandleCommentReview)
+			e.Post("/resolve", binding.JSON(dto.ResolveReviewRequest{}), handler.HandleResolveReview)
+			e.Post("/dismiss", binding.JSON(dto.DismissReviewRequest{}), handler.HandleDismissReview)
+			e.Post("/push", binding.JSON(dto.PushReviewRequest{}), handler.HandlePushReview)
+			e.Post("/reviewers/list", binding.JSON(dto.ReviewerRequest{}), handler.HandleListReviewers)
+			e.Post("/reviewers/add", binding.JSON(dto.ReviewerRequest{}), handler.HandleAddReviewer)
+			e.Post("/reviewers/remove", binding.JSON(dto.ReviewerRequest{}), handler.HandleRemoveReviewer)
+		})
+
+		// 课程资料上传接口（管理员）
+		e.Group("/documents", func() {
+			e.Post("/upload", handler.HandleUploadDocument)
+			e.Post("/list", binding.JSON(dto.ListDocumentsRequest{}), handler.HandleListDocuments)
+			e.Post("/get", binding.JSON(dto.DocumentIdRequest{}), handler.HandleGetDocument)
+			e.Post("/delete", binding.JSON(dto.DocumentIdRequest{}), handler.HandleDeleteDocument)
+		})
+
+		// 用量统计接口（管理员）
+		e.Group("/analytics", func() {
+			e.Post("/daily-active", binding.JSON(dto.AnalyticsRequest{}), handler.HandleAnalyticsDailyActive)
+			e.Post("/hourly", binding.JSON(dto.AnalyticsRequest{}), handler.HandleAnalyticsHourly)
+			e.Post("/error-rate", binding.JSON(dto.AnalyticsRequest{}), handler.HandleAnalyticsErrorRate)
+			e.Post("/top-users", binding.JSON(dto.AnalyticsRequest{}), handler.HandleAnalyticsTopUsers)
+			e.Post("/export", binding.JSON(dto.AnalyticsRequest{}), handler.HandleAnalyticsExport)
+			e.Post("/queue", handler.HandleAnalyticsQueue)
+		})
+
+		// 本地聊天记录查询接口（管理员）
+		e.Group("/records", func() {
+			e.Post("/sessions", binding.JSON(dto.ListChatRecordsRequest{}), handler.HandleListChatSessions)
+			e.Post("/messages", binding.JSON(dto.ListChatRecordsRequest{}), handler.HandleListChatMessages)
 		})
 	}, web.Authorization)
 }
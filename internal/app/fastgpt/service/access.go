@@ -0,0 +1,65 @@
+package service
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/model"
+	managerDAO "HelpStudent/internal/app/managers/dao"
+	subjectDAO "HelpStudent/internal/app/subject/dao"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrAppForbidden 当前用户无权访问该应用
+var ErrAppForbidden = errors.New("无权访问该应用")
+
+// AccessLevel 应用访问级别
+type AccessLevel int
+
+const (
+	// AccessChat 对话、历史、记录、引用等接口：已选该科目的学生或管理员
+	AccessChat AccessLevel = iota
+	// AccessManage 数据集、集合、数据等知识库接口：仅管理员
+	AccessManage
+)
+
+// CheckAppAccess 检查用户是否可以访问指定应用
+// 应用通过 AppName 与 user_subjects 中的 SubjectName 关联
+func CheckAppAccess(info auth.Info, app *model.FastgptApp, level AccessLevel) error {
+	if managerDAO.Managers.IsManager(info.StaffId) {
+		return nil
+	}
+	if level == AccessManage {
+		return ErrAppForbidden
+	}
+
+	enrolled, err := IsEnrolled(info, app.AppName)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return ErrAppForbidden
+	}
+	return nil
+}
+
+// IsEnrolled 检查用户是否选了该科目
+func IsEnrolled(info auth.Info, subjectName string) (bool, error) {
+	subjects, err := subjectDAO.Subject.GetUserSubjectsByUserId(info.Uid)
+	if err != nil {
+		return false, fmt.Errorf("get user subjects: %w", err)
+	}
+	if slices.Contains(subjects, subjectName) {
+		return true, nil
+	}
+
+	// Excel 导入的关联只有学号没有 user_id，按学号兜底
+	if info.StaffId == "" {
+		return false, nil
+	}
+	subjects, err = subjectDAO.Subject.GetUserSubjects(info.StaffId)
+	if err != nil {
+		return false, fmt.Errorf("get user subjects: %w", err)
+	}
+	return slices.Contains(subjects, subjectName), nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/fastgpttest"
+	"HelpStudent/internal/app/fastgpt/model"
+	subjectModel "HelpStudent/internal/app/subject/model"
+)
+
+func TestCheckAppAccess(t *testing.T) {
+	env := fastgpttest.Setup(t)
+	env.AddManager(t, "T001")
+	env.Enroll(t, "uid-enrolled", "S001", "高等数学")
+	// Excel 导入的关联只有学号
+	env.Enroll(t, "", "S002", "高等数学")
+	env.Enroll(t, "uid-other", "S003", "线性代数")
+	app := &model.FastgptApp{AppName: "高等数学"}
+
+	cases := []struct {
+		name  string
+		info  auth.Info
+		level AccessLevel
+		want  error
+	}{
+		{"manager chat", auth.Info{Uid: "uid-manager", StaffId: "T001"}, AccessChat, nil},
+		{"manager manage", auth.Info{Uid: "uid-manager", StaffId: "T001"}, AccessManage, nil},
+		{"enrolled chat", auth.Info{Uid: "uid-enrolled", StaffId: "S001"}, AccessChat, nil},
+		{"enrolled manage", auth.Info{Uid: "uid-enrolled", StaffId: "S001"}, AccessManage, ErrAppForbidden},
+		{"staffId fallback", auth.Info{Uid: "uid-imported", StaffId: "S002"}, AccessChat, nil},
+		{"other subject", auth.Info{Uid: "uid-other", StaffId: "S003"}, AccessChat, ErrAppForbidden},
+		{"no staffId", auth.Info{Uid: "uid-unknown"}, AccessChat, ErrAppForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := CheckAppAccess(c.info, app, c.level); !errors.Is(err, c.want) {
+				t.Errorf("CheckAppAccess = %v, want %v", err, c.want)
+			}
+		})
+	}
+}
+
+func TestIsEnrolled(t *testing.T) {
+	env := fastgpttest.Setup(t)
+	env.Enroll(t, "uid-enrolled", "S001", "高等数学")
+	env.Enroll(t, "", "S002", "高等数学")
+
+	cases := []struct {
+		name string
+		info auth.Info
+		want bool
+	}{
+		{"by uid", auth.Info{Uid: "uid-enrolled"}, true},
+		{"by staffId", auth.Info{Uid: "uid-imported", StaffId: "S002"}, true},
+		{"other staffId", auth.Info{Uid: "uid-imported", StaffId: "S003"}, false},
+		{"no staffId", auth.Info{Uid: "uid-imported"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := IsEnrolled(c.info, "高等数学")
+			if err != nil || got != c.want {
+				t.Errorf("IsEnrolled = %v, %v; want %v", got, err, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckAppAccess_DBError(t *testing.T) {
+	env := fastgpttest.Setup(t)
+	if err := env.DB.Migrator().DropTable(&subjectModel.UserSubject{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 查询选课失败时返回错误，不能放行，也不能当作未选课
+	err := CheckAppAccess(auth.Info{Uid: "uid", StaffId: "S001"}, &model.FastgptApp{AppName: "高等数学"}, AccessChat)
+	if err == nil || errors.Is(err, ErrAppForbidden) {
+		t.Errorf("CheckAppAccess = %v, want db error", err)
+	}
+	if _, err := IsEnrolled(auth.Info{Uid: "uid"}, "高等数学"); err == nil {
+		t.Error("IsEnrolled should return the db error")
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	"HelpStudent/internal/app/fastgpt/dao"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// UsageReport 用量统计报表
+type UsageReport struct {
+	Filter      dao.UsageFilter
+	DailyActive []dao.DailyActiveRow
+	Hourly      []dao.HourlyRow
+	ErrorRates  []dao.ErrorRateRow
+	TopUsers    []dao.TopUserRow
+}
+
+// BuildUsageReport 汇总统计区间内的全部指标
+func BuildUsageReport(ctx context.Context, f dao.UsageFilter, topN int) (*UsageReport, error) {
+	report := &UsageReport{Filter: f}
+	var err error
+	if report.DailyActive, err = dao.Usage.DailyActive(ctx, f); err != nil {
+		return nil, fmt.Errorf("daily active: %w", err)
+	}
+	hourly, err := dao.Usage.Hourly(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("hourly: %w", err)
+	}
+	report.Hourly = FillHours(hourly)
+	if report.ErrorRates, err = dao.Usage.ErrorRates(ctx, f); err != nil {
+		return nil, fmt.Errorf("error rates: %w", err)
+	}
+	if report.TopUsers, err = dao.Usage.TopUsers(ctx, f, topN); err != nil {
+		return nil, fmt.Errorf("top users: %w", err)
+	}
+	return report, nil
+}
+
+// FillHours 补齐没有消息的小时，返回 0-23 点共 24 项
+func FillHours(rows []dao.HourlyRow) []dao.HourlyRow {
+	hours := make([]dao.HourlyRow, 24)
+	for i := range hours {
+		hours[i].Hour = i
+	}
+	for _, row := range rows {
+		if row.Hour >= 0 && row.Hour < 24 {
+			hours[row.Hour] = row
+		}
+	}
+	return hours
+}
+
+// ErrorRate 失败率，保留四位小数
+func ErrorRate(errors, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(errors)/float64(total)*10000) / 10000
+}
+
+// WriteXLSX 导出为 Excel，每项指标一个工作表
+func (r *UsageReport) WriteXLSX(w io.Writer) error {
+	f := excelize.NewFile()
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var sheets []usageSheet
+
+	daily := usageSheet{name: "每日活跃", headers: []string{"日期", "科目", "活跃学生数", "消息数"}}
+	for _, row := range r.DailyActive {
+		daily.rows = append(daily.rows, []interface{}{row.Day, row.SubjectName, row.Students, row.Messages})
+	}
+	sheets = append(sheets, daily)
+
+	hourly := usageSheet{name: "分时段消息", headers: []string{"小时", "消息数", "失败数"}}
+	for _, row := range r.Hourly {
+		hourly.rows = append(hourly.rows, []interface{}{fmt.Sprintf("%02d:00", row.Hour), row.Messages, row.Errors})
+	}
+	sheets = append(sheets, hourly)
+
+	errorRates := usageSheet{name: "失败率", headers: []string{"科目", "应用ID", "消息数", "失败数", "失败率", "平均耗时(ms)", "被拒绝数"}}
+	for _, row := range r.ErrorRates {
+		errorRates.rows = append(errorRates.rows, []interface{}{
+			row.SubjectName, row.AppId, row.Messages, row.Errors, ErrorRate(row.Errors, row.Messages), math.Round(row.AvgLatencyMs), row.Rejected,
+		})
+	}
+	sheets = append(sheets, errorRates)
+
+	topUsers := usageSheet{name: "活跃用户", headers: []string{"用户ID", "学号", "消息数", "活跃天数", "提问字数", "回答字数"}}
+	for _, row := range r.TopUsers {
+		topUsers.rows = append(topUsers.rows, []interface{}{
+			row.UserId, row.StaffId, row.Messages, row.ActiveDays, row.InputChars, row.OutputChars,
+		})
+	}
+	sheets = append(sheets, topUsers)
+
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	for i, sheet := range sheets {
+		if err := sheet.write(f, headerStyle); err != nil {
+			return err
+		}
+		if i == 0 {
+			index, _ := f.GetSheetIndex(sheet.name)
+			f.SetActiveSheet(index)
+		}
+	}
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+type usageSheet struct {
+	name    string
+	headers []string
+	rows    [][]interface{}
+}
+
+func (s usageSheet) write(f *excelize.File, headerStyle int) error {
+	if _, err := f.NewSheet(s.name); err != nil {
+		return err
+	}
+	header := make([]interface{}, len(s.headers))
+	for i, h := range s.headers {
+		header[i] = h
+	}
+	if err := f.SetSheetRow(s.name, "A1", &header); err != nil {
+		return err
+	}
+	for i, row := range s.rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(s.name, cell, &row); err != nil {
+			return err
+		}
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(s.headers))
+	_ = f.SetCellStyle(s.name, "A1", lastCol+"1", headerStyle)
+	return f.SetColWidth(s.name, "A", lastCol, 16)
+}
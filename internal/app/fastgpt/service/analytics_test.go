@@ -0,0 +1,53 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+
+	"HelpStudent/internal/app/fastgpt/dao"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestFillHours(t *testing.T) {
+	hours := FillHours([]dao.HourlyRow{{Hour: 9, Messages: 3, Errors: 1}, {Hour: 23, Messages: 1}})
+	if len(hours) != 24 {
+		t.Fatalf("expected 24 hours, got %d", len(hours))
+	}
+	if hours[9].Messages != 3 || hours[9].Errors != 1 || hours[23].Messages != 1 {
+		t.Errorf("hours not filled from rows: %+v", hours)
+	}
+	if hours[0].Hour != 0 || hours[0].Messages != 0 {
+		t.Errorf("empty hour should be zero: %+v", hours[0])
+	}
+}
+
+func TestUsageReport_WriteXLSX(t *testing.T) {
+	report := &UsageReport{
+		DailyActive: []dao.DailyActiveRow{{Day: "2026-06-01", SubjectName: "高等数学", Students: 12, Messages: 40}},
+		Hourly:      FillHours(nil),
+		ErrorRates:  []dao.ErrorRateRow{{SubjectName: "高等数学", Messages: 40, Errors: 2, AvgLatencyMs: 1234.4}},
+	}
+	var buf bytes.Buffer
+	if err := report.WriteXLSX(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if sheets := f.GetSheetList(); len(sheets) != 4 || sheets[0] != "每日活跃" {
+		t.Errorf("unexpected sheets %v", sheets)
+	}
+	if v, _ := f.GetCellValue("每日活跃", "C2"); v != "12" {
+		t.Errorf("expected students 12, got %q", v)
+	}
+	if v, _ := f.GetCellValue("失败率", "E2"); v != "0.05" {
+		t.Errorf("expected error rate 0.05, got %q", v)
+	}
+	if rows, _ := f.GetRows("分时段消息"); len(rows) != 25 {
+		t.Errorf("expected 24 hourly rows plus header, got %d", len(rows))
+	}
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"HelpStudent/config"
+	"HelpStudent/core/cache"
+	"HelpStudent/core/store/rds"
+	"HelpStudent/core/syncx"
+	"HelpStudent/core/threadx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultAnswerCacheTTL = 3600
+	// replayChunkRunes 缓存回答以流式重放时每个数据块的字数
+	replayChunkRunes = 16
+)
+
+// errAnswerNotCached 合并请求中负责生成的请求未得到完整回答
+var errAnswerNotCached = errors.New("answer not cached")
+
+// answerFlight 合并同一时刻相同提问的请求，只向后端请求一次
+// 非流式与流式请求的结果类型不同，分别使用 chatFlightKey 与 streamFlightKey
+var answerFlight = syncx.NewSingleFlight()
+
+func chatFlightKey(key string) string   { return "chat:" + key }
+func streamFlightKey(key string) string { return "stream:" + key }
+
+// AnswerCache 一次可缓存提问对应的缓存项
+// 只缓存与上下文无关的提问：新会话中的第一个问题，且不请求 detail
+type AnswerCache struct {
+	key string
+	ttl int
+}
+
+// AnswerCacheFor 判断本次提问能否使用回答缓存，应用未开启或提问依赖上下文时返回 nil
+// 需在记录本次提问之前调用，否则新会话会被当作已有会话
+func AnswerCacheFor(ctx context.Context, app *model.FastgptApp, req *dto.ChatCompletionRequest) (*AnswerCache, error) {
+	setting, err := dao.AnswerCache.GetByAppId(ctx, app.ID)
+	if err != nil {
+		return nil, err
+	}
+	if setting == nil || !setting.Enabled || req.Detail {
+		return nil, nil
+	}
+
+	question, ok := singleQuestion(req.Messages)
+	if !ok {
+		return nil, nil
+	}
+	if req.ChatId != "" {
+		session, err := dao.ChatRecord.GetSession(ctx, app.ID, req.ChatId)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil {
+			return nil, nil
+		}
+	}
+
+	names, err := answerCacheVariables(ctx, app, setting)
+	if err != nil {
+		return nil, err
+	}
+	return &AnswerCache{
+		key: answerCacheKey(app.ID, question, names, req.Variables),
+		ttl: AnswerCacheTTL(setting),
+	}, nil
+}
+
+// answerCacheVariables 参与缓存键的变量名：设置中的变量加上服务端注入的变量
+// 注入的变量因学生而异（如年级、已选科目），回答可能随之不同，不能在学生之间共用
+func answerCacheVariables(ctx context.Context, app *model.FastgptApp, setting *model.FastgptAnswerCacheSetting) ([]string, error) {
+	names := CacheVariables(setting)
+	varSetting, err := dao.Variables.GetByAppId(ctx, app.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get variable setting: %w", err)
+	}
+	if varSetting == nil {
+		return names, nil
+	}
+	for _, v := range InjectedVariables(varSetting) {
+		if !slices.Contains(names, v.Name) {
+			names = append(names, v.Name)
+		}
+	}
+	return names, nil
+}
+
+// AnswerCacheTTL 缓存保留秒数
+func AnswerCacheTTL(setting *model.FastgptAnswerCacheSetting) int {
+	if setting != nil && setting.TTLSeconds > 0 {
+		return setting.TTLSeconds
+	}
+	if ttl := config.GetConfig().FastGPT.AnswerCacheTTL; ttl > 0 {
+		return ttl
+	}
+	return defaultAnswerCacheTTL
+}
+
+// CacheVariables 设置中参与缓存键的变量名，服务端注入的变量总是参与，无需在此设置
+func CacheVariables(setting *model.FastgptAnswerCacheSetting) []string {
+	var names []string
+	for _, name := range strings.Split(setting.Variables, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// InvalidateAnswerCache 清除应用的全部缓存回答，返回清除的条数
+func InvalidateAnswerCache(appId string) (int, error) {
+	keys, err := cache.Keys(rds.Key("fastgpt", "answer", appId) + rds.KeySeparator + "*")
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	return cache.Del(keys...)
+}
+
+// Wrap 返回带缓存的对话后端，c 为 nil 时原样返回
+func (c *AnswerCache) Wrap(p Provider) Provider {
+	if c == nil {
+		return p
+	}
+	return &cachingProvider{inner: p, cache: c}
+}
+
+func (c *AnswerCache) get() (string, bool) {
+	return cache.GetString(c.key)
+}
+
+func (c *AnswerCache) store(answer string) {
+	if answer != "" {
+		_ = cache.Setex(c.key, answer, c.ttl)
+	}
+}
+
+// singleQuestion 消息中除系统提示外只有一条用户提问时返回该提问
+func singleQuestion(messages []dto.Message) (string, bool) {
+	question := ""
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+		case "user":
+			if question != "" {
+				return "", false
+			}
+			question = MessageText(m.Content)
+		default:
+			return "", false
+		}
+	}
+	return question, question != ""
+}
+
+// normalizeQuestion 忽略大小写、多余空白与结尾标点
+func normalizeQuestion(q string) string {
+	q = strings.ToLower(strings.Join(strings.Fields(q), " "))
+	return strings.TrimRight(q, " ?？。.!！~～")
+}
+
+func answerCacheKey(appId, question string, names []string, variables map[string]interface{}) string {
+	vars := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		vars[name] = variables[name]
+	}
+	// map 序列化时按键排序，结果稳定
+	encoded, _ := json.Marshal(vars)
+
+	h := sha256.New()
+	h.Write([]byte(normalizeQuestion(question)))
+	h.Write([]byte{0})
+	h.Write(encoded)
+	return rds.Key("fastgpt", "answer", appId, hex.EncodeToString(h.Sum(nil)))
+}
+
+// cachingProvider 先查缓存，未命中时合并相同提问的并发请求，成功的完整回答写入缓存
+type cachingProvider struct {
+	inner Provider
+	cache *AnswerCache
+}
+
+type chatResult struct {
+	body   []byte
+	status int
+}
+
+func (p *cachingProvider) Chat(ctx context.Context, req *dto.ChatCompletionRequest) ([]byte, int, error) {
+	if answer, ok := p.cache.get(); ok {
+		return completionBody(req.ChatId, answer), http.StatusOK, nil
+	}
+
+	val, fresh, err := answerFlight.DoEx(chatFlightKey(p.cache.key), func() (any, error) {
+		// 共享的请求不随发起者断开而取消
+		body, status, err := p.inner.Chat(context.WithoutCancel(ctx), req)
+		if err != nil {
+			return nil, err
+		}
+		if status == http.StatusOK {
+			p.cache.store(gjson.GetBytes(body, "choices.0.message.content").String())
+		}
+		return &chatResult{body: body, status: status}, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	res := val.(*chatResult)
+	if fresh || res.status != http.StatusOK {
+		return res.body, res.status, nil
+	}
+	// 其他请求得到的响应带有发起者的会话信息，只取回答内容
+	return completionBody(req.ChatId, gjson.GetBytes(res.body, "choices.0.message.content").String()), http.StatusOK, nil
+}
+
+// ChatStream 未命中缓存时，第一个请求正常流式生成，同时到达的相同提问等待其完成后重放回答
+func (p *cachingProvider) ChatStream(ctx context.Context, req *dto.ChatCompletionRequest) (*ChatStreamReader, error) {
+	if answer, ok := p.cache.get(); ok {
+		return replayReader(req.ChatId, answer)
+	}
+
+	leader := make(chan struct{})
+	finished := make(chan string, 1)
+	shared := make(chan error, 1)
+	var sharedAnswer string
+	threadx.GoSafe(func() {
+		val, _, err := answerFlight.DoEx(streamFlightKey(p.cache.key), func() (any, error) {
+			close(leader)
+			answer, ok := <-finished
+			if !ok {
+				return nil, errAnswerNotCached
+			}
+			return answer, nil
+		})
+		if err == nil {
+			sharedAnswer = val.(string)
+		}
+		shared <- err
+	})
+
+	select {
+	case <-leader:
+		reader, err := p.inner.ChatStream(ctx, req)
+		if err != nil {
+			close(finished)
+			return nil, err
+		}
+		reader.onClose = func(answer string, complete bool) {
+			if complete && answer != "" {
+				p.cache.store(answer)
+				finished <- answer
+			}
+			close(finished)
+		}
+		return reader, nil
+	case err := <-shared:
+		if err != nil {
+			// 生成失败或被中断，自行请求
+			return p.inner.ChatStream(ctx, req)
+		}
+		return replayReader(req.ChatId, sharedAnswer)
+	case <-ctx.Done():
+		// 未接手生成，之后成为发起者时立即结束，等待的请求自行请求
+		close(finished)
+		return nil, ctx.Err()
+	}
+}
+
+// completionBody 以 OpenAI 非流式响应格式返回缓存的回答
+func completionBody(chatId, answer string) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":      chatId,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   "",
+		"cached":  true,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": answer},
+			"finish_reason": "stop",
+		}},
+	})
+	return body
+}
+
+// replayReader 将缓存的回答切成数据块，以流式响应的格式重放
+func replayReader(chatId, answer string) (*ChatStreamReader, error) {
+	var sb strings.Builder
+	created := time.Now().Unix()
+	writeChunk := func(delta map[string]string, finishReason interface{}) {
+		chunk, _ := json.Marshal(map[string]interface{}{
+			"id":      chatId,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   "",
+			"cached":  true,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finishReason,
+			}},
+		})
+		sb.WriteString("data: ")
+		sb.Write(chunk)
+		sb.WriteString("\n\n")
+	}
+
+	runes := []rune(answer)
+	for start := 0; start < len(runes); start += replayChunkRunes {
+		end := start + replayChunkRunes
+		if end > len(runes) {
+			end = len(runes)
+		}
+		writeChunk(map[string]string{"role": "assistant", "content": string(runes[start:end])}, nil)
+	}
+	writeChunk(map[string]string{}, "stop")
+	sb.WriteString("data: [DONE]\n\n")
+
+	return newChatStreamReader(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(sb.String())),
+	})
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/fastgpttest"
+	"HelpStudent/internal/app/fastgpt/model"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestAnswerCacheKey_Normalize(t *testing.T) {
+	a := answerCacheKey("app", "  什么是 极限？", nil, nil)
+	b := answerCacheKey("app", "什么是   极限", nil, map[string]interface{}{"ignored": 1})
+	if a != b {
+		t.Error("questions differing only in whitespace and punctuation should share a key")
+	}
+	if a == answerCacheKey("other", "什么是 极限", nil, nil) {
+		t.Error("different apps should not share a key")
+	}
+	c := answerCacheKey("app", "什么是 极限", []string{"lang"}, map[string]interface{}{"lang": "en"})
+	d := answerCacheKey("app", "什么是 极限", []string{"lang"}, map[string]interface{}{"lang": "zh"})
+	if c == d {
+		t.Error("relevant variables should be part of the key")
+	}
+}
+
+func TestAnswerCacheFor_InjectedVariables(t *testing.T) {
+	env := fastgpttest.Setup(t)
+	app := env.CreateApp(t, &model.FastgptApp{AppName: "高等数学", AppId: "app1"})
+	ctx := context.Background()
+	if err := dao.AnswerCache.Save(ctx, &model.FastgptAnswerCacheSetting{AppId: app.ID, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dao.Variables.Save(ctx, &model.FastgptVariableSetting{AppId: app.ID, Injected: `[{"name":"grade","source":"grade"}]`}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 注入的变量未在缓存设置中列出，也参与缓存键
+	keyFor := func(staffId string) string {
+		t.Helper()
+		req := &dto.ChatCompletionRequest{Messages: []dto.Message{{Role: "user", Content: "选课要求"}}}
+		if err := ApplyVariables(ctx, auth.Info{Uid: "uid-" + staffId, StaffId: staffId}, app, req); err != nil {
+			t.Fatal(err)
+		}
+		c, err := AnswerCacheFor(ctx, app, req)
+		if err != nil || c == nil {
+			t.Fatalf("AnswerCacheFor = %v, %v", c, err)
+		}
+		return c.key
+	}
+	if keyFor("22050626") == keyFor("23050626") {
+		t.Error("students of different grades share a cache key")
+	}
+	if keyFor("22050626") != keyFor("22050627") {
+		t.Error("students of the same grade should share a cache key")
+	}
+}
+
+func TestSingleQuestion(t *testing.T) {
+	if q, ok := singleQuestion([]dto.Message{{Role: "system", Content: "s"}, {Role: "user", Content: "q"}}); !ok || q != "q" {
+		t.Errorf("expected single question, got %q %v", q, ok)
+	}
+	if _, ok := singleQuestion([]dto.Message{{Role: "user", Content: "q1"}, {Role: "assistant", Content: "a"}, {Role: "user", Content: "q2"}}); ok {
+		t.Error("multi-turn conversation should not be cacheable")
+	}
+}
+
+// slowProvider 记录调用次数，等待 release 后返回固定回答
+type slowProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (p *slowProvider) Chat(ctx context.Context, req *dto.ChatCompletionRequest) ([]byte, int, error) {
+	p.calls.Add(1)
+	<-p.release
+	return []byte(`{"id":"leader-chat","choices":[{"message":{"role":"assistant","content":"极限的定义"}}]}`), http.StatusOK, nil
+}
+
+func (p *slowProvider) ChatStream(ctx context.Context, req *dto.ChatCompletionRequest) (*ChatStreamReader, error) {
+	p.calls.Add(1)
+	<-p.release
+	body := "data: {\"choices\":[{\"delta\":{\"content\":\"极限\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"的定义\"}}]}\n\ndata: [DONE]\n\n"
+	return newChatStreamReader(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))})
+}
+
+func readAnswer(t *testing.T, r *ChatStreamReader) string {
+	t.Helper()
+	defer r.Close()
+	var sb strings.Builder
+	for {
+		data, err := r.Recv()
+		if err == io.EOF {
+			return sb.String()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb.WriteString(gjson.Get(data, "choices.0.delta.content").String())
+	}
+}
+
+func TestCachingProvider_ChatCollapsesConcurrentRequests(t *testing.T) {
+	inner := &slowProvider{release: make(chan struct{})}
+	c := &AnswerCache{key: "test:answer:chat:" + t.Name(), ttl: 60}
+	p := c.Wrap(inner)
+
+	var wg sync.WaitGroup
+	bodies := make([][]byte, 5)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body, status, err := p.Chat(context.Background(), &dto.ChatCompletionRequest{ChatId: "own-chat"})
+			if err != nil || status != http.StatusOK {
+				t.Errorf("unexpected result %d %v", status, err)
+			}
+			bodies[i] = body
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	if n := inner.calls.Load(); n != 1 {
+		t.Errorf("expected 1 backend call, got %d", n)
+	}
+	for _, body := range bodies {
+		if gjson.GetBytes(body, "choices.0.message.content").String() != "极限的定义" {
+			t.Errorf("unexpected body %s", body)
+		}
+	}
+
+	// 之后的请求直接命中缓存，且使用自己的会话ID
+	body, _, _ := p.Chat(context.Background(), &dto.ChatCompletionRequest{ChatId: "later"})
+	if n := inner.calls.Load(); n != 1 || gjson.GetBytes(body, "id").String() != "later" {
+		t.Errorf("expected cache hit for own chat, calls=%d body=%s", n, body)
+	}
+}
+
+func TestCachingProvider_StreamFollowersReplay(t *testing.T) {
+	inner := &slowProvider{release: make(chan struct{})}
+	c := &AnswerCache{key: "test:answer:stream:" + t.Name(), ttl: 60}
+	p := c.Wrap(inner)
+
+	var wg sync.WaitGroup
+	answers := make([]string, 3)
+	for i := range answers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reader, err := p.ChatStream(context.Background(), &dto.ChatCompletionRequest{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			answers[i] = readAnswer(t, reader)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	if n := inner.calls.Load(); n != 1 {
+		t.Errorf("expected 1 backend call, got %d", n)
+	}
+	for _, answer := range answers {
+		if answer != "极限的定义" {
+			t.Errorf("unexpected answer %q", answer)
+		}
+	}
+	if cached, ok := c.get(); !ok || cached != "极限的定义" {
+		t.Errorf("complete answer should be cached, got %q %v", cached, ok)
+	}
+}
+
+func TestCachingProvider_ChatAndStreamConcurrent(t *testing.T) {
+	inner := &slowProvider{release: make(chan struct{})}
+	c := &AnswerCache{key: "test:answer:mixed:" + t.Name(), ttl: 60}
+	p := c.Wrap(inner)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		body, _, err := p.Chat(context.Background(), &dto.ChatCompletionRequest{})
+		if err != nil || gjson.GetBytes(body, "choices.0.message.content").String() != "极限的定义" {
+			t.Errorf("chat: %s %v", body, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		reader, err := p.ChatStream(context.Background(), &dto.ChatCompletionRequest{})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if answer := readAnswer(t, reader); answer != "极限的定义" {
+			t.Errorf("stream: %q", answer)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+}
+
+func TestCachingProvider_StreamFollowerCancelled(t *testing.T) {
+	inner := &slowProvider{release: make(chan struct{})}
+	c := &AnswerCache{key: "test:answer:cancel:" + t.Name(), ttl: 60}
+	p := c.Wrap(inner)
+
+	go func() {
+		if reader, err := p.ChatStream(context.Background(), &dto.ChatCompletionRequest{}); err == nil {
+			readAnswer(t, reader)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.ChatStream(ctx, &dto.ChatCompletionRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	close(inner.release)
+}
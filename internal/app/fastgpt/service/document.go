@@ -0,0 +1,401 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"HelpStudent/config"
+	"HelpStudent/core/fileServer"
+	"HelpStudent/core/logx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/model"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/tidwall/gjson"
+)
+
+// 支持上传的资料类型
+const (
+	DocumentPDF      = "pdf"
+	DocumentDOCX     = "docx"
+	DocumentPPTX     = "pptx"
+	DocumentMarkdown = "md"
+	DocumentText     = "txt"
+)
+
+const (
+	defaultDocumentStorageKey = "documents"
+	defaultDocumentMaxSizeMB  = 50
+	defaultDocumentChunkSize  = 800
+	// pushDataBatch FastGPT pushData 单次最多 200 条
+	pushDataBatch = 100
+	// maxXMLPartSize 解压单个 XML 部件的大小上限，防止压缩炸弹
+	maxXMLPartSize = 64 << 20
+)
+
+var (
+	// ErrUnsupportedDocument 不支持的文件类型
+	ErrUnsupportedDocument = errors.New("仅支持 PDF、DOCX、PPTX、Markdown 与 TXT 文件")
+	// ErrEmptyDocument 未解析到文本内容
+	ErrEmptyDocument = errors.New("未从文件中解析到文本内容")
+	// ErrStorageUnavailable 未配置资料存储
+	ErrStorageUnavailable = errors.New("未配置资料文件存储")
+
+	slideName = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+)
+
+// DocumentType 根据文件名判断资料类型
+func DocumentType(fileName string) (string, error) {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".pdf":
+		return DocumentPDF, nil
+	case ".docx":
+		return DocumentDOCX, nil
+	case ".pptx":
+		return DocumentPPTX, nil
+	case ".md", ".markdown":
+		return DocumentMarkdown, nil
+	case ".txt":
+		return DocumentText, nil
+	}
+	return "", ErrUnsupportedDocument
+}
+
+// DocumentMaxSize 单个资料文件的大小上限（字节）
+func DocumentMaxSize() int64 {
+	mb := config.GetConfig().FastGPT.Documents.MaxSizeMB
+	if mb <= 0 {
+		mb = defaultDocumentMaxSizeMB
+	}
+	return int64(mb) << 20
+}
+
+// DocumentStorage 保存原始资料文件的存储
+func DocumentStorage() (fileServer.FileClient, error) {
+	key := config.GetConfig().FastGPT.Documents.StorageKey
+	if key == "" {
+		key = defaultDocumentStorageKey
+	}
+	client := fileServer.Client(key)
+	if client == nil {
+		return nil, ErrStorageUnavailable
+	}
+	return client, nil
+}
+
+// DocumentFileKey 为新上传的原始文件生成存储路径
+func DocumentFileKey(appId, fileType string) string {
+	return "fastgpt/documents/" + appId + "/" + ulid.Make().String() + "." + fileType
+}
+
+// ExtractText 提取资料中的文本，PDF 不在本地解析，交由 FastGPT 处理
+func ExtractText(fileType string, data []byte) (string, error) {
+	switch fileType {
+	case DocumentMarkdown, DocumentText:
+		if !utf8.Valid(data) {
+			return "", errors.New("文件不是 UTF-8 编码")
+		}
+		return string(data), nil
+	case DocumentDOCX:
+		return extractDOCX(data)
+	case DocumentPPTX:
+		return extractPPTX(data)
+	}
+	return "", ErrUnsupportedDocument
+}
+
+// extractDOCX 读取 word/document.xml 中的段落文本
+func extractDOCX(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("open docx: %w", err)
+	}
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			return extractXMLPart(f)
+		}
+	}
+	return "", errors.New("docx 文件缺少 word/document.xml")
+}
+
+// extractPPTX 按页码顺序读取每页幻灯片的文本
+func extractPPTX(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("open pptx: %w", err)
+	}
+	type slide struct {
+		index int
+		file  *zip.File
+	}
+	var slides []slide
+	for _, f := range zr.File {
+		if m := slideName.FindStringSubmatch(f.Name); m != nil {
+			index, _ := strconv.Atoi(m[1])
+			slides = append(slides, slide{index: index, file: f})
+		}
+	}
+	sort.Slice(slides, func(i, j int) bool { return slides[i].index < slides[j].index })
+
+	var pages []string
+	for _, s := range slides {
+		text, err := extractXMLPart(s.file)
+		if err != nil {
+			return "", err
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			pages = append(pages, text)
+		}
+	}
+	return strings.Join(pages, "\n\n"), nil
+}
+
+// extractXMLPart 提取 OOXML 中 <t> 元素的文本，<p> 结束时换行
+// docx 的 w:t/w:p 与 pptx 的 a:t/a:p 本地名相同，可共用
+func extractXMLPart(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	var sb strings.Builder
+	inText := false
+	decoder := xml.NewDecoder(io.LimitReader(rc, maxXMLPartSize))
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parse %s: %w", f.Name, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteByte('\t')
+			case "br":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// SplitChunks 按行切分文本，尽量在行边界处断开，每段不超过 size 个字符
+func SplitChunks(text string, size int) []string {
+	if size <= 0 {
+		size = defaultDocumentChunkSize
+	}
+	var (
+		chunks []string
+		cur    strings.Builder
+		curLen int
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		cur.Reset()
+		curLen = 0
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		runes := []rune(line)
+		// 超长的行直接按长度切开
+		for len(runes) > size {
+			flush()
+			chunks = append(chunks, string(runes[:size]))
+			runes = runes[size:]
+		}
+		if curLen > 0 && curLen+1+len(runes) > size {
+			flush()
+		}
+		if curLen > 0 {
+			cur.WriteByte('\n')
+			curLen++
+		}
+		cur.WriteString(string(runes))
+		curLen += len(runes)
+	}
+	flush()
+	return chunks
+}
+
+// ProcessDocument 将资料导入 FastGPT 知识库，在后台执行并持续更新处理进度
+// PDF 以本地文件集合上传由 FastGPT 解析，其余类型在本地解析切分后推送到虚拟集合
+func ProcessDocument(app *model.FastgptApp, doc *model.FastgptDocument, data []byte) {
+	ctx := context.Background()
+	client := NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey)
+
+	var err error
+	if doc.FileType == DocumentPDF {
+		err = importFileCollection(ctx, client, doc, data)
+	} else {
+		err = importTextCollection(ctx, client, doc, data)
+	}
+	if err != nil {
+		logx.SystemLogger.CtxError(ctx, "import document "+doc.ID, err)
+		updateDocument(ctx, doc.ID, map[string]interface{}{
+			"status":    model.DocumentError,
+			"error_msg": err.Error(),
+		})
+		return
+	}
+	updateDocument(ctx, doc.ID, map[string]interface{}{
+		"status": model.DocumentSuccess,
+		"stage":  model.DocumentStageDone,
+	})
+	// 知识库内容变化后，缓存的回答可能已过时
+	if _, err := InvalidateAnswerCache(app.ID); err != nil {
+		logx.SystemLogger.CtxError(ctx, "invalidate answer cache", err)
+	}
+}
+
+func importFileCollection(ctx context.Context, client *FastGPTClient, doc *model.FastgptDocument, data []byte) error {
+	updateDocument(ctx, doc.ID, map[string]interface{}{"stage": model.DocumentStageUpload})
+
+	meta, err := json.Marshal(map[string]interface{}{
+		"datasetId":    doc.DatasetId,
+		"trainingType": "chunk",
+		"chunkSize":    chunkSize(),
+	})
+	if err != nil {
+		return err
+	}
+	body, status, err := client.ForwardMultipart(ctx, "/core/dataset/collection/create/localFile",
+		"file", doc.FileName, data, map[string]string{"data": string(meta)})
+	result, err := fastGPTData(body, status, err)
+	if err != nil {
+		return err
+	}
+
+	inserted := int(result.Get("results.insertLen").Int())
+	updateDocument(ctx, doc.ID, map[string]interface{}{
+		"collection_id": result.Get("collectionId").String(),
+		"total_chunks":  inserted,
+		"pushed_chunks": inserted,
+	})
+	return nil
+}
+
+func importTextCollection(ctx context.Context, client *FastGPTClient, doc *model.FastgptDocument, data []byte) error {
+	updateDocument(ctx, doc.ID, map[string]interface{}{"stage": model.DocumentStageExtract})
+	text, err := ExtractText(doc.FileType, data)
+	if err != nil {
+		return err
+	}
+	chunks := SplitChunks(text, chunkSize())
+	if len(chunks) == 0 {
+		return ErrEmptyDocument
+	}
+
+	body, status, err := client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/collection/create", map[string]interface{}{
+		"datasetId": doc.DatasetId,
+		"name":      doc.FileName,
+		"type":      "virtual",
+	})
+	result, err := fastGPTData(body, status, err)
+	if err != nil {
+		return err
+	}
+	collectionId := result.String()
+	updateDocument(ctx, doc.ID, map[string]interface{}{
+		"collection_id": collectionId,
+		"stage":         model.DocumentStagePush,
+		"total_chunks":  len(chunks),
+	})
+
+	for start := 0; start < len(chunks); start += pushDataBatch {
+		end := start + pushDataBatch
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		items := make([]map[string]string, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			items = append(items, map[string]string{"q": chunk})
+		}
+		body, status, err := client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/data/pushData", map[string]interface{}{
+			"collectionId": collectionId,
+			"trainingType": "chunk",
+			"data":         items,
+		})
+		if _, err := fastGPTData(body, status, err); err != nil {
+			return err
+		}
+		updateDocument(ctx, doc.ID, map[string]interface{}{"pushed_chunks": end})
+	}
+	return nil
+}
+
+// DeleteDocumentCollection 删除资料在 FastGPT 中对应的集合
+func DeleteDocumentCollection(ctx context.Context, app *model.FastgptApp, collectionId string) error {
+	client := NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey)
+	body, status, err := client.ForwardRequestWithQuery(ctx, http.MethodDelete, "/core/dataset/collection/delete", map[string]string{
+		"id": collectionId,
+	})
+	_, err = fastGPTData(body, status, err)
+	return err
+}
+
+// fastGPTData 校验 FastGPT 响应 {"code":200,"data":...} 并返回 data
+func fastGPTData(body []byte, status int, err error) (gjson.Result, error) {
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if status != http.StatusOK || gjson.GetBytes(body, "code").Int() != http.StatusOK {
+		msg := gjson.GetBytes(body, "message").String()
+		if msg == "" {
+			msg = gjson.GetBytes(body, "statusText").String()
+		}
+		if msg == "" {
+			msg = http.StatusText(status)
+		}
+		return gjson.Result{}, fmt.Errorf("FastGPT 返回错误(%d): %s", status, msg)
+	}
+	return gjson.GetBytes(body, "data"), nil
+}
+
+func chunkSize() int {
+	if size := config.GetConfig().FastGPT.Documents.ChunkSize; size > 0 {
+		return size
+	}
+	return defaultDocumentChunkSize
+}
+
+func updateDocument(ctx context.Context, id string, updates map[string]interface{}) {
+	if err := dao.Document.Update(ctx, id, updates); err != nil {
+		logx.SystemLogger.Errorf("update document %s: %v", id, err)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractText_DOCX(t *testing.T) {
+	data := zipFiles(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:body>` +
+			`<w:p><w:r><w:t>第一章</w:t></w:r><w:r><w:t xml:space="preserve"> 极限</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>定义</w:t><w:tab/><w:t>说明</w:t></w:r></w:p>` +
+			`</w:body></w:document>`,
+	})
+	text, err := ExtractText(DocumentDOCX, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "第一章 极限\n定义\t说明\n" {
+		t.Errorf("unexpected docx text %q", text)
+	}
+}
+
+func TestExtractText_PPTXSlideOrder(t *testing.T) {
+	slide := func(s string) string {
+		return `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>` + s + `</a:t></a:r></a:p></p:sld>`
+	}
+	data := zipFiles(t, map[string]string{
+		"ppt/slides/slide10.xml":            slide("十"),
+		"ppt/slides/slide2.xml":             slide("二"),
+		"ppt/slides/slide1.xml":             slide("一"),
+		"ppt/slides/_rels/slide1.xml.rels":  "<Relationships/>",
+		"ppt/slideLayouts/slideLayout1.xml": slide("版式"),
+	})
+	text, err := ExtractText(DocumentPPTX, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "一\n\n二\n\n十" {
+		t.Errorf("unexpected pptx text %q", text)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	text := "第一行\n\n第二行\r\n" + strings.Repeat("长", 25) + "\n最后"
+	chunks := SplitChunks(text, 10)
+
+	want := []string{"第一行\n第二行", strings.Repeat("长", 10), strings.Repeat("长", 10), strings.Repeat("长", 5) + "\n最后"}
+	if len(chunks) != len(want) {
+		t.Fatalf("expected %d chunks, got %d: %q", len(want), len(chunks), chunks)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("chunk %d: expected %q, got %q", i, want[i], chunks[i])
+		}
+		if utf8.RuneCountInString(chunks[i]) > 10 {
+			t.Errorf("chunk %d exceeds size: %q", i, chunks[i])
+		}
+	}
+
+	if len(SplitChunks(" \n\t\n", 10)) != 0 {
+		t.Error("blank text should produce no chunks")
+	}
+}
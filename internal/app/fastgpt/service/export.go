@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"HelpStudent/config"
+	"HelpStudent/internal/app/fastgpt/model"
+
+	"github.com/tidwall/gjson"
+	"github.com/xuri/excelize/v2"
+)
+
+// 会话导出格式
+const (
+	ExportMarkdown = "markdown"
+	ExportJSON     = "json"
+	ExportXLSX     = "xlsx"
+)
+
+const (
+	exportPageSize = 50
+	// maxExportRecords 单个会话最多导出的记录数（提问与回答各算一条）
+	maxExportRecords = 2000
+)
+
+// ExportUpstreamError 获取聊天记录时 FastGPT 返回错误，由调用方按状态码映射
+type ExportUpstreamError struct {
+	Status int
+	Body   []byte
+}
+
+func (e *ExportUpstreamError) Error() string {
+	return fmt.Sprintf("get pagination records: status=%d", e.Status)
+}
+
+// ChatExport 导出的会话
+type ChatExport struct {
+	AppName    string       `json:"appName"`
+	ChatId     string       `json:"chatId"`
+	Title      string       `json:"title"`
+	ExportedAt time.Time    `json:"exportedAt"`
+	Turns      []ExportTurn `json:"turns"`
+}
+
+// ExportTurn 一轮问答，只有提问或只有回答时另一项为空
+type ExportTurn struct {
+	Question   string        `json:"question"`
+	QuestionAt *time.Time    `json:"questionAt,omitempty"`
+	Answer     string        `json:"answer"`
+	AnswerAt   *time.Time    `json:"answerAt,omitempty"`
+	Quotes     []ExportQuote `json:"quotes,omitempty"`
+}
+
+// ExportQuote 回答引用的知识库内容
+type ExportQuote struct {
+	SourceName string `json:"sourceName"`
+	Q          string `json:"q"`
+	A          string `json:"a,omitempty"`
+}
+
+// ValidExportFormat 是否为支持的导出格式
+func ValidExportFormat(format string) bool {
+	return format == ExportMarkdown || format == ExportJSON || format == ExportXLSX
+}
+
+// ExportContentType 导出格式对应的 Content-Type 与扩展名
+func ExportContentType(format string) (contentType, ext string) {
+	switch format {
+	case ExportJSON:
+		return "application/json; charset=utf-8", ".json"
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"
+	default:
+		return "text/markdown; charset=utf-8", ".md"
+	}
+}
+
+// ExportChat 分页拉取 FastGPT 中的会话记录，仅 FastGPT 应用支持
+func ExportChat(ctx context.Context, app *model.FastgptApp, chatId string) (*ChatExport, error) {
+	return exportChat(ctx, NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey), app, chatId)
+}
+
+func exportChat(ctx context.Context, client *FastGPTClient, app *model.FastgptApp, chatId string) (*ChatExport, error) {
+	records, err := fetchRecords(ctx, client, app, chatId)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatExport{
+		AppName:    app.AppName,
+		ChatId:     chatId,
+		ExportedAt: time.Now(),
+		Turns:      buildTurns(records),
+	}, nil
+}
+
+// fetchRecords 分页拉取会话的全部记录，最多 maxExportRecords 条
+func fetchRecords(ctx context.Context, client *FastGPTClient, app *model.FastgptApp, chatId string) ([]gjson.Result, error) {
+	var records []gjson.Result
+	for offset := 0; offset < maxExportRecords; offset += exportPageSize {
+		body, status, err := client.ForwardRequest(ctx, http.MethodPost, "/core/chat/getPaginationRecords", map[string]interface{}{
+			"appId":    app.AppId,
+			"chatId":   chatId,
+			"offset":   offset,
+			"pageSize": exportPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK || gjson.GetBytes(body, "code").Int() != http.StatusOK {
+			return nil, &ExportUpstreamError{Status: status, Body: body}
+		}
+		list := gjson.GetBytes(body, "data.list").Array()
+		records = append(records, list...)
+		if len(list) < exportPageSize || int64(len(records)) >= gjson.GetBytes(body, "data.total").Int() {
+			break
+		}
+	}
+	return records, nil
+}
+
+// buildTurns 将按时间排列的记录合并为问答轮次，连续的提问或回答各自成为一轮
+func buildTurns(records []gjson.Result) []ExportTurn {
+	turns := make([]ExportTurn, 0, len(records)/2+1)
+	for _, rec := range records {
+		text := recordText(rec.Get("value"))
+		at := recordTime(rec.Get("time"))
+		switch rec.Get("obj").String() {
+		case "Human":
+			turns = append(turns, ExportTurn{Question: text, QuestionAt: at})
+		case "AI":
+			if n := len(turns); n > 0 && turns[n-1].AnswerAt == nil && turns[n-1].Answer == "" {
+				turns[n-1].Answer, turns[n-1].AnswerAt = text, at
+				turns[n-1].Quotes = recordQuotes(rec)
+				continue
+			}
+			turns = append(turns, ExportTurn{Answer: text, AnswerAt: at, Quotes: recordQuotes(rec)})
+		}
+	}
+	return turns
+}
+
+// recordText 拼接记录中的文本内容，图片、文件等其他类型忽略
+func recordText(value gjson.Result) string {
+	if value.Type == gjson.String {
+		return value.String()
+	}
+	var parts []string
+	for _, item := range value.Array() {
+		if item.Get("type").String() == "text" {
+			if content := item.Get("text.content").String(); content != "" {
+				parts = append(parts, content)
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func recordTime(v gjson.Result) *time.Time {
+	t, err := time.Parse(time.RFC3339, v.String())
+	if err != nil {
+		return nil
+	}
+	t = t.Local()
+	return &t
+}
+
+// recordQuotes 回答引用的知识库内容，兼容 totalQuoteList 与 responseData 中的 quoteList
+func recordQuotes(rec gjson.Result) []ExportQuote {
+	var quotes []ExportQuote
+	seen := map[string]bool{}
+	add := func(list gjson.Result) {
+		for _, q := range list.Array() {
+			key := q.Get("id").String()
+			if key == "" {
+				key = q.Get("q").String()
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			quotes = append(quotes, ExportQuote{
+				SourceName: q.Get("sourceName").String(),
+				Q:          q.Get("q").String(),
+				A:          q.Get("a").String(),
+			})
+		}
+	}
+	add(rec.Get("totalQuoteList"))
+	for _, node := range rec.Get("responseData").Array() {
+		add(node.Get("quoteList"))
+	}
+	return quotes
+}
+
+// Write 按格式写出
+func (e *ChatExport) Write(w io.Writer, format string) error {
+	switch format {
+	case ExportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(e)
+	case ExportXLSX:
+		return e.writeXLSX(w)
+	default:
+		return e.writeMarkdown(w)
+	}
+}
+
+func (e *ChatExport) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	title := e.Title
+	if title == "" {
+		title = e.ChatId
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- 科目：%s\n- 会话ID：%s\n- 导出时间：%s\n", e.AppName, e.ChatId, e.ExportedAt.Format(exportTimeLayout))
+	for i, turn := range e.Turns {
+		fmt.Fprintf(&b, "\n## 第 %d 轮\n", i+1)
+		if turn.Question != "" || turn.QuestionAt != nil {
+			fmt.Fprintf(&b, "\n**提问**（%s）\n\n%s\n", formatExportTime(turn.QuestionAt), turn.Question)
+		}
+		if turn.Answer != "" || turn.AnswerAt != nil {
+			fmt.Fprintf(&b, "\n**回答**（%s）\n\n%s\n", formatExportTime(turn.AnswerAt), turn.Answer)
+		}
+		if len(turn.Quotes) > 0 {
+			b.WriteString("\n**引用**\n\n")
+			for j, q := range turn.Quotes {
+				fmt.Fprintf(&b, "%d. %s：%s\n", j+1, q.SourceName, quoteLine(q.Q))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (e *ChatExport) writeXLSX(w io.Writer) error {
+	sheet := usageSheet{name: "会话记录", headers: []string{"轮次", "提问", "提问时间", "回答", "回答时间", "引用来源"}}
+	for i, turn := range e.Turns {
+		sources := make([]string, 0, len(turn.Quotes))
+		for _, q := range turn.Quotes {
+			sources = append(sources, q.SourceName+"："+q.Q)
+		}
+		sheet.rows = append(sheet.rows, []interface{}{
+			i + 1, turn.Question, formatExportTime(turn.QuestionAt), turn.Answer, formatExportTime(turn.AnswerAt), strings.Join(sources, "\n"),
+		})
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		_ = f.Close()
+	}()
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	if err := sheet.write(f, headerStyle); err != nil {
+		return err
+	}
+	// 提问、回答与引用列较长，加宽并自动换行
+	wrapStyle, err := f.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"}})
+	if err != nil {
+		return err
+	}
+	if len(sheet.rows) > 0 {
+		last, _ := excelize.CoordinatesToCellName(len(sheet.headers), len(sheet.rows)+1)
+		_ = f.SetCellStyle(sheet.name, "A2", last, wrapStyle)
+	}
+	for _, col := range []string{"B", "D", "F"} {
+		_ = f.SetColWidth(sheet.name, col, col, 60)
+	}
+	index, _ := f.GetSheetIndex(sheet.name)
+	f.SetActiveSheet(index)
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(exportTimeLayout)
+}
+
+// quoteLine 引用内容压成一行，过长时截断
+func quoteLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return truncateRunes(s, 200)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"HelpStudent/internal/app/fastgpt/fake"
+	"HelpStudent/internal/app/fastgpt/model"
+
+	"github.com/tidwall/gjson"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportChat(t *testing.T) {
+	srv := fake.New()
+	baseURL := srv.Start()
+	defer srv.Close()
+	srv.SetAPIKey("export-key")
+	srv.BindKey("export-key", "app1")
+	srv.SetAnswerFunc(func(question string) string { return "回答：" + question })
+
+	client := NewFastGPTClient(baseURL, "export-key")
+	// 超过一页，验证分页拉取
+	for i := 0; i < exportPageSize; i++ {
+		_, status, err := client.ForwardRequest(context.Background(), http.MethodPost, "/v1/chat/completions", map[string]interface{}{
+			"chatId":   "c1",
+			"messages": []map[string]string{{"role": "user", "content": fmt.Sprintf("问题%d", i)}},
+		})
+		if err != nil || status != http.StatusOK {
+			t.Fatalf("chat: status=%d err=%v", status, err)
+		}
+	}
+
+	app := &model.FastgptApp{AppName: "高数", AppId: "app1"}
+	export, err := exportChat(context.Background(), client, app, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Turns) != exportPageSize {
+		t.Fatalf("turns = %d", len(export.Turns))
+	}
+	last := export.Turns[exportPageSize-1]
+	if last.Question != "问题49" || last.Answer != "回答：问题49" || last.QuestionAt == nil || last.AnswerAt == nil {
+		t.Errorf("last turn = %+v", last)
+	}
+
+	_, err = exportChat(context.Background(), NewFastGPTClient(baseURL, "wrong"), app, "c1")
+	var ue *ExportUpstreamError
+	if !errors.As(err, &ue) || ue.Status != http.StatusUnauthorized {
+		t.Errorf("wrong key err = %v", err)
+	}
+}
+
+func TestBuildTurnsQuotes(t *testing.T) {
+	records := gjson.Parse(`[
+		{"obj":"Human","time":"2024-03-01T08:00:00Z","value":[{"type":"text","text":{"content":"什么是极限"}}]},
+		{"obj":"AI","time":"2024-03-01T08:00:05Z","value":[{"type":"text","text":{"content":"极限描述"}},{"type":"text","text":{"content":"变化趋势"}}],
+		 "totalQuoteList":[{"id":"q1","sourceName":"讲义.pdf","q":"极限的定义","a":""}],
+		 "responseData":[{"quoteList":[{"id":"q1","sourceName":"讲义.pdf","q":"极限的定义"},{"id":"q2","sourceName":"习题.docx","q":"例题 1"}]}]},
+		{"obj":"AI","time":"2024-03-01T08:01:00Z","value":"补充说明"}
+	]`).Array()
+
+	turns := buildTurns(records)
+	if len(turns) != 2 {
+		t.Fatalf("turns = %+v", turns)
+	}
+	if turns[0].Answer != "极限描述\n变化趋势" || len(turns[0].Quotes) != 2 || turns[0].Quotes[1].SourceName != "习题.docx" {
+		t.Errorf("first turn = %+v", turns[0])
+	}
+	if turns[1].Question != "" || turns[1].Answer != "补充说明" {
+		t.Errorf("answer without question = %+v", turns[1])
+	}
+
+	export := &ChatExport{AppName: "高数", ChatId: "c1", Title: "极限", Turns: turns}
+
+	var md bytes.Buffer
+	if err := export.Write(&md, ExportMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# 极限", "什么是极限", "变化趋势", "1. 讲义.pdf：极限的定义"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+
+	var js bytes.Buffer
+	if err := export.Write(&js, ExportJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded ChatExport
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded.Turns) != 2 {
+		t.Errorf("json = %s, err = %v", js.String(), err)
+	}
+
+	var xlsx bytes.Buffer
+	if err := export.Write(&xlsx, ExportXLSX); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&xlsx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows("会话记录")
+	if err != nil || len(rows) != 3 || rows[1][1] != "什么是极限" {
+		t.Errorf("xlsx rows = %v, err = %v", rows, err)
+	}
+}
@@ -3,106 +3,194 @@ package service
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"time"
+
+	"HelpStudent/core/breaker"
 )
 
+// ErrFastGPTUnavailable FastGPT 连续失败，熔断器处于打开状态
+var ErrFastGPTUnavailable = errors.New("FastGPT 服务暂时不可用，请稍后再试")
+
+// upstreamError FastGPT 返回 5xx，计入熔断失败次数
+type upstreamError struct {
+	StatusCode int
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("FastGPT server error: status=%d", e.StatusCode)
+}
+
 // FastGPTClient FastGPT 客户端
+// 同一 FastGPT 地址与 API Key 共用一个熔断器，所有客户端共用连接池
 type FastGPTClient struct {
 	BaseURL string
 	APIKey  string
 	Client  *http.Client
+
+	stream  *http.Client
+	options clientOptions
+	breaker breaker.Breaker
 }
 
 // NewFastGPTClient 创建 FastGPT 客户端
 func NewFastGPTClient(baseURL, apiKey string) *FastGPTClient {
+	opts, client, stream := sharedClients()
 	return &FastGPTClient{
 		BaseURL: baseURL,
 		APIKey:  apiKey,
-		Client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		Client:  client,
+		stream:  stream,
+		options: opts,
+		breaker: breaker.GetBreaker("fastgpt:" + baseURL + ":" + APIKeyFingerprint(apiKey)),
 	}
 }
 
 // ForwardRequest 转发请求到 FastGPT
-func (c *FastGPTClient) ForwardRequest(method, path string, body interface{}) ([]byte, int, error) {
-	var reqBody io.Reader
+func (c *FastGPTClient) ForwardRequest(ctx context.Context, method, path string, body interface{}) ([]byte, int, error) {
+	var jsonData []byte
 	if body != nil {
-		jsonData, err := json.Marshal(body)
+		var err error
+		jsonData, err = json.Marshal(body)
 		if err != nil {
 			return nil, 0, fmt.Errorf("marshal request body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	url := c.BaseURL + path
-	req, err := http.NewRequest(method, url, reqBody)
-	if err != nil {
-		return nil, 0, fmt.Errorf("create request: %w", err)
-	}
-
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	return c.do(ctx, method, c.BaseURL+path, func(req *http.Request) {
+		if jsonData != nil {
+			req.Body = io.NopCloser(bytes.NewReader(jsonData))
+			req.ContentLength = int64(len(jsonData))
+		}
+		req.Header.Set("Content-Type", "application/json")
+	})
+}
 
-	// 发送请求
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, 0, fmt.Errorf("send request: %w", err)
+// ForwardRequestWithQuery 带查询参数转发请求到 FastGPT
+func (c *FastGPTClient) ForwardRequestWithQuery(ctx context.Context, method, path string, queryParams map[string]string) ([]byte, int, error) {
+	u := c.BaseURL + path
+	// 添加查询参数
+	if len(queryParams) > 0 {
+		q := url.Values{}
+		for key, value := range queryParams {
+			q.Add(key, value)
+		}
+		u += "?" + q.Encode()
 	}
-	defer resp.Body.Close()
+	return c.do(ctx, method, u, nil)
+}
 
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
+// ForwardMultipart 以 multipart/form-data 上传文件到 FastGPT，fields 为附加的表单字段
+func (c *FastGPTClient) ForwardMultipart(ctx context.Context, path, fileField, fileName string, file []byte, fields map[string]string) ([]byte, int, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for key, value := range fields {
+		if err := w.WriteField(key, value); err != nil {
+			return nil, 0, fmt.Errorf("write form field: %w", err)
+		}
+	}
+	part, err := w.CreateFormFile(fileField, fileName)
 	if err != nil {
-		return nil, resp.StatusCode, fmt.Errorf("read response: %w", err)
+		return nil, 0, fmt.Errorf("create form file: %w", err)
+	}
+	if _, err := part.Write(file); err != nil {
+		return nil, 0, fmt.Errorf("write form file: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, 0, fmt.Errorf("close multipart writer: %w", err)
 	}
 
-	return respBody, resp.StatusCode, nil
+	data := buf.Bytes()
+	return c.do(ctx, http.MethodPost, c.BaseURL+path, func(req *http.Request) {
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Type", w.FormDataContentType())
+	})
 }
 
-// ForwardRequestWithQuery 带查询参数转发请求到 FastGPT
-func (c *FastGPTClient) ForwardRequestWithQuery(method, path string, queryParams map[string]string) ([]byte, int, error) {
-	url := c.BaseURL + path
-	req, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return nil, 0, fmt.Errorf("create request: %w", err)
+// do 经熔断器发送请求并读取响应，幂等请求在网络错误或网关错误时按退避重试
+func (c *FastGPTClient) do(ctx context.Context, method, u string, prepare func(req *http.Request)) ([]byte, int, error) {
+	retries := 0
+	if isIdempotent(method) {
+		retries = c.options.maxRetries
 	}
 
-	// 添加查询参数
-	if len(queryParams) > 0 {
-		q := req.URL.Query()
-		for key, value := range queryParams {
-			q.Add(key, value)
+	var (
+		respBody   []byte
+		statusCode int
+		err        error
+	)
+	for attempt := 0; ; attempt++ {
+		respBody, statusCode, err = c.doOnce(ctx, method, u, prepare)
+		if attempt >= retries || !shouldRetry(ctx, statusCode, err) {
+			break
+		}
+		select {
+		case <-time.After(backoff(attempt)):
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
 		}
-		req.URL.RawQuery = q.Encode()
 	}
+	return respBody, statusCode, err
+}
 
-	// 设置请求头
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+func (c *FastGPTClient) doOnce(ctx context.Context, method, u string, prepare func(req *http.Request)) ([]byte, int, error) {
+	var (
+		respBody   []byte
+		statusCode int
+	)
+	err := c.breaker.DoWithAcceptable(func() error {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return fmt.Errorf("create request: %w", err)
+		}
+		if prepare != nil {
+			prepare(req)
+		}
+		// 设置请求头
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
 
-	// 发送请求
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, 0, fmt.Errorf("send request: %w", err)
-	}
-	defer resp.Body.Close()
+		// 发送请求
+		resp, err := c.Client.Do(req)
+		if err != nil {
+			return fmt.Errorf("send request: %w", err)
+		}
+		defer resp.Body.Close()
+		statusCode = resp.StatusCode
 
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, resp.StatusCode, fmt.Errorf("read response: %w", err)
-	}
+		// 读取响应
+		respBody, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("read response: %w", err)
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return &upstreamError{StatusCode: resp.StatusCode}
+		}
+		return nil
+	}, acceptable(ctx))
 
-	return respBody, resp.StatusCode, nil
+	var ue *upstreamError
+	if errors.As(err, &ue) {
+		// 5xx 已计入熔断，响应体仍交给调用方做错误转换
+		return respBody, statusCode, nil
+	}
+	if errors.Is(err, breaker.ErrServiceUnavailable) {
+		return nil, 0, ErrFastGPTUnavailable
+	}
+	return respBody, statusCode, err
 }
 
 // ForwardStreamRequest 转发流式请求到 FastGPT，返回响应对象用于流式读取
-func (c *FastGPTClient) ForwardStreamRequest(method, path string, body interface{}) (*http.Response, error) {
+// 响应头超时与数据间隔超时由配置决定，ctx 取消时请求随之中止
+func (c *FastGPTClient) ForwardStreamRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
 	var reqBody io.Reader
 	if body != nil {
 		jsonData, err := json.Marshal(body)
@@ -112,9 +200,10 @@ func (c *FastGPTClient) ForwardStreamRequest(method, path string, body interface
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	url := c.BaseURL + path
-	req, err := http.NewRequest(method, url, reqBody)
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reqBody)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 
@@ -125,19 +214,68 @@ func (c *FastGPTClient) ForwardStreamRequest(method, path string, body interface
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Connection", "keep-alive")
 
-	// 流式客户端配置
-	streamClient := &http.Client{
-		Timeout: 0, // 流式请求不设置超时
-		Transport: &http.Transport{
-			DisableCompression: true, // 禁用压缩，确保数据实时到达
-		},
+	var resp *http.Response
+	err = c.breaker.DoWithAcceptable(func() error {
+		var err error
+		resp, err = c.stream.Do(req)
+		if err != nil {
+			return fmt.Errorf("send request: %w", err)
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return &upstreamError{StatusCode: resp.StatusCode}
+		}
+		return nil
+	}, acceptable(ctx))
+
+	var ue *upstreamError
+	switch {
+	case errors.As(err, &ue):
+	case errors.Is(err, breaker.ErrServiceUnavailable):
+		cancel()
+		return nil, ErrFastGPTUnavailable
+	case err != nil:
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = newIdleTimeoutBody(resp.Body, c.options.streamIdleTimeout, cancel)
+	return resp, nil
+}
+
+// acceptable 调用方主动取消的请求不计入熔断失败
+func acceptable(ctx context.Context) breaker.Acceptable {
+	return func(err error) bool {
+		return err == nil || ctx.Err() != nil
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry 网络错误与网关类错误可以重试，熔断打开或调用方取消时不重试
+func shouldRetry(ctx context.Context, statusCode int, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrFastGPTUnavailable) {
+		return false
 	}
-	resp, err := streamClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("send request: %w", err)
+		return true
+	}
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
 	}
+	return false
+}
 
-	return resp, nil
+// backoff 200ms 起指数退避，附带随机抖动
+func backoff(attempt int) time.Duration {
+	d := 200 * time.Millisecond << attempt
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
 }
 
 // StreamReader 流式读取器
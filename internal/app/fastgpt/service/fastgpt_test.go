@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFastGPTClient_RetryIdempotent(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"code":200}`))
+	}))
+	defer srv.Close()
+
+	client := NewFastGPTClient(srv.URL, "retry-key")
+	body, status, err := client.ForwardRequestWithQuery(context.Background(), http.MethodGet, "/detail", nil)
+	if err != nil || status != http.StatusOK || string(body) != `{"code":200}` {
+		t.Fatalf("GET should succeed after retry, got status=%d err=%v body=%s", status, err, body)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 calls, got %d", calls.Load())
+	}
+
+	// POST 不是幂等请求，不重试
+	calls.Store(0)
+	_, status, err = client.ForwardRequest(context.Background(), http.MethodPost, "/create", map[string]string{"a": "b"})
+	if err != nil || status != http.StatusBadGateway {
+		t.Fatalf("POST should return upstream status, got status=%d err=%v", status, err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("POST should not be retried, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"HelpStudent/config"
+	"HelpStudent/core/logx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+// ForwardFeedbackEnabled 是否需要将评价同步到 FastGPT，仅 FastGPT 应用支持
+func ForwardFeedbackEnabled(app *model.FastgptApp) bool {
+	return config.GetConfig().FastGPT.ForwardFeedback && app.IsFastGPT() && app.AppId != ""
+}
+
+// ForwardFeedback 将评价同步到 FastGPT 对话日志的用户反馈，并记录同步结果
+func ForwardFeedback(ctx context.Context, app *model.FastgptApp, fb *model.FastgptAnswerFeedback) error {
+	body := map[string]interface{}{
+		"appId":  app.AppId,
+		"chatId": fb.ChatId,
+		"dataId": fb.DataId,
+	}
+	// FastGPT 中赞为 "yes"，踩的内容即反馈理由；另一项留空表示清除
+	if fb.Rating == model.FeedbackUp {
+		body["userGoodFeedback"] = "yes"
+	} else {
+		reason := fb.Reason
+		if reason == "" {
+			reason = "yes"
+		}
+		body["userBadFeedback"] = reason
+	}
+
+	client := NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey)
+	respBody, status, err := client.ForwardRequest(ctx, http.MethodPost, "/core/chat/feedback/updateUserFeedback", body)
+	_, err = fastGPTData(respBody, status, err)
+
+	if markErr := dao.Feedback.MarkForwarded(ctx, fb.DataId, fb.UserId, err); markErr != nil {
+		logx.SystemLogger.CtxError(ctx, "mark feedback forwarded", markErr)
+	}
+	return err
+}
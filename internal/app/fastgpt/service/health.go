@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"HelpStudent/config"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/threadx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/model"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultHealthInterval = 300
+	defaultHealthTimeout  = 10
+	// healthCheckUid 检查分享链接时使用的外链用户
+	healthCheckUid = "health-check"
+)
+
+// AppHealth 一次健康检查的结果
+type AppHealth struct {
+	Status string
+	Error  string
+}
+
+// CheckAppHealth 校验应用的 API Key 与分享链接，非 FastGPT 应用返回 nil
+func CheckAppHealth(ctx context.Context, app *model.FastgptApp) *AppHealth {
+	if !app.IsFastGPT() {
+		return nil
+	}
+	return checkAppHealth(ctx, NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey), app)
+}
+
+func checkAppHealth(ctx context.Context, client *FastGPTClient, app *model.FastgptApp) *AppHealth {
+	// 以最小的历史查询校验 API Key 与应用是否存在，不产生对话
+	body, status, err := client.ForwardRequest(ctx, http.MethodPost, "/core/chat/getHistories", map[string]interface{}{
+		"appId":    app.AppId,
+		"offset":   0,
+		"pageSize": 1,
+	})
+	if health := classifyHealth(body, status, err, model.AppHealthKeyInvalid); health != nil {
+		return health
+	}
+
+	if app.ShareId != "" {
+		body, status, err = client.ForwardRequestWithQuery(ctx, http.MethodGet, "/core/chat/outLink/init", map[string]string{
+			"shareId":    app.ShareId,
+			"outLinkUid": healthCheckUid,
+		})
+		if health := classifyHealth(body, status, err, model.AppHealthShareInvalid); health != nil {
+			return health
+		}
+	}
+	return &AppHealth{Status: model.AppHealthOK}
+}
+
+// classifyHealth 成功时返回 nil，否则按响应判断失败原因，鉴权类错误归为 invalid
+func classifyHealth(body []byte, status int, err error, invalid string) *AppHealth {
+	if err != nil {
+		return &AppHealth{Status: model.AppHealthUnreachable, Error: err.Error()}
+	}
+	if status == http.StatusOK && gjson.GetBytes(body, "code").Int() == http.StatusOK {
+		return nil
+	}
+
+	msg := gjson.GetBytes(body, "message").String()
+	if msg == "" {
+		msg = gjson.GetBytes(body, "statusText").String()
+	}
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	detail := fmt.Sprintf("status=%d: %s", status, msg)
+	lower := strings.ToLower(msg)
+
+	switch {
+	case invalid == model.AppHealthKeyInvalid && strings.Contains(lower, "app") &&
+		(strings.Contains(lower, "not exist") || strings.Contains(lower, "不存在")):
+		return &AppHealth{Status: model.AppHealthAppMissing, Error: detail}
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusNotFound ||
+		strings.Contains(lower, "unauth") || strings.Contains(lower, "not exist") || strings.Contains(lower, "不存在"):
+		return &AppHealth{Status: invalid, Error: detail}
+	case status >= http.StatusInternalServerError:
+		return &AppHealth{Status: model.AppHealthUnreachable, Error: detail}
+	default:
+		return &AppHealth{Status: invalid, Error: detail}
+	}
+}
+
+// HealthMonitor 后台定期检查所有应用，状态变化时记录日志并发送通知
+type HealthMonitor struct {
+	interval time.Duration
+	timeout  time.Duration
+	webhook  string
+	client   *http.Client // 发送通知，与访问 FastGPT 的连接池分开
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// StartHealthMonitor 启动后台检查，配置关闭时返回 nil
+func StartHealthMonitor(ctx context.Context) *HealthMonitor {
+	conf := config.GetConfig().FastGPT.HealthCheck
+	if conf.Interval < 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	timeout := seconds(conf.Timeout, defaultHealthTimeout)
+	m := &HealthMonitor{
+		interval: seconds(conf.Interval, defaultHealthInterval),
+		timeout:  timeout,
+		webhook:  conf.AlertWebhook,
+		client:   &http.Client{Timeout: timeout},
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	threadx.GoSafe(func() {
+		defer close(m.done)
+		m.run(ctx)
+	})
+	return m
+}
+
+// Stop 停止检查并等待进行中的一轮结束，ctx 到期时不再等待
+func (m *HealthMonitor) Stop(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	m.cancel()
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *HealthMonitor) run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		m.checkAll(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkAll 依次检查所有应用，单个应用失败不影响其他应用
+func (m *HealthMonitor) checkAll(ctx context.Context) {
+	apps, err := dao.FastgptApp.ListAllApps(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logx.SystemLogger.Errorf("health check: list apps: %v", err)
+		}
+		return
+	}
+	for i := range apps {
+		if ctx.Err() != nil {
+			return
+		}
+		m.check(ctx, &apps[i])
+	}
+}
+
+func (m *HealthMonitor) check(ctx context.Context, app *model.FastgptApp) {
+	checkCtx, cancel := context.WithTimeout(ctx, m.timeout)
+	health := CheckAppHealth(checkCtx, app)
+	cancel()
+	if health == nil || ctx.Err() != nil {
+		return
+	}
+
+	if err := dao.FastgptApp.UpdateHealth(ctx, app.ID, health.Status, health.Error, time.Now()); err != nil {
+		logx.SystemLogger.Errorf("health check: update app %s: %v", app.ID, err)
+		return
+	}
+	if health.Status == app.HealthStatus {
+		return
+	}
+
+	// 首次检查正常时不提示
+	if health.Status == model.AppHealthOK {
+		if app.HealthStatus != model.AppHealthUnknown {
+			logx.SystemLogger.Infof("FastGPT 应用 %s 已恢复正常（之前为 %s）", app.AppName, app.HealthStatus)
+			m.alert(ctx, app, health)
+		}
+		return
+	}
+	logx.SystemLogger.Errorf("FastGPT 应用 %s 健康检查失败: %s %s", app.AppName, health.Status, health.Error)
+	m.alert(ctx, app, health)
+}
+
+// alert 将状态变化通知到 AlertWebhook
+func (m *HealthMonitor) alert(ctx context.Context, app *model.FastgptApp, health *AppHealth) {
+	if m.webhook == "" {
+		return
+	}
+	payload, _ := json.Marshal(map[string]string{
+		"id":      app.ID,
+		"appName": app.AppName,
+		"from":    app.HealthStatus,
+		"to":      health.Status,
+		"error":   health.Error,
+		"time":    time.Now().Format(time.RFC3339),
+	})
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.webhook, bytes.NewReader(payload))
+	if err != nil {
+		logx.SystemLogger.Errorf("health check alert: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			err = errors.New(resp.Status)
+		}
+	}
+	if err != nil {
+		logx.SystemLogger.Errorf("health check alert: %v", err)
+	}
+}
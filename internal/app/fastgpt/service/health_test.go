@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"HelpStudent/internal/app/fastgpt/fake"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+func TestCheckAppHealth(t *testing.T) {
+	srv := fake.New()
+	baseURL := srv.Start()
+	defer srv.Close()
+	srv.SetAPIKey("health-key")
+	srv.AddShare("share1", "app1", "高数")
+
+	check := func(apiKey, shareId string) *AppHealth {
+		app := &model.FastgptApp{AppId: "app1", ShareId: shareId, APIKey: apiKey}
+		return checkAppHealth(context.Background(), NewFastGPTClient(baseURL, apiKey), app)
+	}
+
+	if h := check("health-key", "share1"); h.Status != model.AppHealthOK {
+		t.Errorf("valid app: %+v", h)
+	}
+	if h := check("revoked-key", ""); h.Status != model.AppHealthKeyInvalid {
+		t.Errorf("revoked key: %+v", h)
+	}
+	if h := check("health-key", "deleted-share"); h.Status != model.AppHealthShareInvalid {
+		t.Errorf("deleted share: %+v", h)
+	}
+
+	srv.Fail("/core/chat/getHistories", fake.Failure{
+		Status: http.StatusInternalServerError,
+		Body:   `{"code":500,"statusText":"appUnExist","message":"App not exist"}`,
+	})
+	if h := check("health-key", ""); h.Status != model.AppHealthAppMissing {
+		t.Errorf("deleted app: %+v", h)
+	}
+
+	srv.Close()
+	if h := check("health-key", ""); h.Status != model.AppHealthUnreachable || h.Error == "" {
+		t.Errorf("closed server: %+v", h)
+	}
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"HelpStudent/config"
+	"HelpStudent/core/fileServer"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/syncx"
+)
+
+const (
+	defaultImageCacheDir   = "data/image-cache"
+	defaultImageCacheMB    = 256
+	defaultImageMaxMB      = 10
+	defaultImageMaxAge     = 86400
+	imageCachePrefix       = "fastgpt/images/"
+	maxImageIdLength       = 128
+	imageContentTypeSniffN = 512
+)
+
+var (
+	// ErrInvalidImageId 图片ID格式不正确
+	ErrInvalidImageId = errors.New("图片ID格式不正确")
+	// ErrImageNotFound FastGPT 中不存在该图片
+	ErrImageNotFound = errors.New("图片不存在")
+	// ErrImageTooLarge 图片超过大小上限
+	ErrImageTooLarge = errors.New("图片超过大小限制")
+	// ErrImageType 不是允许的图片类型
+	ErrImageType = errors.New("不支持的图片类型")
+
+	// FastGPT 图片ID为 ObjectId，可带扩展名
+	imageIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9]+)?$`)
+
+	// allowedImageTypes 允许代理的图片类型，SVG 可携带脚本，不在其中
+	allowedImageTypes = map[string]bool{
+		"image/png":  true,
+		"image/jpeg": true,
+		"image/gif":  true,
+		"image/webp": true,
+		"image/bmp":  true,
+	}
+)
+
+// CachedImage 缓存的图片
+type CachedImage struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+}
+
+// imageFetcher 从 FastGPT 获取图片原始内容
+type imageFetcher func(ctx context.Context, imageId string, maxSize int64) ([]byte, error)
+
+// imageEntry LRU 中的一项，ContentType/ETag 为空表示重启后尚未读取过
+type imageEntry struct {
+	id          string
+	size        int64
+	contentType string
+	etag        string
+}
+
+// ImageCache 以 LRU 淘汰、总大小受限的图片缓存，图片内容保存在文件存储中
+type ImageCache struct {
+	storage  fileServer.FileClient
+	fetch    imageFetcher
+	maxTotal int64
+	maxImage int64
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	total int64
+
+	flight syncx.SingleFlight
+}
+
+var (
+	imageCacheOnce sync.Once
+	imageCache     *ImageCache
+	imageCacheErr  error
+)
+
+// Images 全局图片缓存，首次使用时按配置创建
+func Images() (*ImageCache, error) {
+	imageCacheOnce.Do(func() {
+		conf := config.GetConfig().FastGPT.ImageCache
+		var storage fileServer.FileClient
+		if conf.StorageKey != "" {
+			storage = fileServer.Client(conf.StorageKey)
+			if storage == nil {
+				imageCacheErr = fmt.Errorf("image cache storage %s not configured", conf.StorageKey)
+				return
+			}
+		} else {
+			dir := conf.Dir
+			if dir == "" {
+				dir = defaultImageCacheDir
+			}
+			storage, imageCacheErr = fileServer.NewLocal(fileServer.Config{Prefix: dir})
+			if imageCacheErr != nil {
+				return
+			}
+		}
+		imageCache = NewImageCache(storage, megabytes(conf.MaxTotalMB, defaultImageCacheMB), megabytes(conf.MaxImageMB, defaultImageMaxMB), fetchFastGPTImage)
+	})
+	return imageCache, imageCacheErr
+}
+
+// ImageMaxAge 浏览器缓存图片的秒数
+func ImageMaxAge() int {
+	if age := config.GetConfig().FastGPT.ImageCache.MaxAge; age > 0 {
+		return age
+	}
+	return defaultImageMaxAge
+}
+
+// NewImageCache 创建图片缓存，并载入存储中已有的图片
+func NewImageCache(storage fileServer.FileClient, maxTotal, maxImage int64, fetch imageFetcher) *ImageCache {
+	c := &ImageCache{
+		storage:  storage,
+		fetch:    fetch,
+		maxTotal: maxTotal,
+		maxImage: maxImage,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+		flight:   syncx.NewSingleFlight(),
+	}
+	// 目录不存在或存储不支持列举时从空缓存开始
+	if files, err := storage.ReadDir(imageCachePrefix); err == nil {
+		for _, f := range files {
+			if id := path.Base(f.Name); !f.IsDir && ValidImageId(id) {
+				c.add(&imageEntry{id: id, size: f.Size})
+			}
+		}
+	}
+	return c
+}
+
+// ValidImageId 图片ID只允许字母数字、下划线、横线与扩展名，防止拼接出其他路径
+func ValidImageId(imageId string) bool {
+	return len(imageId) <= maxImageIdLength && imageIdPattern.MatchString(imageId)
+}
+
+// Get 获取图片，未缓存时从 FastGPT 下载，相同图片的并发请求只下载一次
+func (c *ImageCache) Get(ctx context.Context, imageId string) (*CachedImage, error) {
+	if !ValidImageId(imageId) {
+		return nil, ErrInvalidImageId
+	}
+	if img, ok := c.load(imageId); ok {
+		return img, nil
+	}
+
+	val, err := c.flight.Do(imageId, func() (any, error) {
+		data, err := c.fetch(context.WithoutCancel(ctx), imageId, c.maxImage)
+		if err != nil {
+			return nil, err
+		}
+		img, err := newCachedImage(data, c.maxImage)
+		if err != nil {
+			return nil, err
+		}
+		c.store(imageId, img)
+		return img, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*CachedImage), nil
+}
+
+// load 从存储读取已缓存的图片，读取失败时移出缓存
+func (c *ImageCache) load(imageId string) (*CachedImage, bool) {
+	c.mu.Lock()
+	el, ok := c.items[imageId]
+	if !ok {
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	entry := *el.Value.(*imageEntry)
+	c.mu.Unlock()
+
+	data, err := c.storage.ReadAll(imageCachePrefix + imageId)
+	if err != nil {
+		c.remove(imageId)
+		return nil, false
+	}
+	if entry.contentType != "" {
+		return &CachedImage{Data: data, ContentType: entry.contentType, ETag: entry.etag}, true
+	}
+
+	// 重启后载入的图片首次读取时补全类型与 ETag
+	img, err := newCachedImage(data, c.maxImage)
+	if err != nil {
+		c.remove(imageId)
+		_ = c.storage.DeleteFile(imageCachePrefix + imageId)
+		return nil, false
+	}
+	c.mu.Lock()
+	if el, ok := c.items[imageId]; ok {
+		e := el.Value.(*imageEntry)
+		e.contentType, e.etag = img.ContentType, img.ETag
+	}
+	c.mu.Unlock()
+	return img, true
+}
+
+// store 写入存储并加入 LRU，写入失败只记日志
+func (c *ImageCache) store(imageId string, img *CachedImage) {
+	if int64(len(img.Data)) > c.maxTotal {
+		return
+	}
+	if _, err := c.storage.UploadFile(img.Data, imageCachePrefix+imageId); err != nil {
+		logx.SystemLogger.Errorf("cache image %s: %v", imageId, err)
+		return
+	}
+	evicted := c.add(&imageEntry{
+		id:          imageId,
+		size:        int64(len(img.Data)),
+		contentType: img.ContentType,
+		etag:        img.ETag,
+	})
+	for _, id := range evicted {
+		if err := c.storage.DeleteFile(imageCachePrefix + id); err != nil {
+			logx.SystemLogger.Errorf("evict cached image %s: %v", id, err)
+		}
+	}
+}
+
+// add 加入 LRU 并返回因超出总大小被淘汰的图片
+func (c *ImageCache) add(entry *imageEntry) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[entry.id]; ok {
+		c.total -= el.Value.(*imageEntry).size
+		c.ll.Remove(el)
+	}
+	c.items[entry.id] = c.ll.PushFront(entry)
+	c.total += entry.size
+
+	var evicted []string
+	for c.total > c.maxTotal {
+		el := c.ll.Back()
+		if el == nil || el.Value.(*imageEntry).id == entry.id {
+			break
+		}
+		e := el.Value.(*imageEntry)
+		c.ll.Remove(el)
+		delete(c.items, e.id)
+		c.total -= e.size
+		evicted = append(evicted, e.id)
+	}
+	return evicted
+}
+
+func (c *ImageCache) remove(imageId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[imageId]; ok {
+		c.total -= el.Value.(*imageEntry).size
+		c.ll.Remove(el)
+		delete(c.items, imageId)
+	}
+}
+
+// newCachedImage 校验大小并按内容识别图片类型，不信任上游返回的 Content-Type
+func newCachedImage(data []byte, maxSize int64) (*CachedImage, error) {
+	if int64(len(data)) > maxSize {
+		return nil, ErrImageTooLarge
+	}
+	sniff := data
+	if len(sniff) > imageContentTypeSniffN {
+		sniff = sniff[:imageContentTypeSniffN]
+	}
+	contentType := http.DetectContentType(sniff)
+	if !allowedImageTypes[contentType] {
+		return nil, ErrImageType
+	}
+	sum := sha256.Sum256(data)
+	return &CachedImage{
+		Data:        data,
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}, nil
+}
+
+// fetchFastGPTImage 从 FastGPT 下载图片，超过 maxSize 时中止
+func fetchFastGPTImage(ctx context.Context, imageId string, maxSize int64) ([]byte, error) {
+	_, client, _ := sharedClients()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.GetConfig().FastGPT.BaseURL+"/system/img/"+imageId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrImageNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetch image: status=%d", resp.StatusCode)
+	case resp.ContentLength > maxSize:
+		return nil, ErrImageTooLarge
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") {
+		return nil, ErrImageType
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrImageTooLarge
+	}
+	return data, nil
+}
+
+func megabytes(mb, def int) int64 {
+	if mb <= 0 {
+		mb = def
+	}
+	return int64(mb) << 20
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"HelpStudent/core/fileServer"
+)
+
+// pngOf 生成以 PNG 文件头开头、总长为 size 的数据
+func pngOf(size int) []byte {
+	data := make([]byte, size)
+	copy(data, "\x89PNG\r\n\x1a\n")
+	return data
+}
+
+func newTestImageCache(t *testing.T, maxTotal int64, fetch imageFetcher) (*ImageCache, fileServer.FileClient) {
+	t.Helper()
+	storage, err := fileServer.NewLocal(fileServer.Config{Prefix: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewImageCache(storage, maxTotal, 1000, fetch), storage
+}
+
+func TestValidImageId(t *testing.T) {
+	for id, want := range map[string]bool{
+		"65f1c2d3e4b5a6978f0e1d2c":     true,
+		"65f1c2d3e4b5a6978f0e1d2c.png": true,
+		"../etc/passwd":                false,
+		"a/b":                          false,
+		"a.b.c":                        false,
+		"":                             false,
+	} {
+		if got := ValidImageId(id); got != want {
+			t.Errorf("ValidImageId(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestImageCacheFetchOnce(t *testing.T) {
+	var calls int32
+	c, _ := newTestImageCache(t, 10000, func(ctx context.Context, id string, max int64) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		return pngOf(100), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(context.Background(), "img1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	img, err := c.Get(context.Background(), "img1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != "image/png" || img.ETag == "" || len(img.Data) != 100 {
+		t.Errorf("unexpected image %s %s %d", img.ContentType, img.ETag, len(img.Data))
+	}
+	// 并发请求可能在首次写入缓存前各自合并，但缓存后不再下载
+	if n := atomic.LoadInt32(&calls); n < 1 || n > 2 {
+		t.Errorf("fetch called %d times", n)
+	}
+}
+
+func TestImageCacheEviction(t *testing.T) {
+	c, storage := newTestImageCache(t, 250, func(ctx context.Context, id string, max int64) ([]byte, error) {
+		return pngOf(100), nil
+	})
+	for _, id := range []string{"a", "b", "a", "c"} {
+		if _, err := c.Get(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// b 最久未使用，被淘汰
+	if _, err := storage.ReadAll(imageCachePrefix + "b"); err == nil {
+		t.Error("evicted image should be deleted from storage")
+	}
+	if _, ok := c.items["b"]; ok || c.total != 200 {
+		t.Errorf("unexpected index: total=%d", c.total)
+	}
+
+	// 重启后从存储恢复索引
+	restored := NewImageCache(storage, 250, 1000, func(ctx context.Context, id string, max int64) ([]byte, error) {
+		return nil, errors.New("should be served from storage")
+	})
+	img, err := restored.Get(context.Background(), "a")
+	if err != nil || img.ContentType != "image/png" {
+		t.Fatalf("restored image: %v", err)
+	}
+}
+
+func TestImageCacheRejects(t *testing.T) {
+	c, _ := newTestImageCache(t, 10000, func(ctx context.Context, id string, max int64) ([]byte, error) {
+		if id == "svg" {
+			return []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), nil
+		}
+		return bytes.Repeat([]byte{0}, 2000), nil
+	})
+	if _, err := c.Get(context.Background(), "svg"); !errors.Is(err, ErrImageType) {
+		t.Errorf("svg: got %v", err)
+	}
+	if _, err := c.Get(context.Background(), "big"); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("big: got %v", err)
+	}
+	if _, err := c.Get(context.Background(), "../x"); !errors.Is(err, ErrInvalidImageId) {
+		t.Errorf("traversal: got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"HelpStudent/config"
+	"HelpStudent/pkg/utils/crypto"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// NewKeyring 根据配置创建 API Key 加密密钥环
+func NewKeyring(conf config.FastGPT) (*crypto.Keyring, error) {
+	keys := make([]crypto.MasterKey, 0, len(conf.MasterKeys))
+	for _, k := range conf.MasterKeys {
+		mk, err := crypto.ParseMasterKey(k.Id, k.Key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, mk)
+	}
+	return crypto.NewKeyring(keys...)
+}
+
+// MaskAPIKey 脱敏 API Key，只保留前缀和末尾 4 位
+func MaskAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	runes := []rune(apiKey)
+	if len(runes) <= 12 {
+		return "****"
+	}
+	return string(runes[:8]) + "****" + string(runes[len(runes)-4:])
+}
+
+// APIKeyFingerprint API Key 指纹，用于比对密钥是否变化
+func APIKeyFingerprint(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])[:12]
+}
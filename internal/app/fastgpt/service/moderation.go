@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/core/stringx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+// ErrContentBlocked 提问中包含禁止的内容
+var ErrContentBlocked = errors.New("提问包含不允许的内容，请修改后重试")
+
+// wordSet 某个科目生效的敏感词
+type wordSet struct {
+	block  stringx.Trie // 提问中出现即拒绝
+	mask   stringx.Trie // 除审核词外的全部敏感词，用于打码
+	review stringx.Trie // 回答中出现时提交人工审核
+	maxLen int          // 最长打码词的字符数
+}
+
+var moderationCache = struct {
+	sync.RWMutex
+	loaded bool
+	words  []model.FastgptSensitiveWord
+	sets   map[string]*wordSet
+}{}
+
+// InvalidateModeration 敏感词变更后清除缓存
+func InvalidateModeration() {
+	moderationCache.Lock()
+	moderationCache.loaded = false
+	moderationCache.words = nil
+	moderationCache.sets = nil
+	moderationCache.Unlock()
+}
+
+// subjectWords 获取科目生效的敏感词（全局 + 科目），首次使用时从数据库加载
+func subjectWords(ctx context.Context, subjectName string) (*wordSet, error) {
+	moderationCache.RLock()
+	if moderationCache.loaded {
+		if set, ok := moderationCache.sets[subjectName]; ok {
+			moderationCache.RUnlock()
+			return set, nil
+		}
+	}
+	moderationCache.RUnlock()
+
+	moderationCache.Lock()
+	defer moderationCache.Unlock()
+	if !moderationCache.loaded {
+		words, err := dao.Moderation.GetAllWords(ctx)
+		if err != nil {
+			return nil, err
+		}
+		moderationCache.words = words
+		moderationCache.sets = map[string]*wordSet{}
+		moderationCache.loaded = true
+	}
+	if set, ok := moderationCache.sets[subjectName]; ok {
+		return set, nil
+	}
+
+	var block, all, review []string
+	maxLen := 0
+	for _, w := range moderationCache.words {
+		if w.SubjectName != "" && w.SubjectName != subjectName {
+			continue
+		}
+		if w.Action == model.ModerationReview {
+			review = append(review, w.Word)
+			continue
+		}
+		all = append(all, w.Word)
+		if w.Action == model.ModerationBlock {
+			block = append(block, w.Word)
+		}
+		if n := utf8.RuneCountInString(w.Word); n > maxLen {
+			maxLen = n
+		}
+	}
+	set := &wordSet{
+		block:  stringx.NewTrie(block),
+		mask:   stringx.NewTrie(all),
+		review: stringx.NewTrie(review),
+		maxLen: maxLen,
+	}
+	moderationCache.sets[subjectName] = set
+	return set, nil
+}
+
+// ModerateInput 检查提问中用户发送的消息：命中禁止词时返回 ErrContentBlocked，命中打码词时就地打码
+func ModerateInput(ctx context.Context, info auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest) error {
+	set, err := subjectWords(ctx, app.AppName)
+	if err != nil {
+		return err
+	}
+	if set.maxLen == 0 {
+		return nil
+	}
+
+	var masked []string
+	for i := range req.Messages {
+		if req.Messages[i].Role != "user" {
+			continue
+		}
+		text := MessageText(req.Messages[i].Content)
+		if keywords := set.block.FindKeywords(text); len(keywords) > 0 {
+			recordHit(ctx, info, app, req.ChatId, model.ModerationInput, model.ModerationBlock, keywords, text)
+			return ErrContentBlocked
+		}
+		keywords := maskMessage(set.mask, &req.Messages[i])
+		if len(keywords) > 0 {
+			masked = append(masked, keywords...)
+		}
+	}
+	if len(masked) > 0 {
+		recordHit(ctx, info, app, req.ChatId, model.ModerationInput, model.ModerationMask, masked, LastUserQuestion(req.Messages))
+	}
+	return nil
+}
+
+// maskMessage 对消息中的文本打码，content 为数组时只处理 text 部分
+func maskMessage(trie stringx.Trie, msg *dto.Message) []string {
+	switch v := msg.Content.(type) {
+	case string:
+		masked, keywords, found := trie.Filter(v)
+		if found {
+			msg.Content = masked
+		}
+		return keywords
+	case []interface{}:
+		var all []string
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok || m["type"] != "text" {
+				continue
+			}
+			text, _ := m["text"].(string)
+			if masked, keywords, found := trie.Filter(text); found {
+				m["text"] = masked
+				all = append(all, keywords...)
+			}
+		}
+		return all
+	}
+	return nil
+}
+
+// OutputFilter 对流式回答打码
+// 每次保留末尾不足一个敏感词长度的内容，与下一段拼接后再检查，保证跨片段的敏感词也能被打码
+type OutputFilter struct {
+	set      *wordSet
+	pending  []rune
+	keywords map[string]struct{}
+}
+
+// NewOutputFilter 创建回答打码器，未配置敏感词时返回 nil，nil 打码器原样输出
+func NewOutputFilter(ctx context.Context, app *model.FastgptApp) (*OutputFilter, error) {
+	set, err := subjectWords(ctx, app.AppName)
+	if err != nil {
+		return nil, err
+	}
+	if set.maxLen == 0 {
+		return nil, nil
+	}
+	return &OutputFilter{set: set, keywords: map[string]struct{}{}}, nil
+}
+
+// Push 输入一段回答，返回可以安全输出的部分
+func (f *OutputFilter) Push(text string) string {
+	if f == nil {
+		return text
+	}
+	buf := string(f.pending) + text
+	masked, keywords, _ := f.set.mask.Filter(buf)
+	for _, k := range keywords {
+		f.keywords[k] = struct{}{}
+	}
+
+	runes := []rune(masked)
+	hold := f.set.maxLen - 1
+	if hold > len(runes) {
+		hold = len(runes)
+	}
+	f.pending = runes[len(runes)-hold:]
+	return string(runes[:len(runes)-hold])
+}
+
+// Flush 输出保留的剩余内容
+func (f *OutputFilter) Flush() string {
+	if f == nil {
+		return ""
+	}
+	rest := string(f.pending)
+	f.pending = nil
+	return rest
+}
+
+// Keywords 已命中的敏感词
+func (f *OutputFilter) Keywords() []string {
+	if f == nil {
+		return nil
+	}
+	keywords := make([]string, 0, len(f.keywords))
+	for k := range f.keywords {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// MaskChunk 对流式响应中一行 data 的 choices.0.delta.content 打码，
+// 非回答内容原样返回；全部内容暂被保留时 ok 为 false，该行不必发送
+func (f *OutputFilter) MaskChunk(data string) (string, bool) {
+	if f == nil || data == "[DONE]" {
+		return data, true
+	}
+	var chunk map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return data, true
+	}
+	delta := chunkDelta(chunk)
+	content, ok := delta["content"].(string)
+	if !ok {
+		return data, true
+	}
+	out := f.Push(content)
+	if out == "" {
+		return "", false
+	}
+	delta["content"] = out
+	body, err := json.Marshal(chunk)
+	if err != nil {
+		return data, true
+	}
+	return string(body), true
+}
+
+// FlushChunk 将保留的剩余内容包装为一行 data，没有剩余内容时 ok 为 false
+func (f *OutputFilter) FlushChunk() (string, bool) {
+	rest := f.Flush()
+	if rest == "" {
+		return "", false
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{
+			map[string]interface{}{"index": 0, "delta": map[string]interface{}{"content": rest}},
+		},
+	})
+	return string(body), true
+}
+
+func chunkDelta(chunk map[string]interface{}) map[string]interface{} {
+	choices, _ := chunk["choices"].([]interface{})
+	if len(choices) == 0 {
+		return nil
+	}
+	choice, _ := choices[0].(map[string]interface{})
+	delta, _ := choice["delta"].(map[string]interface{})
+	return delta
+}
+
+// MaskAnswer 对非流式响应体中的 choices.0.message.content 打码，返回打码后的响应体与命中的敏感词
+func MaskAnswer(ctx context.Context, app *model.FastgptApp, body []byte) ([]byte, []string, error) {
+	set, err := subjectWords(ctx, app.AppName)
+	if err != nil {
+		return body, nil, err
+	}
+	if set.maxLen == 0 {
+		return body, nil, nil
+	}
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return body, nil, nil
+	}
+	choices, _ := resp["choices"].([]interface{})
+	if len(choices) == 0 {
+		return body, nil, nil
+	}
+	choice, _ := choices[0].(map[string]interface{})
+	message, _ := choice["message"].(map[string]interface{})
+	content, ok := message["content"].(string)
+	if !ok {
+		return body, nil, nil
+	}
+	masked, keywords, found := set.mask.Filter(content)
+	if !found {
+		return body, nil, nil
+	}
+	message["content"] = masked
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return body, keywords, err
+	}
+	return out, keywords, nil
+}
+
+// ReviewKeywords 回答中命中的审核词
+func ReviewKeywords(ctx context.Context, subjectName, answer string) ([]string, error) {
+	set, err := subjectWords(ctx, subjectName)
+	if err != nil {
+		return nil, err
+	}
+	return set.review.FindKeywords(answer), nil
+}
+
+// RecordOutputHit 记录回答中的命中
+func RecordOutputHit(ctx context.Context, info auth.Info, app *model.FastgptApp, chatId string, keywords []string, answer string) {
+	if len(keywords) == 0 {
+		return
+	}
+	recordHit(ctx, info, app, chatId, model.ModerationOutput, model.ModerationMask, keywords, answer)
+}
+
+// recordHit 写入命中记录，失败只记日志
+func recordHit(ctx context.Context, info auth.Info, app *model.FastgptApp, chatId, direction, action string, keywords []string, text string) {
+	hit := &model.FastgptModerationHit{
+		AppId:     app.ID,
+		ChatId:    chatId,
+		UserId:    info.Uid,
+		StaffId:   info.StaffId,
+		Direction: direction,
+		Action:    action,
+		Keywords:  strings.Join(keywords, ","),
+		Excerpt:   truncateRunes(text, 500),
+	}
+	if err := dao.Moderation.CreateHit(context.WithoutCancel(ctx), hit); err != nil {
+		logx.SystemLogger.CtxError(ctx, "create moderation hit", err)
+	}
+}
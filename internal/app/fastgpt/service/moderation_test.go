@@ -0,0 +1,34 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"HelpStudent/core/stringx"
+)
+
+func TestOutputFilter_MaskAcrossChunks(t *testing.T) {
+	words := []string{"作弊", "考试答案"}
+	f := &OutputFilter{
+		set:      &wordSet{mask: stringx.NewTrie(words), maxLen: 4},
+		keywords: map[string]struct{}{},
+	}
+
+	var out strings.Builder
+	for _, chunk := range []string{"这是考", "试答", "案，不要作", "弊。"} {
+		out.WriteString(f.Push(chunk))
+	}
+	out.WriteString(f.Flush())
+
+	if got, want := out.String(), "这是****，不要**。"; got != want {
+		t.Errorf("masked output = %q, want %q", got, want)
+	}
+	if got := f.Keywords(); len(got) != 2 {
+		t.Errorf("expected 2 keywords, got %v", got)
+	}
+
+	var nilFilter *OutputFilter
+	if nilFilter.Push("原样") != "原样" {
+		t.Error("nil filter should pass through")
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"HelpStudent/config"
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+var (
+	// ErrChatNotFound 会话不存在
+	ErrChatNotFound = errors.New("会话不存在")
+	// ErrChatForbidden 会话属于其他用户
+	ErrChatForbidden = errors.New("无权访问该会话")
+	// ErrNoShareId 应用未配置分享链接，无法按外链用户区分会话
+	ErrNoShareId = errors.New("该应用未配置分享链接")
+)
+
+// outLinkUidPrefix 区分派生的外链用户标识与健康检查等内部使用的标识
+const outLinkUidPrefix = "u_"
+
+// OutLinkUid 由登录用户派生的外链用户标识，FastGPT 按该标识区分外链会话的归属
+// 只在服务端计算，客户端无法伪造其他用户的标识
+func OutLinkUid(uid string) string {
+	cfg := config.GetConfig()
+	secret := cfg.FastGPT.OutLinkSecret
+	if secret == "" {
+		secret = cfg.Auth.Secret
+	}
+	return deriveOutLinkUid([]byte(secret), uid)
+}
+
+func deriveOutLinkUid(secret []byte, uid string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(uid))
+	return outLinkUidPrefix + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// BindChatIdentity 用服务端派生的外链用户标识覆盖请求中的 outLinkUid 与 customUid，
+// shareId 始终使用应用自己的分享链接，FastGPT 应用未配置分享链接时返回 ErrNoShareId
+func BindChatIdentity(info auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest) error {
+	if app.IsFastGPT() && app.ShareId == "" {
+		return ErrNoShareId
+	}
+	req.OutLinkUid = OutLinkUid(info.Uid)
+	req.CustomUid = req.OutLinkUid
+	req.ShareId = app.ShareId
+	return nil
+}
+
+// CheckChatOwner 检查会话是否属于当前用户，会话以本地记录为准
+// 不存在时返回 ErrChatNotFound，属于其他用户时返回 ErrChatForbidden
+func CheckChatOwner(ctx context.Context, info auth.Info, app *model.FastgptApp, chatId string) error {
+	session, err := dao.ChatRecord.GetSession(ctx, app.ID, chatId)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return ErrChatNotFound
+	}
+	if session.UserId != info.Uid {
+		return ErrChatForbidden
+	}
+	return nil
+}
+
+// CheckChatContinue 对话前校验会话归属，没有 chatId 或本地与 FastGPT 中均无记录的新会话放行
+// 本地没有会话记录、但 FastGPT 中已有记录的会话（开始记录会话之前创建的）无法确认归属，不允许继续
+func CheckChatContinue(ctx context.Context, info auth.Info, app *model.FastgptApp, chatId string) error {
+	if chatId == "" {
+		return nil
+	}
+	if err := CheckChatOwner(ctx, info, app, chatId); !errors.Is(err, ErrChatNotFound) {
+		return err
+	}
+	if !app.IsFastGPT() {
+		return nil
+	}
+	exists, err := upstreamChatExists(ctx, NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey), app, chatId)
+	if err != nil {
+		return fmt.Errorf("check upstream chat %s: %w", chatId, err)
+	}
+	if exists {
+		return ErrChatForbidden
+	}
+	return nil
+}
+
+// upstreamChatExists FastGPT 中该会话是否已有记录
+func upstreamChatExists(ctx context.Context, client *FastGPTClient, app *model.FastgptApp, chatId string) (bool, error) {
+	data, err := fastGPTData(client.ForwardRequest(ctx, http.MethodPost, "/core/chat/getPaginationRecords", map[string]interface{}{
+		"appId":    app.AppId,
+		"chatId":   chatId,
+		"offset":   0,
+		"pageSize": 1,
+	}))
+	if err != nil {
+		return false, err
+	}
+	return data.Get("total").Int() > 0 || len(data.Get("list").Array()) > 0, nil
+}
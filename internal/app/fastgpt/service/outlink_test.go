@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"HelpStudent/internal/app/fastgpt/fake"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+func TestDeriveOutLinkUid(t *testing.T) {
+	secret := []byte("secret")
+	a := deriveOutLinkUid(secret, "user-a")
+	if a != deriveOutLinkUid(secret, "user-a") {
+		t.Fatal("outLinkUid not stable")
+	}
+	if !strings.HasPrefix(a, outLinkUidPrefix) || len(a) != len(outLinkUidPrefix)+32 {
+		t.Errorf("outLinkUid = %q", a)
+	}
+	if strings.Contains(a, "user-a") {
+		t.Errorf("outLinkUid leaks uid: %q", a)
+	}
+	if a == deriveOutLinkUid(secret, "user-b") {
+		t.Error("different users share an outLinkUid")
+	}
+	if a == deriveOutLinkUid([]byte("other"), "user-a") {
+		t.Error("outLinkUid does not depend on secret")
+	}
+}
+
+func TestUpstreamChatExists(t *testing.T) {
+	srv := fake.New()
+	baseURL := srv.Start()
+	defer srv.Close()
+	srv.BindKey("key", "app1")
+	client := NewFastGPTClient(baseURL, "key")
+	app := &model.FastgptApp{AppId: "app1", APIKey: "key"}
+
+	if _, _, err := client.ForwardRequest(context.Background(), http.MethodPost, "/v1/chat/completions", map[string]interface{}{
+		"chatId":   "legacy",
+		"messages": []map[string]string{{"role": "user", "content": "极限"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := upstreamChatExists(context.Background(), client, app, "legacy"); err != nil || !exists {
+		t.Errorf("legacy chat: %v %v", exists, err)
+	}
+	if exists, err := upstreamChatExists(context.Background(), client, app, "new-chat"); err != nil || exists {
+		t.Errorf("new chat: %v %v", exists, err)
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"HelpStudent/config"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+
+	"github.com/tidwall/gjson"
+)
+
+// historyRounds OpenAI 兼容接口没有会话记忆，按 chatId 从本地记录补充的最近问答轮数
+const historyRounds = 10
+
+// Provider 对话后端，响应统一为 OpenAI chat completions 格式
+type Provider interface {
+	// Chat 非流式对话，返回响应体与状态码
+	Chat(ctx context.Context, req *dto.ChatCompletionRequest) ([]byte, int, error)
+	// ChatStream 流式对话，非 200 响应返回 *StatusError
+	ChatStream(ctx context.Context, req *dto.ChatCompletionRequest) (*ChatStreamReader, error)
+}
+
+// StatusError 对话后端返回非 200 状态
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("provider error: status=%d", e.StatusCode)
+}
+
+// NewProvider 按应用配置创建对话后端
+func NewProvider(app *model.FastgptApp) (Provider, error) {
+	switch app.Provider {
+	case "", model.ProviderFastGPT:
+		return &fastGPTProvider{
+			client: NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey),
+		}, nil
+	case model.ProviderOpenAI:
+		if app.BaseURL == "" || app.Model == "" {
+			return nil, fmt.Errorf("app %s: openai provider requires base url and model", app.ID)
+		}
+		return &openAIProvider{
+			app:    app,
+			client: NewFastGPTClient(strings.TrimRight(app.BaseURL, "/"), app.APIKey),
+		}, nil
+	}
+	return nil, fmt.Errorf("app %s: unknown provider %q", app.ID, app.Provider)
+}
+
+// fastGPTProvider 通过 FastGPT 应用对话，请求原样转发
+type fastGPTProvider struct {
+	client *FastGPTClient
+}
+
+func (p *fastGPTProvider) Chat(ctx context.Context, req *dto.ChatCompletionRequest) ([]byte, int, error) {
+	return p.client.ForwardRequest(ctx, http.MethodPost, "/v1/chat/completions", req)
+}
+
+func (p *fastGPTProvider) ChatStream(ctx context.Context, req *dto.ChatCompletionRequest) (*ChatStreamReader, error) {
+	resp, err := p.client.ForwardStreamRequest(ctx, http.MethodPost, "/v1/chat/completions", req)
+	if err != nil {
+		return nil, err
+	}
+	return newChatStreamReader(resp)
+}
+
+// openAIProvider 通过 OpenAI 兼容接口对话
+// 只发送标准字段，会话历史由本地问答记录补充
+type openAIProvider struct {
+	app    *model.FastgptApp
+	client *FastGPTClient
+}
+
+// openAIRequest OpenAI chat completions 请求
+type openAIRequest struct {
+	Model    string        `json:"model"`
+	Messages []dto.Message `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+func (p *openAIProvider) Chat(ctx context.Context, req *dto.ChatCompletionRequest) ([]byte, int, error) {
+	body, err := p.buildRequest(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	return p.client.ForwardRequest(ctx, http.MethodPost, "/chat/completions", body)
+}
+
+func (p *openAIProvider) ChatStream(ctx context.Context, req *dto.ChatCompletionRequest) (*ChatStreamReader, error) {
+	body, err := p.buildRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.ForwardStreamRequest(ctx, http.MethodPost, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	return newChatStreamReader(resp)
+}
+
+func (p *openAIProvider) buildRequest(ctx context.Context, req *dto.ChatCompletionRequest) (*openAIRequest, error) {
+	messages, err := withHistory(ctx, p.app, req)
+	if err != nil {
+		return nil, err
+	}
+	return &openAIRequest{
+		Model:    p.app.Model,
+		Messages: messages,
+		Stream:   req.Stream,
+	}, nil
+}
+
+// withHistory 客户端只发送了本轮提问时，在前面补上本地记录的最近问答
+func withHistory(ctx context.Context, app *model.FastgptApp, req *dto.ChatCompletionRequest) ([]dto.Message, error) {
+	if req.ChatId == "" || len(req.Messages) != 1 {
+		return req.Messages, nil
+	}
+	history, err := dao.ChatRecord.RecentSuccessMessages(ctx, app.ID, req.ChatId, historyRounds)
+	if err != nil {
+		return nil, fmt.Errorf("load chat history: %w", err)
+	}
+	messages := make([]dto.Message, 0, len(history)*2+1)
+	for _, m := range history {
+		messages = append(messages,
+			dto.Message{Role: "user", Content: m.Question},
+			dto.Message{Role: "assistant", Content: m.Answer},
+		)
+	}
+	return append(messages, req.Messages...), nil
+}
+
+// ChatStreamReader 读取流式响应中的 data 行
+// 兼容 "data:" 后有无空格的写法，忽略注释与 event 行
+type ChatStreamReader struct {
+	resp    *http.Response
+	scanner *bufio.Scanner
+	done    bool
+
+	// onClose 关闭时回调已读取的完整回答，complete 表示读到了结束标记且未出错
+	onClose  func(answer string, complete bool)
+	answer   strings.Builder
+	complete bool
+	closed   bool
+}
+
+func newChatStreamReader(resp *http.Response) (*ChatStreamReader, error) {
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: body}
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	return &ChatStreamReader{resp: resp, scanner: scanner}, nil
+}
+
+// Recv 返回下一行 data 的内容，[DONE] 之后返回 io.EOF
+// 未收到 [DONE] 连接就已结束时返回 io.ErrUnexpectedEOF，回答可能不完整
+func (r *ChatStreamReader) Recv() (string, error) {
+	if r.done {
+		return "", io.EOF
+	}
+	for r.scanner.Scan() {
+		line := strings.TrimSpace(r.scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			r.done = true
+			r.complete = true
+		} else if r.onClose != nil {
+			r.answer.WriteString(gjson.Get(data, "choices.0.delta.content").String())
+		}
+		return data, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.ErrUnexpectedEOF
+}
+
+// Close 关闭响应
+func (r *ChatStreamReader) Close() error {
+	if r.onClose != nil && !r.closed {
+		r.closed = true
+		r.onClose(r.answer.String(), r.complete)
+	}
+	return r.resp.Body.Close()
+}
+
+// IsStatusError 判断是否为后端返回的非 200 状态
+func IsStatusError(err error) (*StatusError, bool) {
+	var se *StatusError
+	ok := errors.As(err, &se)
+	return se, ok
+}
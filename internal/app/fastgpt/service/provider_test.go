@@ -0,0 +1,49 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestChatStreamReader_Normalize(t *testing.T) {
+	body := ": keep-alive\n\nevent: message\ndata:{\"a\":1}\n\ndata: {\"b\":2}\n\n"
+	reader, err := newChatStreamReader(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	complete := true
+	reader.onClose = func(answer string, c bool) { complete = c }
+	for {
+		data, err := reader.Recv()
+		if err != nil {
+			// 后端没有发送 [DONE] 就断开，回答不完整
+			if err != io.ErrUnexpectedEOF {
+				t.Fatalf("Recv error = %v, want io.ErrUnexpectedEOF", err)
+			}
+			break
+		}
+		got = append(got, data)
+	}
+	if want := []string{`{"a":1}`, `{"b":2}`}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Recv = %v, want %v", got, want)
+	}
+	reader.Close()
+	if complete {
+		t.Error("stream without [DONE] reported as complete")
+	}
+
+	_, err = newChatStreamReader(&http.Response{
+		StatusCode: http.StatusBadGateway,
+		Body:       io.NopCloser(strings.NewReader("bad gateway")),
+	})
+	if se, ok := IsStatusError(err); !ok || se.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected StatusError, got %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueueMaxWaiting = 100
+	defaultQueueMaxWait    = 2 * time.Minute
+	// defaultStreamDuration 尚无完成的流式对话时用于估算等待时间
+	defaultStreamDuration = 30 * time.Second
+	// streamDurationWeight 平均对话时长的指数移动平均权重
+	streamDurationWeight = 0.2
+)
+
+// QueueTicket 一次排队请求，Limit 不大于 0 时不限制
+type QueueTicket struct {
+	AppId      string
+	UserId     string
+	Limit      int
+	MaxWaiting int
+	MaxWait    time.Duration
+}
+
+// QueueStatus 排队位置，Position 从 1 开始
+type QueueStatus struct {
+	Position      int
+	EstimatedWait time.Duration
+}
+
+// QueueStat 应用的排队统计，计数为本实例启动以来的累计值
+type QueueStat struct {
+	AppId       string
+	Limit       int
+	Active      int
+	Waiting     int
+	PeakWaiting int
+	Admitted    int64 // 放行的请求数，含无需排队的
+	Queued      int64 // 排过队的请求数
+	TimedOut    int64
+	Rejected    int64 // 排队已满被拒绝
+	Cancelled   int64 // 排队期间客户端离开
+	AvgWait     time.Duration
+	AvgDuration time.Duration
+}
+
+// AdmissionQueue 按应用限制同时进行的流式对话数，超出时排队
+// 排队按轮次放行，每个用户每轮最多一个请求，同一轮内按到达顺序，单个用户的多次提问不会挤占其他人
+// 名额在本实例内计数，多实例部署时每个实例分别限制
+type AdmissionQueue struct {
+	mu   sync.Mutex
+	apps map[string]*appQueue
+}
+
+// StreamQueue 全局的流式对话排队
+var StreamQueue = NewAdmissionQueue()
+
+// NewAdmissionQueue 创建排队
+func NewAdmissionQueue() *AdmissionQueue {
+	return &AdmissionQueue{apps: map[string]*appQueue{}}
+}
+
+type appQueue struct {
+	limit       int
+	active      int
+	waiters     []*queueWaiter // 按 (round, seq) 排序
+	seq         uint64
+	round       int // 最近放行的请求所在轮次
+	avgDuration time.Duration
+	stat        QueueStat
+	totalWait   time.Duration
+}
+
+type queueWaiter struct {
+	userId   string
+	round    int // 放行轮次，同一用户的请求依次排在之后的轮次
+	seq      uint64
+	admitted chan struct{}
+	changed  chan struct{} // 位置可能变化，容量为 1
+}
+
+func (h *AdmissionQueue) app(appId string) *appQueue {
+	q := h.apps[appId]
+	if q == nil {
+		q = &appQueue{avgDuration: defaultStreamDuration, stat: QueueStat{AppId: appId}}
+		h.apps[appId] = q
+	}
+	return q
+}
+
+// Acquire 获取名额，需要排队时每次位置变化调用 onStatus；返回的 release 在对话结束后调用
+func (h *AdmissionQueue) Acquire(ctx context.Context, t QueueTicket, onStatus func(QueueStatus)) (release func(), err error) {
+	h.mu.Lock()
+	q := h.app(t.AppId)
+	q.limit = t.Limit
+	if t.Limit <= 0 || (len(q.waiters) == 0 && q.active < t.Limit) {
+		q.active++
+		q.stat.Admitted++
+		h.mu.Unlock()
+		return h.releaseFunc(t.AppId), nil
+	}
+	if len(q.waiters) >= t.MaxWaiting {
+		q.stat.Rejected++
+		h.mu.Unlock()
+		return nil, &QuotaError{Message: "当前排队人数过多，请稍后再试", RetryAfter: 5 * time.Second}
+	}
+	w := q.enqueue(t.UserId)
+	q.stat.Queued++
+	h.mu.Unlock()
+
+	start := time.Now()
+	timer := time.NewTimer(t.MaxWait)
+	defer timer.Stop()
+	last := QueueStatus{}
+	for {
+		h.mu.Lock()
+		status, waiting := q.status(w)
+		h.mu.Unlock()
+		if waiting && status != last && onStatus != nil {
+			onStatus(status)
+			last = status
+		}
+
+		select {
+		case <-w.admitted:
+			h.mu.Lock()
+			q.totalWait += time.Since(start)
+			h.mu.Unlock()
+			return h.releaseFunc(t.AppId), nil
+		case <-w.changed:
+		case <-timer.C:
+			if h.leave(q, w, func() { q.stat.TimedOut++ }) {
+				return nil, &QuotaError{Message: "排队超时，请稍后再试", RetryAfter: 5 * time.Second}
+			}
+			return h.releaseFunc(t.AppId), nil
+		case <-ctx.Done():
+			if h.leave(q, w, func() { q.stat.Cancelled++ }) {
+				return nil, ctx.Err()
+			}
+			// 离开的同时被放行，归还名额
+			h.mu.Lock()
+			q.active--
+			q.admitNext()
+			h.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// leave 离开排队，已被放行时返回 false
+func (h *AdmissionQueue) leave(q *appQueue, w *queueWaiter, count func()) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	select {
+	case <-w.admitted:
+		return false
+	default:
+	}
+	q.remove(w)
+	count()
+	q.notifyAll()
+	return true
+}
+
+func (h *AdmissionQueue) releaseFunc(appId string) func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			q := h.apps[appId]
+			q.active--
+			d := time.Since(start)
+			q.avgDuration = time.Duration(float64(q.avgDuration)*(1-streamDurationWeight) + float64(d)*streamDurationWeight)
+			q.admitNext()
+		})
+	}
+}
+
+// Stats 各应用的排队统计，按应用 ID 排序
+func (h *AdmissionQueue) Stats() []QueueStat {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := make([]QueueStat, 0, len(h.apps))
+	for _, q := range h.apps {
+		s := q.stat
+		s.Limit, s.Active, s.Waiting, s.AvgDuration = q.limit, q.active, len(q.waiters), q.avgDuration
+		if waited := s.Queued - s.TimedOut - s.Cancelled - int64(len(q.waiters)); waited > 0 {
+			s.AvgWait = q.totalWait / time.Duration(waited)
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].AppId < stats[j].AppId })
+	return stats
+}
+
+func (q *appQueue) enqueue(userId string) *queueWaiter {
+	q.seq++
+	w := &queueWaiter{
+		userId:   userId,
+		seq:      q.seq,
+		admitted: make(chan struct{}),
+		changed:  make(chan struct{}, 1),
+	}
+	w.round = q.round
+	for _, other := range q.waiters {
+		if other.userId == userId && other.round >= w.round {
+			w.round = other.round + 1
+		}
+	}
+	// 插在第一个轮次更大的请求之前
+	i := sort.Search(len(q.waiters), func(i int) bool { return q.waiters[i].round > w.round })
+	q.waiters = append(q.waiters, nil)
+	copy(q.waiters[i+1:], q.waiters[i:])
+	q.waiters[i] = w
+	if len(q.waiters) > q.stat.PeakWaiting {
+		q.stat.PeakWaiting = len(q.waiters)
+	}
+	return w
+}
+
+// remove 移出排队
+func (q *appQueue) remove(w *queueWaiter) {
+	for i, other := range q.waiters {
+		if other == w {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// admitNext 有空闲名额时按顺序放行
+func (q *appQueue) admitNext() {
+	admitted := false
+	for len(q.waiters) > 0 && (q.limit <= 0 || q.active < q.limit) {
+		w := q.waiters[0]
+		q.remove(w)
+		q.round = w.round
+		q.active++
+		q.stat.Admitted++
+		close(w.admitted)
+		admitted = true
+	}
+	if admitted {
+		q.notifyAll()
+	}
+}
+
+func (q *appQueue) notifyAll() {
+	for _, w := range q.waiters {
+		select {
+		case w.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// status 排队位置与预计等待时间，已不在排队中时 waiting 为 false
+func (q *appQueue) status(w *queueWaiter) (status QueueStatus, waiting bool) {
+	for i, other := range q.waiters {
+		if other == w {
+			limit := q.limit
+			if limit <= 0 {
+				limit = 1
+			}
+			rounds := (i + limit) / limit
+			wait := (time.Duration(rounds) * q.avgDuration).Round(time.Second)
+			return QueueStatus{Position: i + 1, EstimatedWait: wait}, true
+		}
+	}
+	return QueueStatus{}, false
+}
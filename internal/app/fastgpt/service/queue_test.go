@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued 等待应用的排队人数达到 n
+func waitQueued(t *testing.T, h *AdmissionQueue, appId string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		h.mu.Lock()
+		waiting := len(h.app(appId).waiters)
+		h.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("queue length did not reach %d", n)
+}
+
+func TestAdmissionQueue_FairOrder(t *testing.T) {
+	h := NewAdmissionQueue()
+	ticket := func(user string) QueueTicket {
+		return QueueTicket{AppId: "app1", UserId: user, Limit: 1, MaxWaiting: 10, MaxWait: time.Second}
+	}
+	release, err := h.Acquire(context.Background(), ticket("holder"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a 连续提问三次，b、c 各一次：放行顺序应为 a b c a a
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	users := []string{"a", "a", "b", "a", "c"}
+	for i, user := range users {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			rel, err := h.Acquire(context.Background(), ticket(user), nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, user)
+			mu.Unlock()
+			rel()
+		}(user)
+		waitQueued(t, h, "app1", i+1)
+	}
+
+	release()
+	wg.Wait()
+	want := []string{"a", "b", "c", "a", "a"}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("admission order = %v, want %v", order, want)
+		}
+	}
+
+	stat := h.Stats()[0]
+	if stat.Queued != 5 || stat.Admitted != 6 || stat.PeakWaiting != 5 || stat.Active != 0 || stat.Waiting != 0 {
+		t.Errorf("unexpected stats: %+v", stat)
+	}
+}
+
+func TestAdmissionQueue_Position(t *testing.T) {
+	h := NewAdmissionQueue()
+	ticket := QueueTicket{AppId: "app1", UserId: "u1", Limit: 1, MaxWaiting: 1, MaxWait: time.Second}
+	release, _ := h.Acquire(context.Background(), ticket, nil)
+
+	statuses := make(chan QueueStatus, 10)
+	done := make(chan error, 1)
+	go func() {
+		rel, err := h.Acquire(context.Background(), QueueTicket{AppId: "app1", UserId: "u2", Limit: 1, MaxWaiting: 1, MaxWait: time.Second}, func(s QueueStatus) {
+			statuses <- s
+		})
+		if rel != nil {
+			rel()
+		}
+		done <- err
+	}()
+	waitQueued(t, h, "app1", 1)
+
+	s := <-statuses
+	if s.Position != 1 || s.EstimatedWait != defaultStreamDuration {
+		t.Errorf("status = %+v", s)
+	}
+
+	// 排队已满
+	_, err := h.Acquire(context.Background(), QueueTicket{AppId: "app1", UserId: "u3", Limit: 1, MaxWaiting: 1, MaxWait: time.Second}, nil)
+	var qe *QuotaError
+	if !errors.As(err, &qe) {
+		t.Errorf("full queue should be rejected, got %v", err)
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if stat := h.Stats()[0]; stat.Rejected != 1 {
+		t.Errorf("rejected = %d", stat.Rejected)
+	}
+}
+
+func TestAdmissionQueue_TimeoutAndCancel(t *testing.T) {
+	h := NewAdmissionQueue()
+	ticket := QueueTicket{AppId: "app1", UserId: "u1", Limit: 1, MaxWaiting: 10, MaxWait: 20 * time.Millisecond}
+	release, _ := h.Acquire(context.Background(), ticket, nil)
+	defer release()
+
+	_, err := h.Acquire(context.Background(), ticket, nil)
+	var qe *QuotaError
+	if !errors.As(err, &qe) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticket.MaxWait = time.Second
+	go func() {
+		waitQueued(t, h, "app1", 1)
+		cancel()
+	}()
+	if _, err := h.Acquire(ctx, ticket, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancel, got %v", err)
+	}
+
+	stat := h.Stats()[0]
+	if stat.TimedOut != 1 || stat.Cancelled != 1 || stat.Waiting != 0 || stat.Active != 1 {
+		t.Errorf("unexpected stats: %+v", stat)
+	}
+}
+
+func TestAdmissionQueue_Unlimited(t *testing.T) {
+	h := NewAdmissionQueue()
+	for i := 0; i < 3; i++ {
+		if _, err := h.Acquire(context.Background(), QueueTicket{AppId: "app1", UserId: "u1"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stat := h.Stats()[0]; stat.Active != 3 || stat.Queued != 0 {
+		t.Errorf("unexpected stats: %+v", stat)
+	}
+}
+
+func TestAdmissionQueue_NewcomerJoinsCurrentRound(t *testing.T) {
+	q := &appQueue{}
+	a1, a2, b := q.enqueue("a"), q.enqueue("a"), q.enqueue("b")
+	// 第 0 轮放行 a1 后，b 仍在第 0 轮，新来的 c 从当前轮次开始排在 b 之后、a2 之前
+	q.remove(a1)
+	q.round = a1.round
+	c := q.enqueue("c")
+	if got := []*queueWaiter{b, c, a2}; q.waiters[0] != got[0] || q.waiters[1] != got[1] || q.waiters[2] != got[2] {
+		t.Errorf("unexpected order: rounds %d %d %d", q.waiters[0].round, q.waiters[1].round, q.waiters[2].round)
+	}
+
+	// 进入第 1 轮后新来的 d 排在同一轮先到的 a2 之后
+	q.remove(b)
+	q.remove(c)
+	q.round = 1
+	d := q.enqueue("d")
+	if q.waiters[0] != a2 || q.waiters[1] != d || d.round != 1 {
+		t.Errorf("newcomers should queue in arrival order within the current round")
+	}
+}
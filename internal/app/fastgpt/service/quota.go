@@ -0,0 +1,198 @@
+package service
+
+import (
+	"HelpStudent/config"
+	"HelpStudent/core/auth"
+	"HelpStudent/core/cache"
+	"HelpStudent/core/store/rds"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QuotaError 超出对话配额
+type QuotaError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return e.Message
+}
+
+// RetryAfterSeconds 建议的重试等待秒数，至少为 1
+func (e *QuotaError) RetryAfterSeconds() int {
+	sec := int((e.RetryAfter + time.Second - 1) / time.Second)
+	if sec < 1 {
+		return 1
+	}
+	return sec
+}
+
+// EffectiveQuota 应用实际生效的配额
+func EffectiveQuota(ctx context.Context, appId string) (config.Quota, error) {
+	quota := config.GetConfig().FastGPT.Quota
+	override, err := dao.AppQuota.GetByAppId(ctx, appId)
+	if err != nil {
+		return quota, err
+	}
+	if override == nil {
+		return quota, nil
+	}
+	if override.UserRPM != nil {
+		quota.UserRPM = *override.UserRPM
+	}
+	if override.UserDailyMessages != nil {
+		quota.UserDailyMessages = *override.UserDailyMessages
+	}
+	if override.AppConcurrency != nil {
+		quota.AppConcurrency = *override.AppConcurrency
+	}
+	if override.AppStreams != nil {
+		quota.AppStreams = *override.AppStreams
+	}
+	return quota, nil
+}
+
+// AcquireChatQuota 检查并占用一次对话配额，成功时返回的 release 需在对话结束后调用以释放并发名额
+// 并发名额不足时退回已计入的请求数
+func AcquireChatQuota(ctx context.Context, info auth.Info, app *model.FastgptApp) (release func(), err error) {
+	quota, err := EffectiveQuota(ctx, app.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get app quota: %w", err)
+	}
+	refund, err := checkRateQuota(info, quota, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	release, err = acquireAppConcurrency(app, quota)
+	if err != nil {
+		refund()
+		return nil, err
+	}
+	return release, nil
+}
+
+// AcquireStreamQuota 检查请求数配额后在应用的流式对话数达到上限时排队等待，排队期间位置变化时调用 onQueue；
+// ctx 取消、等待超时或排队已满时返回错误，并退回已计入的请求数
+// 流式对话的并发只由排队限制：上限为 AppStreams，未设置时为 AppConcurrency，不再占用 AppConcurrency 的名额
+func AcquireStreamQuota(ctx context.Context, info auth.Info, app *model.FastgptApp, onQueue func(QueueStatus)) (release func(), err error) {
+	quota, err := EffectiveQuota(ctx, app.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get app quota: %w", err)
+	}
+	refund, err := checkRateQuota(info, quota, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.GetConfig().FastGPT.Queue
+	maxWait := time.Duration(cfg.MaxWaitSeconds) * time.Second
+	if maxWait <= 0 {
+		maxWait = defaultQueueMaxWait
+	}
+	maxWaiting := cfg.MaxWaiting
+	if maxWaiting <= 0 {
+		maxWaiting = defaultQueueMaxWaiting
+	}
+	leave, err := StreamQueue.Acquire(ctx, QueueTicket{
+		AppId:      app.ID,
+		UserId:     info.Uid,
+		Limit:      streamLimit(quota),
+		MaxWaiting: maxWaiting,
+		MaxWait:    maxWait,
+	}, onQueue)
+	if err != nil {
+		refund()
+		return nil, err
+	}
+	return leave, nil
+}
+
+// streamLimit 应用同时进行的流式对话数上限，不大于 0 时不限制
+func streamLimit(quota config.Quota) int {
+	if quota.AppStreams > 0 {
+		return quota.AppStreams
+	}
+	return quota.AppConcurrency
+}
+
+// checkRateQuota 检查并计入每分钟与每日的请求数，返回的 refund 用于请求最终未被放行时退回计数
+// 超出限制时已计入的计数会先退回
+func checkRateQuota(info auth.Info, quota config.Quota, now time.Time) (refund func(), err error) {
+	var keys []string
+	var once sync.Once
+	refund = func() {
+		once.Do(func() {
+			for _, key := range keys {
+				// 计数窗口可能已在排队期间过期，不留下没有过期时间的负数计数
+				if n, _ := cache.IncrBy(key, -1); n <= 0 {
+					_, _ = cache.Del(key)
+				}
+			}
+		})
+	}
+
+	if quota.UserRPM > 0 {
+		window := now.Truncate(time.Minute)
+		key := rds.Key("fastgpt", "quota", "rpm", info.Uid, strconv.FormatInt(window.Unix(), 10))
+		keys = append(keys, key)
+		if !incrWithin(key, quota.UserRPM, 60) {
+			refund()
+			return nil, &QuotaError{
+				Message:    "请求过于频繁，请稍后再试",
+				RetryAfter: window.Add(time.Minute).Sub(now),
+			}
+		}
+	}
+
+	if quota.UserDailyMessages > 0 {
+		day := now.Format("20060102")
+		key := rds.Key("fastgpt", "quota", "daily", info.Uid, day)
+		keys = append(keys, key)
+		if !incrWithin(key, quota.UserDailyMessages, 24*60*60) {
+			refund()
+			y, m, d := now.Date()
+			tomorrow := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+			return nil, &QuotaError{
+				Message:    "今日提问次数已用完，请明天再试",
+				RetryAfter: tomorrow.Sub(now),
+			}
+		}
+	}
+	return refund, nil
+}
+
+// acquireAppConcurrency 占用应用的并发名额，名额在本实例内计数，多实例部署时每个实例分别限制
+func acquireAppConcurrency(app *model.FastgptApp, quota config.Quota) (func(), error) {
+	if quota.AppConcurrency <= 0 {
+		return func() {}, nil
+	}
+	key := rds.Key("fastgpt", "quota", "concurrency", app.ID)
+	if n, _ := cache.IncrBy(key, 1); n > int64(quota.AppConcurrency) {
+		_, _ = cache.IncrBy(key, -1)
+		return nil, &QuotaError{
+			Message:    "当前提问人数过多，请稍后再试",
+			RetryAfter: 5 * time.Second,
+		}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			_, _ = cache.IncrBy(key, -1)
+		})
+	}, nil
+}
+
+// incrWithin 计数加一并判断是否仍在限制内，首次计数时设置过期时间
+func incrWithin(key string, limit int, expireSeconds int) bool {
+	n, _ := cache.Incr(key)
+	if n == 1 {
+		_, _ = cache.Expire(key, expireSeconds)
+	}
+	return n <= int64(limit)
+}
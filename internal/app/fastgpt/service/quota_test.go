@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"HelpStudent/config"
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/fastgpttest"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+func TestAcquireStreamQuota(t *testing.T) {
+	env := fastgpttest.Setup(t)
+	config.GetConfig().FastGPT.Quota = config.Quota{UserDailyMessages: 2, AppConcurrency: 1}
+	app := env.CreateApp(t, &model.FastgptApp{AppName: "高等数学", AppId: "app1"})
+	// 缓存为进程内全局，用户以应用 ID 区分，避免与其他测试的计数冲突
+	holder := auth.Info{Uid: "holder-" + app.ID}
+	student := auth.Info{Uid: "student-" + app.ID}
+
+	// AppStreams 未设置时流式对话上限为 AppConcurrency
+	release, err := AcquireStreamQuota(context.Background(), holder, app, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 流式对话不占用 AppConcurrency 名额，非流式对话仍可进行
+	releaseChat, err := AcquireChatQuota(context.Background(), auth.Info{Uid: "chat-" + app.ID}, app)
+	if err != nil {
+		t.Fatalf("chat blocked by stream: %v", err)
+	}
+	releaseChat()
+
+	// 排队期间离开不计入每日提问数
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := AcquireStreamQuota(ctx, student, app, nil)
+		cancel()
+		if err == nil {
+			t.Fatal("stream admitted beyond limit")
+		}
+		var qe *QuotaError
+		if errors.As(err, &qe) {
+			t.Fatalf("queued request consumed daily quota: %v", err)
+		}
+	}
+	release()
+
+	for i := 0; i < 2; i++ {
+		release, err := AcquireStreamQuota(context.Background(), student, app, nil)
+		if err != nil {
+			t.Fatalf("admit %d: %v", i, err)
+		}
+		release()
+	}
+	var qe *QuotaError
+	if _, err := AcquireStreamQuota(context.Background(), student, app, nil); !errors.As(err, &qe) {
+		t.Errorf("daily quota not enforced: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"HelpStudent/config"
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/model"
+	managerDAO "HelpStudent/internal/app/managers/dao"
+
+	"github.com/tidwall/gjson"
+	"gorm.io/gorm"
+)
+
+// ErrCollectionForbidden 修正回答要写入的集合不属于应用的知识库
+var ErrCollectionForbidden = errors.New("该集合不属于应用的知识库")
+
+// quoteScore 一次回答的知识库检索情况
+type quoteScore struct {
+	searched bool     // 是否检索了知识库
+	best     *float64 // 最高引用相似度，没有引用时为 nil
+}
+
+// merge 合并一组节点运行详情（responseData / flowResponses）中的检索结果
+func (s *quoteScore) merge(nodes gjson.Result) {
+	for _, node := range nodes.Array() {
+		if node.Get("moduleType").String() != "datasetSearchNode" && !node.Get("quoteList").Exists() {
+			continue
+		}
+		s.searched = true
+		for _, quote := range node.Get("quoteList").Array() {
+			score, ok := quoteSimilarity(quote.Get("score"))
+			if ok && (s.best == nil || score > *s.best) {
+				s.best = &score
+			}
+		}
+	}
+}
+
+// lowSimilarity 检索了知识库但没有足够相似的引用
+func (s *quoteScore) lowSimilarity(min float64) bool {
+	return min > 0 && s.searched && (s.best == nil || *s.best < min)
+}
+
+// quoteSimilarity 引用的相似度，新版为 [{type,value}] 时优先取 embedding，旧版为数字
+func quoteSimilarity(score gjson.Result) (float64, bool) {
+	if score.Type == gjson.Number {
+		return score.Float(), true
+	}
+	items := score.Array()
+	for _, item := range items {
+		if item.Get("type").String() == "embedding" {
+			return item.Get("value").Float(), true
+		}
+	}
+	if len(items) > 0 {
+		return items[0].Get("value").Float(), true
+	}
+	return 0, false
+}
+
+// flagTranscript 回答结束后按审核词与引用相似度判断是否提交审核，失败只记日志
+func flagTranscript(ctx context.Context, msg *model.FastgptChatMessage, subject, answer string, score quoteScore) {
+	base := func() *model.FastgptReview {
+		return &model.FastgptReview{
+			AppId:       msg.AppId,
+			FlagKey:     "msg:" + msg.ID,
+			SubjectName: subject,
+			ChatId:      msg.ChatId,
+			MessageId:   msg.ID,
+			UserId:      msg.UserId,
+			StaffId:     msg.StaffId,
+			Question:    msg.Question,
+			Answer:      answer,
+			QuoteScore:  score.best,
+		}
+	}
+
+	keywords, err := ReviewKeywords(ctx, subject, answer)
+	if err != nil {
+		logx.SystemLogger.Errorf("review keywords for %s: %v", msg.ID, err)
+	}
+	if len(keywords) > 0 {
+		reason := "回答命中审核词：" + strings.Join(keywords, "、")
+		if err := flagAnswer(ctx, base(), model.ReviewSourceKeyword, reason); err != nil {
+			logx.SystemLogger.Errorf("flag answer %s: %v", msg.ID, err)
+		}
+	}
+
+	if min := config.GetConfig().FastGPT.Review.MinQuoteScore; score.lowSimilarity(min) {
+		reason := "检索知识库后没有引用"
+		if score.best != nil {
+			reason = fmt.Sprintf("最高引用相似度 %.2f 低于 %.2f", *score.best, min)
+		}
+		if err := flagAnswer(ctx, base(), model.ReviewSourceLowSimilarity, reason); err != nil {
+			logx.SystemLogger.Errorf("flag answer %s: %v", msg.ID, err)
+		}
+	}
+}
+
+// FlagStudentFeedback 学生点踩的回答提交审核，FastGPT 应用会补全提问与回答内容
+func FlagStudentFeedback(ctx context.Context, app *model.FastgptApp, fb *model.FastgptAnswerFeedback) error {
+	r := &model.FastgptReview{
+		AppId:       app.ID,
+		FlagKey:     "data:" + fb.ChatId + "/" + fb.DataId,
+		SubjectName: app.AppName,
+		ChatId:      fb.ChatId,
+		DataId:      fb.DataId,
+		UserId:      fb.UserId,
+		StaffId:     fb.StaffId,
+	}
+	if app.IsFastGPT() {
+		client := NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey)
+		records, err := fetchRecords(ctx, client, app, fb.ChatId)
+		if err != nil {
+			logx.SystemLogger.Errorf("fetch records for review %s: %v", fb.DataId, err)
+		}
+		r.Question, r.Answer = findAnswer(records, fb.DataId)
+	}
+	reason := "学生点踩"
+	if fb.Reason != "" {
+		reason += "：" + fb.Reason
+	}
+	return flagAnswer(ctx, r, model.ReviewSourceStudent, reason)
+}
+
+// findAnswer 按 dataId 找到回答及其前一条提问
+func findAnswer(records []gjson.Result, dataId string) (question, answer string) {
+	for i, rec := range records {
+		if rec.Get("dataId").String() != dataId {
+			continue
+		}
+		answer = recordText(rec.Get("value"))
+		for j := i - 1; j >= 0; j-- {
+			if records[j].Get("obj").String() == "Human" {
+				question = recordText(records[j].Get("value"))
+				break
+			}
+		}
+		return question, answer
+	}
+	return "", ""
+}
+
+// flagAnswer 创建审核项，同一回答已被标记时合并来源与原因
+func flagAnswer(ctx context.Context, r *model.FastgptReview, source, reason string) error {
+	r.Status = model.ReviewPending
+	r.Sources = source
+	r.Reason = reason
+	r.FlagCount = 1
+	return dao.Review.Flag(context.WithoutCancel(ctx), r, func(existing *model.FastgptReview) map[string]interface{} {
+		return mergeFlag(existing, r)
+	})
+}
+
+// mergeFlag 再次标记时需要更新的字段
+func mergeFlag(existing, incoming *model.FastgptReview) map[string]interface{} {
+	updates := map[string]interface{}{"flag_count": gorm.Expr("flag_count + 1")}
+	if !slices.Contains(strings.Split(existing.Sources, ","), incoming.Sources) {
+		updates["sources"] = existing.Sources + "," + incoming.Sources
+	}
+	if incoming.Reason != "" && !strings.Contains(existing.Reason, incoming.Reason) {
+		updates["reason"] = existing.Reason + "\n" + incoming.Reason
+	}
+	fill := map[string][2]string{
+		"question":   {existing.Question, incoming.Question},
+		"answer":     {existing.Answer, incoming.Answer},
+		"data_id":    {existing.DataId, incoming.DataId},
+		"message_id": {existing.MessageId, incoming.MessageId},
+	}
+	for column, v := range fill {
+		if v[0] == "" && v[1] != "" {
+			updates[column] = v[1]
+		}
+	}
+	if existing.QuoteScore == nil && incoming.QuoteScore != nil {
+		updates["quote_score"] = *incoming.QuoteScore
+	}
+	return updates
+}
+
+// ReviewScope 当前用户可审核的应用，管理员可审核全部应用，此时 all 为 true
+func ReviewScope(ctx context.Context, info auth.Info) (appIds []string, all bool, err error) {
+	if managerDAO.Managers.IsManager(info.StaffId) {
+		return nil, true, nil
+	}
+	if info.StaffId == "" {
+		return []string{}, false, nil
+	}
+	appIds, err = dao.Review.ReviewerAppIds(ctx, info.StaffId)
+	if appIds == nil {
+		appIds = []string{}
+	}
+	return appIds, false, err
+}
+
+// CanReview 用户是否可以审核该应用的回答
+func CanReview(ctx context.Context, info auth.Info, appId string) (bool, error) {
+	appIds, all, err := ReviewScope(ctx, info)
+	if err != nil || all {
+		return all, err
+	}
+	return slices.Contains(appIds, appId), nil
+}
+
+// CheckReviewCollection 检查修正回答要写入的集合属于应用的知识库（上传过该应用课程资料的知识库），管理员不受限制
+// 应用的 API Key 查询不到该集合时同样视为不属于，请求 FastGPT 失败时返回错误
+func CheckReviewCollection(ctx context.Context, info auth.Info, app *model.FastgptApp, collectionId string) error {
+	if managerDAO.Managers.IsManager(info.StaffId) {
+		return nil
+	}
+	datasetIds, err := dao.Document.DatasetIds(ctx, app.ID)
+	if err != nil {
+		return fmt.Errorf("get app datasets: %w", err)
+	}
+	if len(datasetIds) == 0 {
+		return ErrCollectionForbidden
+	}
+
+	client := NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey)
+	body, status, err := client.ForwardRequestWithQuery(ctx, http.MethodGet, "/core/dataset/collection/detail", map[string]string{"id": collectionId})
+	if err != nil {
+		return fmt.Errorf("get collection %s: %w", collectionId, err)
+	}
+	data, err := fastGPTData(body, status, nil)
+	if err != nil {
+		return ErrCollectionForbidden
+	}
+	// datasetId 在较新版本的 FastGPT 中为展开的知识库对象
+	datasetId := data.Get("datasetId._id").String()
+	if datasetId == "" {
+		datasetId = data.Get("datasetId").String()
+	}
+	if !slices.Contains(datasetIds, datasetId) {
+		return ErrCollectionForbidden
+	}
+	return nil
+}
+
+// PushCorrection 将修正后的问答写入 FastGPT 知识库集合，与 pushData 转发接口的请求一致，并记录写入结果
+func PushCorrection(ctx context.Context, app *model.FastgptApp, r *model.FastgptReview, collectionId string) error {
+	if !app.IsFastGPT() {
+		return fmt.Errorf("该应用未接入 FastGPT，不能写入知识库")
+	}
+	client := NewFastGPTClient(config.GetConfig().FastGPT.BaseURL, app.APIKey)
+	body, status, err := client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/data/pushData", map[string]interface{}{
+		"collectionId": collectionId,
+		"trainingType": "chunk",
+		"data":         []map[string]string{{"q": r.Question, "a": r.CorrectedAnswer}},
+	})
+	_, err = fastGPTData(body, status, err)
+
+	updates := map[string]interface{}{"collection_id": collectionId, "push_error": ""}
+	if err != nil {
+		updates["push_error"] = err.Error()
+	} else {
+		updates["pushed_at"] = time.Now()
+	}
+	if updateErr := dao.Review.Update(context.WithoutCancel(ctx), r.ID, updates); updateErr != nil {
+		logx.SystemLogger.CtxError(ctx, "update review push result", updateErr)
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/fastgpttest"
+	"HelpStudent/internal/app/fastgpt/model"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestQuoteScoreMerge(t *testing.T) {
+	var s quoteScore
+	s.merge(gjson.Parse(`[{"moduleType":"chatNode"}]`))
+	if s.searched || s.lowSimilarity(0.5) {
+		t.Fatalf("no dataset search: %+v", s)
+	}
+
+	// 新版 score 为数组，优先取 embedding；旧版为数字
+	s.merge(gjson.Parse(`[
+		{"moduleType":"datasetSearchNode","quoteList":[
+			{"score":[{"type":"fullText","value":0.9},{"type":"embedding","value":0.42}]},
+			{"score":0.31}
+		]}
+	]`))
+	if !s.searched || s.best == nil || *s.best != 0.42 {
+		t.Fatalf("best = %v, searched = %v", s.best, s.searched)
+	}
+	if !s.lowSimilarity(0.5) {
+		t.Fatal("0.42 < 0.5 should be low similarity")
+	}
+	if s.lowSimilarity(0.4) {
+		t.Fatal("0.42 >= 0.4 should not be low similarity")
+	}
+	if s.lowSimilarity(0) {
+		t.Fatal("min 0 disables the check")
+	}
+
+	var empty quoteScore
+	empty.merge(gjson.Parse(`[{"moduleType":"datasetSearchNode","quoteList":[]}]`))
+	if !empty.lowSimilarity(0.5) {
+		t.Fatal("search without quotes should be low similarity")
+	}
+}
+
+func TestQuoteSimilarity(t *testing.T) {
+	cases := []struct {
+		score string
+		want  float64
+		ok    bool
+	}{
+		{`0.8`, 0.8, true},
+		{`[{"type":"embedding","value":0.6}]`, 0.6, true},
+		{`[{"type":"rrf","value":0.03}]`, 0.03, true},
+		{`[]`, 0, false},
+		{`null`, 0, false},
+	}
+	for _, c := range cases {
+		got, ok := quoteSimilarity(gjson.Parse(c.score))
+		if got != c.want || ok != c.ok {
+			t.Errorf("quoteSimilarity(%s) = %v, %v; want %v, %v", c.score, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestMergeFlag(t *testing.T) {
+	score := 0.2
+	existing := &model.FastgptReview{Sources: model.ReviewSourceStudent, Reason: "学生点踩", DataId: "d1"}
+	incoming := &model.FastgptReview{
+		Sources:    model.ReviewSourceKeyword,
+		Reason:     "回答命中审核词：待定",
+		Question:   "问题",
+		Answer:     "回答",
+		DataId:     "d2",
+		QuoteScore: &score,
+	}
+	updates := mergeFlag(existing, incoming)
+	if updates["sources"] != "student,keyword" {
+		t.Errorf("sources = %v", updates["sources"])
+	}
+	if updates["reason"] != "学生点踩\n回答命中审核词：待定" {
+		t.Errorf("reason = %v", updates["reason"])
+	}
+	if updates["question"] != "问题" || updates["answer"] != "回答" {
+		t.Errorf("question/answer not filled: %v", updates)
+	}
+	if _, ok := updates["data_id"]; ok {
+		t.Error("existing data_id should be kept")
+	}
+	if updates["quote_score"] != 0.2 {
+		t.Errorf("quote_score = %v", updates["quote_score"])
+	}
+	if _, ok := updates["flag_count"]; !ok {
+		t.Error("flag_count should be incremented")
+	}
+
+	// 同一来源、同一原因再次标记只增加次数
+	again := mergeFlag(&model.FastgptReview{Sources: "student,keyword", Reason: "学生点踩"}, &model.FastgptReview{Sources: "student", Reason: "学生点踩"})
+	if len(again) != 1 {
+		t.Errorf("repeat flag updates = %v", again)
+	}
+}
+
+func TestFindAnswer(t *testing.T) {
+	records := gjson.Parse(`[
+		{"dataId":"h1","obj":"Human","value":[{"type":"text","text":{"content":"什么是极限"}}]},
+		{"dataId":"a1","obj":"AI","value":[{"type":"text","text":{"content":"极限是……"}}]},
+		{"dataId":"h2","obj":"Human","value":"求导"},
+		{"dataId":"a2","obj":"AI","value":"导数是……"}
+	]`).Array()
+
+	q, a := findAnswer(records, "a2")
+	if q != "求导" || a != "导数是……" {
+		t.Errorf("a2 = %q, %q", q, a)
+	}
+	q, a = findAnswer(records, "a1")
+	if q != "什么是极限" || a != "极限是……" {
+		t.Errorf("a1 = %q, %q", q, a)
+	}
+	if q, a = findAnswer(records, "missing"); q != "" || a != "" {
+		t.Errorf("missing = %q, %q", q, a)
+	}
+}
+
+func TestCheckReviewCollection(t *testing.T) {
+	env := fastgpttest.Setup(t)
+	env.AddManager(t, "T001")
+	app := env.CreateApp(t, &model.FastgptApp{AppName: "高等数学", AppId: "app1"})
+	ctx := context.Background()
+	client := NewFastGPTClient(env.Server.URL(), app.APIKey)
+
+	// newCollection 在新知识库中创建集合，返回知识库与集合 ID
+	newCollection := func(name string) (string, string) {
+		t.Helper()
+		datasetId, err := fastGPTData(client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/create", map[string]interface{}{"name": name}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		col, err := fastGPTData(client.ForwardRequest(ctx, http.MethodPost, "/core/dataset/collection/create/text", map[string]interface{}{
+			"datasetId": datasetId.String(), "name": name, "text": "内容",
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return datasetId.String(), col.Get("collectionId").String()
+	}
+	ownDataset, ownCollection := newCollection("高等数学")
+	_, otherCollection := newCollection("线性代数")
+	if err := dao.Document.Create(ctx, &model.FastgptDocument{
+		AppId: app.ID, SubjectName: "高等数学", DatasetId: ownDataset, FileName: "讲义.pdf", FileKey: "k", FileType: "pdf",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	teacher := auth.Info{Uid: "uid-teacher", StaffId: "T002"}
+	cases := []struct {
+		name         string
+		info         auth.Info
+		collectionId string
+		want         error
+	}{
+		{"own collection", teacher, ownCollection, nil},
+		{"other dataset", teacher, otherCollection, ErrCollectionForbidden},
+		{"unknown collection", teacher, "missing", ErrCollectionForbidden},
+		{"manager", auth.Info{Uid: "uid-manager", StaffId: "T001"}, otherCollection, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := CheckReviewCollection(ctx, c.info, app, c.collectionId); !errors.Is(err, c.want) {
+				t.Errorf("CheckReviewCollection = %v, want %v", err, c.want)
+			}
+		})
+	}
+}
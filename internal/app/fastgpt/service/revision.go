@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+// ErrRevisionNotFound 版本不存在
+var ErrRevisionNotFound = errors.New("版本不存在")
+
+// AppSnapshot 应用配置快照，API Key 单独加密保存，不在快照中
+type AppSnapshot struct {
+	AppName     string `json:"appName"`
+	AppId       string `json:"appId"`
+	ShareId     string `json:"shareId"`
+	Description string `json:"description"`
+	Provider    string `json:"provider"`
+	BaseURL     string `json:"baseUrl"`
+	Model       string `json:"model"`
+	Status      int    `json:"status"`
+}
+
+// FieldChange 一个字段的变化
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+func snapshotOf(app *model.FastgptApp) AppSnapshot {
+	return AppSnapshot{
+		AppName:     app.AppName,
+		AppId:       app.AppId,
+		ShareId:     app.ShareId,
+		Description: app.Description,
+		Provider:    app.Provider,
+		BaseURL:     app.BaseURL,
+		Model:       app.Model,
+		Status:      app.Status,
+	}
+}
+
+// DiffApps 比较两份配置，before 或 after 为 nil 表示创建或删除；API Key 只以脱敏值与指纹出现
+func DiffApps(before, after *model.FastgptApp) []FieldChange {
+	var oldSnap, newSnap AppSnapshot
+	var oldKey, newKey string
+	if before != nil {
+		oldSnap, oldKey = snapshotOf(before), before.APIKey
+	}
+	if after != nil {
+		newSnap, newKey = snapshotOf(after), after.APIKey
+	}
+
+	var changes []FieldChange
+	ov, nv := reflect.ValueOf(oldSnap), reflect.ValueOf(newSnap)
+	for i := 0; i < ov.NumField(); i++ {
+		o, n := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if o != n {
+			changes = append(changes, FieldChange{Field: ov.Type().Field(i).Tag.Get("json"), Old: o, New: n})
+		}
+	}
+	if oldKey != newKey {
+		changes = append(changes, FieldChange{Field: "apiKey", Old: maskedKey(oldKey), New: maskedKey(newKey)})
+	}
+	return changes
+}
+
+func maskedKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	return MaskAPIKey(apiKey) + " (" + APIKeyFingerprint(apiKey) + ")"
+}
+
+// RecordAppRevision 记录一次应用配置变更，state 为变更后的配置，删除时为删除前的配置
+func RecordAppRevision(ctx context.Context, action string, before, after *model.FastgptApp, operator, comment string) error {
+	state := after
+	if state == nil {
+		state = before
+	}
+	snapshot, err := json.Marshal(snapshotOf(state))
+	if err != nil {
+		return err
+	}
+	changes, err := json.Marshal(DiffApps(before, after))
+	if err != nil {
+		return err
+	}
+	return dao.AppRevision.Create(ctx, &model.FastgptAppRevision{
+		AppId:    state.ID,
+		Action:   action,
+		Snapshot: string(snapshot),
+		APIKey:   state.APIKey,
+		Changes:  string(changes),
+		Operator: operator,
+		Comment:  comment,
+	})
+}
+
+// RevisionChanges 解析版本中记录的字段变化
+func RevisionChanges(rev *model.FastgptAppRevision) []FieldChange {
+	var changes []FieldChange
+	_ = json.Unmarshal([]byte(rev.Changes), &changes)
+	return changes
+}
+
+// RollbackApp 将应用配置恢复为指定版本，已删除的应用会被恢复，并记录一个新的回滚版本
+func RollbackApp(ctx context.Context, appId string, version int, operator string) (*model.FastgptApp, error) {
+	rev, err := dao.AppRevision.Get(ctx, appId, version)
+	if err != nil {
+		return nil, err
+	}
+	if rev == nil {
+		return nil, ErrRevisionNotFound
+	}
+	var snap AppSnapshot
+	if err := json.Unmarshal([]byte(rev.Snapshot), &snap); err != nil {
+		return nil, fmt.Errorf("parse revision snapshot: %w", err)
+	}
+
+	before, err := dao.FastgptApp.GetAppIncludingDeleted(ctx, appId)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	updates := map[string]interface{}{
+		"app_name":    snap.AppName,
+		"app_id":      snap.AppId,
+		"share_id":    snap.ShareId,
+		"api_key":     rev.APIKey,
+		"description": snap.Description,
+		"provider":    snap.Provider,
+		"base_url":    snap.BaseURL,
+		"model":       snap.Model,
+		"status":      snap.Status,
+	}
+	if err := dao.FastgptApp.RestoreApp(ctx, appId, updates); err != nil {
+		return nil, err
+	}
+
+	after, err := dao.FastgptApp.GetAppByPrimaryID(ctx, appId)
+	if err != nil {
+		return nil, err
+	}
+	comment := fmt.Sprintf("回滚到版本 %d", version)
+	if before.DeletedAt.Valid {
+		comment += "，恢复已删除的应用"
+	}
+	if err := RecordAppRevision(ctx, model.RevisionRollback, before, after, operator, comment); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
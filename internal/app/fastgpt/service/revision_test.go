@@ -0,0 +1,45 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+func TestDiffApps(t *testing.T) {
+	before := &model.FastgptApp{AppName: "高数", AppId: "app1", APIKey: "fastgpt-oldsecretkey123", Status: model.AppStatusEnabled}
+	after := *before
+	after.AppName = "高等数学"
+	after.APIKey = "fastgpt-newsecretkey456"
+
+	changes := DiffApps(before, &after)
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	if changes[0].Field != "appName" || changes[0].Old != "高数" || changes[0].New != "高等数学" {
+		t.Errorf("appName change = %+v", changes[0])
+	}
+	key := changes[1]
+	if key.Field != "apiKey" {
+		t.Fatalf("apiKey change = %+v", key)
+	}
+	for _, v := range []interface{}{key.Old, key.New} {
+		if s := v.(string); strings.Contains(s, "secretkey") {
+			t.Errorf("api key not masked: %q", s)
+		}
+	}
+
+	if changes := DiffApps(before, before); len(changes) != 0 {
+		t.Errorf("unchanged app: %+v", changes)
+	}
+	// 创建时所有非空字段都出现在变化中
+	created := DiffApps(nil, before)
+	fields := map[string]bool{}
+	for _, ch := range created {
+		fields[ch.Field] = true
+	}
+	if !fields["appName"] || !fields["appId"] || !fields["status"] || !fields["apiKey"] {
+		t.Errorf("create changes = %+v", created)
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+const clockLayout = "15:04"
+
+var weekdayNames = []string{"", "周一", "周二", "周三", "周四", "周五", "周六", "周日"}
+
+// UnavailableError 应用当前不可对话
+type UnavailableError struct {
+	Message string
+	// AvailableAt 预计恢复可用的时间，无法确定时为 nil
+	AvailableAt *time.Time
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Message
+}
+
+// CheckAvailability 检查应用当前是否可以对话：应用需为启用状态，且不在暂停时段内；
+// 配置了开放时段时还需处于某个开放时段内
+func CheckAvailability(ctx context.Context, app *model.FastgptApp) error {
+	if app.Status == model.AppStatusDisabled {
+		return &UnavailableError{Message: "该应用已停用"}
+	}
+	schedules, err := dao.AppSchedule.ListByApp(ctx, app.ID)
+	if err != nil {
+		return fmt.Errorf("list app schedules: %w", err)
+	}
+	if ue := availableAt(schedules, time.Now()); ue != nil {
+		return ue
+	}
+	return nil
+}
+
+// availableAt 按时段判断 now 时是否可用，可用时返回 nil
+func availableAt(schedules []model.FastgptAppSchedule, now time.Time) *UnavailableError {
+	var windows []model.FastgptAppSchedule
+	for _, s := range schedules {
+		switch s.Type {
+		case model.ScheduleBlackout:
+			if s.StartAt != nil && s.EndAt != nil && !now.Before(*s.StartAt) && now.Before(*s.EndAt) {
+				msg := s.Message
+				if msg == "" {
+					msg = fmt.Sprintf("该应用在 %s 至 %s 期间暂停使用",
+						s.StartAt.Format("01-02 15:04"), s.EndAt.Format("01-02 15:04"))
+				}
+				end := *s.EndAt
+				return &UnavailableError{Message: msg, AvailableAt: &end}
+			}
+		case model.ScheduleOpen:
+			windows = append(windows, s)
+		}
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+
+	for _, w := range windows {
+		if inWindow(w, now) {
+			return nil
+		}
+	}
+	msg := windows[0].Message
+	if msg == "" {
+		msg = "当前不在开放时间，开放时间：" + DescribeWindows(windows)
+	}
+	return &UnavailableError{Message: msg, AvailableAt: nextOpening(windows, now)}
+}
+
+// inWindow now 是否处于开放时段内，结束时间早于开始时间的时段跨越午夜，属于开始的那一天
+func inWindow(w model.FastgptAppSchedule, now time.Time) bool {
+	days := ParseWeekdays(w.Weekdays)
+	start, _ := parseClock(w.StartTime)
+	end, _ := parseClock(w.EndTime)
+	minute := now.Hour()*60 + now.Minute()
+	today := isoWeekday(now)
+	yesterday := today - 1
+	if yesterday == 0 {
+		yesterday = 7
+	}
+
+	if start < end {
+		return days[today] && minute >= start && minute < end
+	}
+	return (days[today] && minute >= start) || (days[yesterday] && minute < end)
+}
+
+// nextOpening 七天内最近一个开放时段的开始时间
+func nextOpening(windows []model.FastgptAppSchedule, now time.Time) *time.Time {
+	var next *time.Time
+	y, m, d := now.Date()
+	for offset := 0; offset <= 7; offset++ {
+		day := time.Date(y, m, d+offset, 0, 0, 0, 0, now.Location())
+		for _, w := range windows {
+			if !ParseWeekdays(w.Weekdays)[isoWeekday(day)] {
+				continue
+			}
+			start, _ := parseClock(w.StartTime)
+			at := day.Add(time.Duration(start) * time.Minute)
+			if at.After(now) && (next == nil || at.Before(*next)) {
+				next = &at
+			}
+		}
+		if next != nil {
+			return next
+		}
+	}
+	return nil
+}
+
+// DescribeWindows 开放时段的可读描述，如“周一、周三 08:00-22:00”
+func DescribeWindows(windows []model.FastgptAppSchedule) string {
+	parts := make([]string, 0, len(windows))
+	for _, w := range windows {
+		days := ParseWeekdays(w.Weekdays)
+		var names []string
+		for i := 1; i <= 7; i++ {
+			if days[i] {
+				names = append(names, weekdayNames[i])
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s %s-%s", strings.Join(names, "、"), w.StartTime, w.EndTime))
+	}
+	return strings.Join(parts, "；")
+}
+
+// ParseWeekdays 解析逗号分隔的星期，下标 1-7 对应周一至周日
+func ParseWeekdays(s string) [8]bool {
+	var days [8]bool
+	for _, part := range strings.Split(s, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && n >= 1 && n <= 7 {
+			days[n] = true
+		}
+	}
+	return days
+}
+
+// FormatWeekdays 去重排序后以逗号连接
+func FormatWeekdays(days []int) (string, error) {
+	seen := map[int]bool{}
+	var sorted []int
+	for _, d := range days {
+		if d < 1 || d > 7 {
+			return "", errors.New("星期需为 1-7")
+		}
+		if !seen[d] {
+			seen[d] = true
+			sorted = append(sorted, d)
+		}
+	}
+	if len(sorted) == 0 {
+		return "", errors.New("请选择开放的星期")
+	}
+	sort.Ints(sorted)
+	parts := make([]string, len(sorted))
+	for i, d := range sorted {
+		parts[i] = strconv.Itoa(d)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// ValidClock 是否为 HH:MM 格式的时间
+func ValidClock(s string) bool {
+	_, err := parseClock(s)
+	return err == nil
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse(clockLayout, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// isoWeekday 周一为 1，周日为 7
+func isoWeekday(t time.Time) int {
+	if wd := int(t.Weekday()); wd != 0 {
+		return wd
+	}
+	return 7
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+func TestAvailableAt(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	examStart, examEnd := at("2026-06-20 00:00"), at("2026-06-22 00:00")
+	schedules := []model.FastgptAppSchedule{
+		{Type: model.ScheduleOpen, Weekdays: "1,2,3,4,5", StartTime: "08:00", EndTime: "22:00"},
+		{Type: model.ScheduleOpen, Weekdays: "6", StartTime: "20:00", EndTime: "02:00"},
+		{Type: model.ScheduleBlackout, StartAt: &examStart, EndAt: &examEnd, Message: "考试期间暂停使用"},
+	}
+
+	// 2026-06-15 为周一
+	for now, want := range map[string]bool{
+		"2026-06-15 08:00": true,
+		"2026-06-15 21:59": true,
+		"2026-06-15 22:00": false,
+		"2026-06-13 23:30": true,  // 周六跨午夜时段
+		"2026-06-14 01:30": true,  // 周六时段延续到周日凌晨
+		"2026-06-14 10:00": false, // 周日
+		"2026-06-22 10:00": true,  // 考试结束后的周一
+	} {
+		if got := availableAt(schedules, at(now)) == nil; got != want {
+			t.Errorf("%s: available=%v, want %v", now, got, want)
+		}
+	}
+
+	// 考试期间即使在开放时段内也不可用
+	ue := availableAt(schedules, at("2026-06-20 21:00"))
+	if ue == nil || ue.Message != "考试期间暂停使用" || !ue.AvailableAt.Equal(examEnd) {
+		t.Fatalf("blackout: %+v", ue)
+	}
+
+	// 周日上午不可用，下一次开放为周一 08:00
+	ue = availableAt(schedules, at("2026-06-14 10:00"))
+	if ue == nil || ue.AvailableAt == nil || !ue.AvailableAt.Equal(at("2026-06-15 08:00")) {
+		t.Fatalf("closed: %+v", ue)
+	}
+	if ue.Message != "当前不在开放时间，开放时间：周一、周二、周三、周四、周五 08:00-22:00；周六 20:00-02:00" {
+		t.Errorf("message = %q", ue.Message)
+	}
+
+	// 没有开放时段时不限制
+	if availableAt(nil, at("2026-06-14 10:00")) != nil {
+		t.Error("app without schedules should be available")
+	}
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"HelpStudent/config"
+	"HelpStudent/pkg/utils/gen/xrandom"
+)
+
+// maxStreamEvents 单次生成最多缓存的事件数，超出后丢弃最早的事件
+const maxStreamEvents = 20000
+
+// StreamEvent 流式对话中的一条事件
+type StreamEvent struct {
+	Seq   int
+	Event string
+	Data  string
+}
+
+// ChatStream 一次流式生成
+// 生成在后台进行并缓存已产生的事件，客户端断线后在宽限期内可凭 Last-Event-ID 重连续传，
+// 宽限期内无人重连则取消生成
+type ChatStream struct {
+	Id     string
+	UserId string
+	ChatId string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	hub    *StreamHub
+
+	mu          sync.Mutex
+	events      []StreamEvent
+	seq         int
+	done        bool
+	stopped     bool
+	changed     chan struct{} // 有新事件或生成结束时关闭并替换
+	subscribers int
+	idleTimer   *time.Timer
+}
+
+// StreamHub 进行中与刚结束的流式生成
+type StreamHub struct {
+	mu      sync.Mutex
+	streams map[string]*ChatStream
+	byChat  map[string]*ChatStream // 用户 + chatId 对应的最近一次生成
+}
+
+// Streams 全局的流式生成表
+var Streams = &StreamHub{
+	streams: map[string]*ChatStream{},
+	byChat:  map[string]*ChatStream{},
+}
+
+// streamResumeGrace 续传宽限期
+func streamResumeGrace() time.Duration {
+	sec := 60
+	if cfg := config.GetConfig(); cfg != nil && cfg.FastGPT.StreamResumeGrace > 0 {
+		sec = cfg.FastGPT.StreamResumeGrace
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// Start 开始一次流式生成，同一用户同一会话中尚未结束的上一次生成会被取消
+// 生成的 context 继承 parent 的值但不随 parent 取消
+func (h *StreamHub) Start(parent context.Context, userId, chatId string) *ChatStream {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	s := &ChatStream{
+		Id:      xrandom.GetRandom(16),
+		UserId:  userId,
+		ChatId:  chatId,
+		ctx:     ctx,
+		cancel:  cancel,
+		hub:     h,
+		changed: make(chan struct{}),
+	}
+	// 尚无客户端订阅时同样按宽限期计时
+	s.idleTimer = time.AfterFunc(streamResumeGrace(), cancel)
+
+	h.mu.Lock()
+	h.streams[s.Id] = s
+	var prev *ChatStream
+	if chatId != "" {
+		key := userId + ":" + chatId
+		prev = h.byChat[key]
+		h.byChat[key] = s
+	}
+	h.mu.Unlock()
+
+	if prev != nil {
+		prev.cancel()
+	}
+	return s
+}
+
+// Get 按 ID 获取生成，已过期时返回 nil
+func (h *StreamHub) Get(id string) *ChatStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.streams[id]
+}
+
+// Latest 获取用户在某会话中最近一次生成，已过期时返回 nil
+func (h *StreamHub) Latest(userId, chatId string) *ChatStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.byChat[userId+":"+chatId]
+}
+
+// Stop 停止用户在某会话中进行中的生成，没有进行中的生成时返回 false
+func (h *StreamHub) Stop(userId, chatId string) bool {
+	s := h.Latest(userId, chatId)
+	return s != nil && s.Stop()
+}
+
+func (h *StreamHub) remove(s *ChatStream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.streams, s.Id)
+	key := s.UserId + ":" + s.ChatId
+	if h.byChat[key] == s {
+		delete(h.byChat, key)
+	}
+}
+
+// EventId 事件 ID，格式为 <生成ID>.<序号>
+func (s *ChatStream) EventId(seq int) string {
+	return s.Id + "." + strconv.Itoa(seq)
+}
+
+// ParseEventId 解析 Last-Event-ID
+func ParseEventId(id string) (streamId string, seq int, ok bool) {
+	i := strings.LastIndexByte(id, '.')
+	if i <= 0 {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(id[i+1:])
+	if err != nil || seq < 0 {
+		return "", 0, false
+	}
+	return id[:i], seq, true
+}
+
+// Context 生成使用的 context，宽限期内无人重连或被新的生成取代时取消
+func (s *ChatStream) Context() context.Context {
+	return s.ctx
+}
+
+// Cancel 取消生成
+func (s *ChatStream) Cancel() {
+	s.cancel()
+}
+
+// Stop 由用户停止生成，生成已结束时返回 false
+func (s *ChatStream) Stop() bool {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return false
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.cancel()
+	return true
+}
+
+// Stopped 生成是否由用户停止
+func (s *ChatStream) Stopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// Publish 追加一条事件
+func (s *ChatStream) Publish(event, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.seq++
+	if len(s.events) >= maxStreamEvents {
+		s.events = s.events[1:]
+	}
+	s.events = append(s.events, StreamEvent{Seq: s.seq, Event: event, Data: data})
+	s.notifyLocked()
+}
+
+// Close 结束生成，缓存的事件在宽限期后清除
+func (s *ChatStream) Close() {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+	s.idleTimer.Stop()
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	s.cancel()
+	time.AfterFunc(streamResumeGrace(), func() { s.hub.remove(s) })
+}
+
+// Done 生成是否已结束
+func (s *ChatStream) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+func (s *ChatStream) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Subscribe 推送序号大于 after 的事件，直到生成结束、ctx 取消或 send 返回 false
+// 最后一个订阅者离开而生成尚未结束时开始宽限期计时
+func (s *ChatStream) Subscribe(ctx context.Context, after int, send func(StreamEvent) bool) {
+	s.mu.Lock()
+	s.subscribers++
+	s.idleTimer.Stop()
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.subscribers--
+		if s.subscribers == 0 && !s.done {
+			s.idleTimer.Reset(streamResumeGrace())
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		s.mu.Lock()
+		// 序号连续，按第一条缓存事件的序号直接定位；事件只追加或丢弃最早的，切片内容不会被修改
+		var pending []StreamEvent
+		if n := len(s.events); n > 0 {
+			start := after - s.events[0].Seq + 1
+			if start < 0 {
+				start = 0
+			}
+			if start < n {
+				pending = s.events[start:]
+			}
+		}
+		done, changed := s.done, s.changed
+		s.mu.Unlock()
+
+		for _, e := range pending {
+			if !send(e) {
+				return
+			}
+			after = e.Seq
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
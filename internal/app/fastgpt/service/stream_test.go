@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestChatStream_Resume(t *testing.T) {
+	hub := &StreamHub{streams: map[string]*ChatStream{}, byChat: map[string]*ChatStream{}}
+	s := hub.Start(context.Background(), "u1", "chat1")
+	s.Publish("", "a")
+	s.Publish("", "b")
+
+	// 第一个客户端收到一条后断开
+	var first []string
+	s.Subscribe(context.Background(), 0, func(e StreamEvent) bool {
+		first = append(first, e.Data)
+		return false
+	})
+	if len(first) != 1 || first[0] != "a" {
+		t.Fatalf("unexpected events before disconnect: %v", first)
+	}
+
+	// 断线期间生成继续
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.Publish("", "c")
+		s.Close()
+	}()
+
+	streamId, seq, ok := ParseEventId(s.EventId(1))
+	if !ok || hub.Get(streamId) != s {
+		t.Fatalf("ParseEventId failed: %s %d %v", streamId, seq, ok)
+	}
+	var resumed []string
+	s.Subscribe(context.Background(), seq, func(e StreamEvent) bool {
+		resumed = append(resumed, e.Data)
+		return true
+	})
+	if len(resumed) != 2 || resumed[0] != "b" || resumed[1] != "c" {
+		t.Errorf("resume should replay missed events, got %v", resumed)
+	}
+	if s.Context().Err() == nil {
+		t.Error("context should be cancelled after Close")
+	}
+
+	// 同一会话的新提问取代旧的生成
+	s2 := hub.Start(context.Background(), "u1", "chat2")
+	hub.Start(context.Background(), "u1", "chat2")
+	if s2.Context().Err() == nil {
+		t.Error("previous generation in the same chat should be cancelled")
+	}
+}
+
+func TestStreamHub_Stop(t *testing.T) {
+	hub := &StreamHub{streams: map[string]*ChatStream{}, byChat: map[string]*ChatStream{}}
+	if hub.Stop("u1", "chat1") {
+		t.Fatal("no generation to stop")
+	}
+
+	s := hub.Start(context.Background(), "u1", "chat1")
+	if hub.Stop("u2", "chat1") || hub.Stop("u1", "chat2") {
+		t.Fatal("should only stop the user's own generation in the same chat")
+	}
+	if s.Stopped() || s.Context().Err() != nil {
+		t.Fatal("generation should still be running")
+	}
+
+	if !hub.Stop("u1", "chat1") {
+		t.Fatal("Stop should succeed for a running generation")
+	}
+	if !s.Stopped() || s.Context().Err() == nil {
+		t.Error("stopped generation should be cancelled")
+	}
+
+	// 停止后生成方仍可推送 stopped 事件
+	s.Publish("stopped", `{"chatId":"chat1"}`)
+	s.Close()
+	var events []string
+	s.Subscribe(context.Background(), 0, func(e StreamEvent) bool {
+		events = append(events, e.Event)
+		return true
+	})
+	if len(events) != 1 || events[0] != "stopped" {
+		t.Errorf("events = %v", events)
+	}
+	if hub.Stop("u1", "chat1") {
+		t.Error("finished generation cannot be stopped")
+	}
+
+	// 被新提问取代的生成不是用户停止
+	old := hub.Start(context.Background(), "u1", "chat3")
+	hub.Start(context.Background(), "u1", "chat3")
+	if old.Stopped() {
+		t.Error("superseded generation should not be marked as stopped")
+	}
+}
+
+func TestChatStream_SubscribeAfterTrim(t *testing.T) {
+	hub := &StreamHub{streams: map[string]*ChatStream{}, byChat: map[string]*ChatStream{}}
+	s := hub.Start(context.Background(), "u1", "chat1")
+	for i := 0; i < maxStreamEvents+5; i++ {
+		s.Publish("", "x")
+	}
+	s.Close()
+
+	collect := func(after int) (first, count int) {
+		s.Subscribe(context.Background(), after, func(e StreamEvent) bool {
+			if count == 0 {
+				first = e.Seq
+			}
+			count++
+			return true
+		})
+		return first, count
+	}
+	// 续传位置早于缓存的第一条时从第一条开始
+	if first, count := collect(0); first != 6 || count != maxStreamEvents {
+		t.Errorf("after 0: first=%d count=%d", first, count)
+	}
+	if first, count := collect(maxStreamEvents); first != maxStreamEvents+1 || count != 5 {
+		t.Errorf("after %d: first=%d count=%d", maxStreamEvents, first, count)
+	}
+	if _, count := collect(maxStreamEvents + 5); count != 0 {
+		t.Errorf("after last: count=%d", count)
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"HelpStudent/core/auth"
+	"HelpStudent/core/logx"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+// Transcript 记录一次问答到本地数据库
+// 提问时写入 pending 记录，结束时补全回答、耗时与结束状态，并记录一条用量
+type Transcript struct {
+	msg      *model.FastgptChatMessage
+	subject  string
+	answer   strings.Builder
+	start    time.Time
+	firstAt  time.Time
+	quotes   quoteScore
+	finished bool
+	mu       sync.Mutex
+}
+
+// StartTranscript 记录提问，写库失败只记日志，不影响对话
+func StartTranscript(ctx context.Context, info auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest, stream bool) *Transcript {
+	now := time.Now()
+	t := &Transcript{
+		start:   now,
+		subject: app.AppName,
+		msg: &model.FastgptChatMessage{
+			AppId:     app.ID,
+			ChatId:    req.ChatId,
+			UserId:    info.Uid,
+			StaffId:   info.StaffId,
+			Question:  LastUserQuestion(req.Messages),
+			Stream:    stream,
+			Status:    model.ChatStatusPending,
+			RequestAt: now,
+		},
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if err := dao.ChatRecord.CreateMessage(ctx, t.msg); err != nil {
+		logx.SystemLogger.CtxError(ctx, "create chat message record", err)
+	}
+	if req.ChatId != "" {
+		if err := dao.ChatRecord.TouchSession(ctx, &model.FastgptChatSession{
+			AppId:         app.ID,
+			ChatId:        req.ChatId,
+			UserId:        info.Uid,
+			StaffId:       info.StaffId,
+			Title:         truncateRunes(t.msg.Question, 200),
+			LastMessageAt: now,
+		}); err != nil {
+			logx.SystemLogger.CtxError(ctx, "touch chat session", err)
+		}
+	}
+	return t
+}
+
+// AppendChunk 累加流式响应中 data 行的回答内容，detail 模式下的节点运行详情用于判断引用相似度
+func (t *Transcript) AppendChunk(data string) {
+	if data == "[DONE]" {
+		return
+	}
+	if strings.HasPrefix(data, "[") {
+		t.mu.Lock()
+		t.quotes.merge(gjson.Parse(data))
+		t.mu.Unlock()
+		return
+	}
+	content := gjson.Get(data, "choices.0.delta.content")
+	if !content.Exists() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.firstAt.IsZero() {
+		t.firstAt = time.Now()
+	}
+	t.answer.WriteString(content.String())
+}
+
+// SetAnswer 从非流式响应体中提取回答
+func (t *Transcript) SetAnswer(body []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.answer.Reset()
+	t.answer.WriteString(gjson.GetBytes(body, "choices.0.message.content").String())
+	t.quotes.merge(gjson.GetBytes(body, "responseData"))
+}
+
+// Answer 当前已累积的回答
+func (t *Transcript) Answer() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.answer.String()
+}
+
+// Finish 写入结束状态，重复调用只有第一次生效；提问记录写入失败时仍记录用量
+func (t *Transcript) Finish(status string, err error) {
+	t.mu.Lock()
+	if t.finished {
+		t.mu.Unlock()
+		return
+	}
+	t.finished = true
+	now := time.Now()
+	updates := map[string]interface{}{
+		"answer":      t.answer.String(),
+		"status":      status,
+		"finished_at": now,
+		"latency_ms":  now.Sub(t.start).Milliseconds(),
+	}
+	if !t.firstAt.IsZero() {
+		updates["first_chunk_ms"] = t.firstAt.Sub(t.start).Milliseconds()
+	}
+	if err != nil {
+		updates["error_msg"] = err.Error()
+	}
+	id := t.msg.ID
+	answer, quotes := t.answer.String(), t.quotes
+	event := newUsageEvent(t.msg, t.subject, status, t.start)
+	event.LatencyMs = now.Sub(t.start).Milliseconds()
+	event.OutputChars = utf8.RuneCountInString(answer)
+	t.mu.Unlock()
+
+	if id != "" {
+		if err := dao.ChatRecord.FinishMessage(context.Background(), id, updates); err != nil {
+			logx.SystemLogger.Errorf("finish chat message record %s: %v", id, err)
+		}
+	}
+	if err := dao.Usage.Create(context.Background(), event); err != nil {
+		logx.SystemLogger.Errorf("create usage event for %s: %v", id, err)
+	}
+	if status == model.ChatStatusSuccess && id != "" {
+		flagTranscript(context.Background(), t.msg, t.subject, answer, quotes)
+	}
+}
+
+// RecordRejectedUsage 记录转发前被拒绝的调用，status 为 model.UsageStatus* 或 model.ChatStatusAborted
+func RecordRejectedUsage(ctx context.Context, info auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest, stream bool, status string) {
+	msg := &model.FastgptChatMessage{
+		AppId:    app.ID,
+		UserId:   info.Uid,
+		StaffId:  info.StaffId,
+		Question: LastUserQuestion(req.Messages),
+		Stream:   stream,
+	}
+	if err := dao.Usage.Create(context.WithoutCancel(ctx), newUsageEvent(msg, app.AppName, status, time.Now())); err != nil {
+		logx.SystemLogger.CtxError(ctx, "create rejected usage event", err)
+	}
+}
+
+func newUsageEvent(msg *model.FastgptChatMessage, subject, status string, start time.Time) *model.FastgptUsageEvent {
+	return &model.FastgptUsageEvent{
+		AppId:       msg.AppId,
+		SubjectName: subject,
+		UserId:      msg.UserId,
+		StaffId:     msg.StaffId,
+		Stream:      msg.Stream,
+		Status:      status,
+		Day:         start.Format("2006-01-02"),
+		Hour:        start.Hour(),
+		RequestAt:   start,
+		InputChars:  utf8.RuneCountInString(msg.Question),
+	}
+}
+
+// LastUserQuestion 取最后一条用户消息的文本内容
+func LastUserQuestion(messages []dto.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return MessageText(messages[i].Content)
+		}
+	}
+	return ""
+}
+
+// MessageText 提取消息文本，content 可能是字符串或 [{type:"text",text:"..."}] 形式的数组
+func MessageText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var parts []string
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok && m["type"] == "text" {
+				if text, ok := m["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
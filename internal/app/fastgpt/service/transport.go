@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"HelpStudent/config"
+)
+
+// ErrStreamIdleTimeout 流式响应长时间没有新数据
+var ErrStreamIdleTimeout = errors.New("FastGPT 流式响应超时")
+
+// clientOptions 生效的客户端设置
+type clientOptions struct {
+	connectTimeout      time.Duration
+	requestTimeout      time.Duration
+	firstByteTimeout    time.Duration
+	streamIdleTimeout   time.Duration
+	maxRetries          int
+	maxIdleConnsPerHost int
+}
+
+var (
+	transportOnce sync.Once
+	options       clientOptions
+	httpClient    *http.Client // 非流式请求
+	streamClient  *http.Client // 流式请求，不设置整体超时
+)
+
+// sharedClients 所有 FastGPTClient 共用的连接池，首次使用时按配置创建
+// 超时、重试与连接池设置只在创建时读取一次，配置文件热更新不会生效，修改后需重启服务
+func sharedClients() (clientOptions, *http.Client, *http.Client) {
+	transportOnce.Do(func() {
+		var conf config.FastGPTClient
+		if cfg := config.GetConfig(); cfg != nil {
+			conf = cfg.FastGPT.Client
+		}
+		options = clientOptions{
+			connectTimeout:      seconds(conf.ConnectTimeout, 5),
+			requestTimeout:      seconds(conf.RequestTimeout, 30),
+			firstByteTimeout:    seconds(conf.FirstByteTimeout, 60),
+			streamIdleTimeout:   seconds(conf.StreamIdleTimeout, 120),
+			maxRetries:          conf.MaxRetries,
+			maxIdleConnsPerHost: conf.MaxIdleConnsPerHost,
+		}
+		switch {
+		case options.maxRetries == 0:
+			options.maxRetries = 2
+		case options.maxRetries < 0:
+			options.maxRetries = 0
+		}
+		if options.maxIdleConnsPerHost <= 0 {
+			options.maxIdleConnsPerHost = 32
+		}
+
+		httpClient = &http.Client{
+			Timeout:   options.requestTimeout,
+			Transport: newTransport(options, false),
+		}
+		streamClient = &http.Client{
+			Transport: newTransport(options, true),
+		}
+	})
+	return options, httpClient, streamClient
+}
+
+func newTransport(opts clientOptions, stream bool) *http.Transport {
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   opts.connectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: opts.connectTimeout,
+		MaxIdleConns:        opts.maxIdleConnsPerHost * 4,
+		MaxIdleConnsPerHost: opts.maxIdleConnsPerHost,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if stream {
+		t.ResponseHeaderTimeout = opts.firstByteTimeout
+		t.DisableCompression = true // 禁用压缩，确保数据实时到达
+	}
+	return t
+}
+
+func seconds(v, def int) time.Duration {
+	if v <= 0 {
+		v = def
+	}
+	return time.Duration(v) * time.Second
+}
+
+// idleTimeoutBody 两次读到数据的间隔超过 timeout 时取消请求
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	cancel   context.CancelFunc
+	timedOut atomic.Bool
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleTimeoutBody {
+	b := &idleTimeoutBody{ReadCloser: body, timeout: timeout, cancel: cancel}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.timedOut.Store(true)
+		cancel()
+	})
+	return b
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && err != io.EOF && b.timedOut.Load() {
+		err = ErrStreamIdleTimeout
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/dao"
+	"HelpStudent/internal/app/fastgpt/dto"
+	"HelpStudent/internal/app/fastgpt/model"
+	subjectDAO "HelpStudent/internal/app/subject/dao"
+	userDAO "HelpStudent/internal/app/users/dao"
+
+	"github.com/tidwall/gjson"
+)
+
+// InjectedVariable 由服务端填充的对话变量
+type InjectedVariable struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// VariableSources 可选的取值来源
+// attr. 来源只开放不含凭证的登录信息字段，登录信息中还保存着第三方的 access_token 等，不能按任意路径读取
+var VariableSources = []string{
+	model.VariableSourceStaffId,
+	model.VariableSourceName,
+	model.VariableSourceSubjects,
+	model.VariableSourceGrade,
+	model.VariableSourceStaffType,
+	model.VariableSourceUnitCode,
+	model.VariableSourceAttrPrefix + "staff_name",
+	model.VariableSourceAttrPrefix + "staff_type",
+	model.VariableSourceAttrPrefix + "unit_code",
+	model.VariableSourceAttrPrefix + "avatar",
+}
+
+// ValidVariableSource 是否为支持的取值来源
+func ValidVariableSource(source string) bool {
+	return slices.Contains(VariableSources, source)
+}
+
+// InjectedVariables 解析设置中的注入变量
+func InjectedVariables(setting *model.FastgptVariableSetting) []InjectedVariable {
+	var vars []InjectedVariable
+	if setting.Injected != "" {
+		_ = json.Unmarshal([]byte(setting.Injected), &vars)
+	}
+	return vars
+}
+
+// AllowedClientVariables 允许客户端传入的变量名
+func AllowedClientVariables(setting *model.FastgptVariableSetting) []string {
+	var names []string
+	for _, name := range strings.Split(setting.AllowedClient, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ApplyVariables 按应用设置处理提问中的变量：丢弃未允许的客户端变量，并填充服务端注入的变量
+// 应用未配置时客户端变量原样转发
+func ApplyVariables(ctx context.Context, info auth.Info, app *model.FastgptApp, req *dto.ChatCompletionRequest) error {
+	setting, err := dao.Variables.GetByAppId(ctx, app.ID)
+	if err != nil {
+		return fmt.Errorf("get variable setting: %w", err)
+	}
+	if setting == nil {
+		return nil
+	}
+
+	vars := filterVariables(req.Variables, AllowedClientVariables(setting))
+	if injected := InjectedVariables(setting); len(injected) > 0 {
+		profile := &studentProfile{ctx: ctx, info: info}
+		for _, v := range injected {
+			value, err := profile.value(v.Source)
+			if err != nil {
+				return err
+			}
+			vars[v.Name] = value
+		}
+	}
+	req.Variables = vars
+	return nil
+}
+
+// filterVariables 只保留允许的客户端变量
+func filterVariables(vars map[string]interface{}, allowed []string) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(allowed))
+	for _, name := range allowed {
+		if v, ok := vars[name]; ok {
+			filtered[name] = v
+		}
+	}
+	return filtered
+}
+
+// studentProfile 提问者的信息，登录信息与已选科目在首次用到时才查询
+type studentProfile struct {
+	ctx      context.Context
+	info     auth.Info
+	attr     []byte
+	loaded   bool
+	subjects []string
+}
+
+// value 按来源取值，取不到时为空字符串，保证客户端无法伪造
+func (p *studentProfile) value(source string) (string, error) {
+	switch source {
+	case model.VariableSourceStaffId:
+		return p.info.StaffId, nil
+	case model.VariableSourceGrade:
+		return gradeFromStaffId(p.info.StaffId), nil
+	case model.VariableSourceSubjects:
+		subjects, err := p.loadSubjects()
+		return strings.Join(subjects, "、"), err
+	case model.VariableSourceName:
+		if p.info.Name != "" {
+			return p.info.Name, nil
+		}
+		return p.attrValue("staff_name")
+	case model.VariableSourceStaffType:
+		return p.attrValue("staff_type")
+	case model.VariableSourceUnitCode:
+		return p.attrValue("unit_code")
+	}
+	// 已保存的设置中不再支持的来源不取值
+	if path, ok := strings.CutPrefix(source, model.VariableSourceAttrPrefix); ok && ValidVariableSource(source) {
+		return p.attrValue(path)
+	}
+	return "", nil
+}
+
+func (p *studentProfile) attrValue(path string) (string, error) {
+	if !p.loaded {
+		bind, err := userDAO.Users.GetBind(p.ctx, p.info.Uid)
+		if err != nil {
+			return "", fmt.Errorf("get user bind: %w", err)
+		}
+		if bind != nil {
+			p.attr = bind.Attr
+		}
+		p.loaded = true
+	}
+	if len(p.attr) == 0 {
+		return "", nil
+	}
+	return gjson.GetBytes(p.attr, path).String(), nil
+}
+
+// loadSubjects 已选科目，合并按 user_id 与按学号导入的关联
+func (p *studentProfile) loadSubjects() ([]string, error) {
+	if p.subjects != nil {
+		return p.subjects, nil
+	}
+	subjects, err := subjectDAO.Subject.GetUserSubjectsByUserId(p.info.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("get user subjects: %w", err)
+	}
+	if p.info.StaffId != "" {
+		byStaffId, err := subjectDAO.Subject.GetUserSubjects(p.info.StaffId)
+		if err != nil {
+			return nil, fmt.Errorf("get user subjects: %w", err)
+		}
+		for _, s := range byStaffId {
+			if !slices.Contains(subjects, s) {
+				subjects = append(subjects, s)
+			}
+		}
+	}
+	p.subjects = append([]string{}, subjects...)
+	return p.subjects, nil
+}
+
+// gradeFromStaffId 本科生学号前两位为入学年份，如 22050626 为 2022 级；无法推算时为空
+func gradeFromStaffId(staffId string) string {
+	if len(staffId) != 8 {
+		return ""
+	}
+	for _, c := range staffId {
+		if c < '0' || c > '9' {
+			return ""
+		}
+	}
+	return "20" + staffId[:2]
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"HelpStudent/core/auth"
+	"HelpStudent/internal/app/fastgpt/model"
+)
+
+func TestFilterVariables(t *testing.T) {
+	vars := filterVariables(map[string]interface{}{"topic": "极限", "staffId": "伪造"}, []string{"topic", "missing"})
+	if len(vars) != 1 || vars["topic"] != "极限" {
+		t.Errorf("filtered = %v", vars)
+	}
+	if vars := filterVariables(nil, nil); vars == nil || len(vars) != 0 {
+		t.Errorf("empty = %v", vars)
+	}
+}
+
+func TestStudentProfileValue(t *testing.T) {
+	p := &studentProfile{ctx: context.Background(), info: auth.Info{Uid: "u1", StaffId: "22050626", Name: "张三"}}
+	// 登录信息已加载，不查询数据库
+	p.loaded, p.attr = true, []byte(`{"staff_type":"1","unit_code":"05","avatar":"a.png","access_token":"secret"}`)
+
+	cases := map[string]string{
+		model.VariableSourceStaffId:   "22050626",
+		model.VariableSourceName:      "张三",
+		model.VariableSourceGrade:     "2022",
+		model.VariableSourceStaffType: "1",
+		model.VariableSourceUnitCode:  "05",
+		"attr.avatar":                 "a.png",
+		"attr.staff_name":             "",
+		"attr.access_token":           "",
+	}
+	for source, want := range cases {
+		if got, err := p.value(source); err != nil || got != want {
+			t.Errorf("%s = %q, %v; want %q", source, got, err, want)
+		}
+	}
+
+	if g := gradeFromStaffId("T1234"); g != "" {
+		t.Errorf("teacher grade = %q", g)
+	}
+}
+
+func TestValidVariableSource(t *testing.T) {
+	for _, s := range []string{"staffId", "subjects", "attr.unit_code"} {
+		if !ValidVariableSource(s) {
+			t.Errorf("%s should be valid", s)
+		}
+	}
+	for _, s := range []string{"", "password", "attr.", "attr.access_token", "attr.refresh_token", "attr.extra.major"} {
+		if ValidVariableSource(s) {
+			t.Errorf("%s should be invalid", s)
+		}
+	}
+}
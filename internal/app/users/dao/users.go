@@ -3,6 +3,7 @@ package dao
 import (
 	"HelpStudent/internal/app/users/model"
 	"context"
+	"errors"
 
 	"gorm.io/gorm"
 )
@@ -54,3 +55,16 @@ func (u *users) CreateWithBind(ctx context.Context, user *model.Users, bind *mod
 	tx.Commit()
 	return nil
 }
+
+// GetBind 获取用户的第三方登录信息，不存在时返回 nil
+func (u *users) GetBind(ctx context.Context, userId string) (*model.UserBind, error) {
+	var bind model.UserBind
+	err := u.WithContext(ctx).Where("user_id = ?", userId).First(&bind).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &bind, nil
+}
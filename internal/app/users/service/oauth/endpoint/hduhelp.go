@@ -61,7 +61,8 @@ type HDUHelpUserResp struct {
 
 type HDUHelpAttr struct {
 	HDUHelpOAuthTokenResp
-	Avatar string `json:"avatar"`
+	Avatar   string `json:"avatar"`
+	UnitCode string `json:"unit_code"`
 }
 
 func (p *HDUHelp) Validate(code string, state string) (staffId string, attr datatypes.JSON, err error) {
@@ -124,6 +125,7 @@ func (p *HDUHelp) Validate(code string, state string) (staffId string, attr data
 	attr, _ = json.Marshal(HDUHelpAttr{
 		HDUHelpOAuthTokenResp: tokenResp,
 		Avatar:                avatarResp.Avatar,
+		UnitCode:              personInfoResp.UnitCode,
 	})
 	return tokenResp.UserId, attr, nil
 }
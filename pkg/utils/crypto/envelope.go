@@ -0,0 +1,198 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 信封加密：每条数据使用随机生成的数据密钥（DEK）以 AES-256-GCM 加密，
+// DEK 再由主密钥（KEK）加密后与密文一起保存。
+// 存储格式: enc:v1:<主密钥ID>:<base64(加密后的DEK)>:<base64(密文)>
+
+const (
+	envelopePrefix = "enc:v1:"
+	keySize        = 32
+)
+
+var (
+	// ErrUnknownMasterKey 密文使用的主密钥不在密钥环中
+	ErrUnknownMasterKey = errors.New("unknown master key")
+	// ErrMalformedEnvelope 密文格式错误
+	ErrMalformedEnvelope = errors.New("malformed envelope")
+	// ErrNoMasterKey 未配置主密钥
+	ErrNoMasterKey = errors.New("no master key configured")
+)
+
+// MasterKey 主密钥
+type MasterKey struct {
+	Id  string
+	Key []byte
+}
+
+// Keyring 主密钥环，第一个密钥用于加密，所有密钥均可用于解密
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// ParseMasterKey 解析 base64 编码的 32 字节主密钥
+func ParseMasterKey(id, encoded string) (MasterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return MasterKey{}, fmt.Errorf("decode master key %s: %w", id, err)
+	}
+	return MasterKey{Id: id, Key: key}, nil
+}
+
+// NewKeyring 创建主密钥环，keys 为空时返回的密钥环不做任何加密
+func NewKeyring(keys ...MasterKey) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys))}
+	for i, mk := range keys {
+		if mk.Id == "" || strings.Contains(mk.Id, ":") {
+			return nil, fmt.Errorf("invalid master key id %q", mk.Id)
+		}
+		if len(mk.Key) != keySize {
+			return nil, fmt.Errorf("master key %s must be %d bytes", mk.Id, keySize)
+		}
+		if _, ok := k.keys[mk.Id]; ok {
+			return nil, fmt.Errorf("duplicate master key id %s", mk.Id)
+		}
+		aead, err := newAEAD(mk.Key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[mk.Id] = aead
+		if i == 0 {
+			k.primary = mk.Id
+		}
+	}
+	return k, nil
+}
+
+// Enabled 是否配置了主密钥
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.primary != ""
+}
+
+// PrimaryId 当前用于加密的主密钥ID
+func (k *Keyring) PrimaryId() string {
+	if k == nil {
+		return ""
+	}
+	return k.primary
+}
+
+// Encrypt 使用当前主密钥加密，未配置主密钥时原样返回
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if !k.Enabled() {
+		return plaintext, nil
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf("generate data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataAEAD, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	// 主密钥ID作为附加数据，防止密文被挪到其他密钥下
+	wrapped, err := seal(k.keys[k.primary], dek, []byte(k.primary))
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + k.primary + ":" +
+		base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt 解密，非信封格式的值视为历史明文原样返回
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEnvelope(value) {
+		return value, nil
+	}
+	if !k.Enabled() {
+		return "", ErrNoMasterKey
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformedEnvelope
+	}
+	kek, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownMasterKey, parts[0])
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformedEnvelope
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformedEnvelope
+	}
+
+	dek, err := open(kek, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("unwrap data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataAEAD, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 判断是否需要用当前主密钥重新加密（历史明文或使用旧主密钥）
+func (k *Keyring) NeedsRotation(value string) bool {
+	if !k.Enabled() || value == "" {
+		return false
+	}
+	if !IsEnvelope(value) {
+		return true
+	}
+	return !strings.HasPrefix(value, envelopePrefix+k.primary+":")
+}
+
+// IsEnvelope 判断是否为信封加密格式
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, ErrMalformedEnvelope
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additional)
+}
@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	k, err := NewKeyring(MasterKey{Id: "k1", Key: bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := k.Encrypt("fastgpt-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEnvelope(enc) {
+		t.Fatalf("Encrypt should return envelope, got %s", enc)
+	}
+
+	dec, err := k.Decrypt(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec != "fastgpt-secret" {
+		t.Errorf("Decrypt returned wrong value: %s", dec)
+	}
+
+	// 历史明文原样返回
+	if dec, _ := k.Decrypt("plain"); dec != "plain" {
+		t.Errorf("Decrypt should pass through plaintext, got %s", dec)
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	oldKey := MasterKey{Id: "old", Key: bytes.Repeat([]byte{1}, 32)}
+	newKey := MasterKey{Id: "new", Key: bytes.Repeat([]byte{2}, 32)}
+
+	oldRing, _ := NewKeyring(oldKey)
+	enc, err := oldRing.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring, err := NewKeyring(newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ring.NeedsRotation(enc) {
+		t.Error("value encrypted with old key should need rotation")
+	}
+	if !ring.NeedsRotation("plain") {
+		t.Error("plaintext should need rotation")
+	}
+	if dec, err := ring.Decrypt(enc); err != nil || dec != "secret" {
+		t.Errorf("Decrypt with previous key failed: %v %s", err, dec)
+	}
+
+	reenc, _ := ring.Encrypt("secret")
+	if ring.NeedsRotation(reenc) {
+		t.Error("value encrypted with primary key should not need rotation")
+	}
+
+	newOnly, _ := NewKeyring(newKey)
+	if _, err := newOnly.Decrypt(enc); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("expected ErrUnknownMasterKey, got %v", err)
+	}
+}